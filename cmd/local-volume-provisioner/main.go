@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"math/rand"
@@ -43,6 +44,8 @@ var (
 	optMetricsPath   string
 	discoveryPeriod  time.Duration
 	configSyncPeriod time.Duration
+	dryRun           bool
+	cleanBlockDevice bool
 )
 
 func main() {
@@ -52,6 +55,8 @@ func main() {
 	flag.StringVar(&optMetricsPath, "metrics-path", "/metrics", "path under which to expose metrics")
 	flag.DurationVar(&discoveryPeriod, "discovery-period", 10*time.Second, "the period for local volume discovery")
 	flag.DurationVar(&configSyncPeriod, "config-sync-period", 5*time.Second, "the period to check if there has been any config changes")
+	flag.BoolVar(&cleanBlockDevice, deleter.CleanBlockDeviceFlag, false, "clean the block device of the "+common.LocalPVEnv+" environment variable with the built-in block cleaner given as arguments, then exit")
+	flag.BoolVar(&dryRun, "dry-run", false, "print the PVs the provisioner would create, update, clean and delete with the configuration, then exit without changing the cluster or the disks")
 	flag.Parse()
 	if err := flag.Set("logtostderr", "true"); err != nil {
 		klog.Errorf("Failed to set logtostderr: %v", err)
@@ -60,6 +65,14 @@ func main() {
 		klog.Errorf("Failed to set legacy_stderr_threshold_behavior: %v", err)
 	}
 
+	if cleanBlockDevice {
+		// Run by the cleanup jobs of the built-in block cleaners.
+		if err := deleter.CleanBlockDevice(context.Background(), os.Getenv(common.LocalPVEnv), flag.Args(), nil); err != nil {
+			klog.Fatalf("Error cleaning block device: %v", err)
+		}
+		return
+	}
+
 	provisionerConfig := common.ProvisionerConfiguration{
 		StorageClassConfig: make(map[string]common.MountConfig),
 		MinResyncPeriod:    metav1.Duration{Duration: 5 * time.Minute},
@@ -87,8 +100,17 @@ func main() {
 	}
 
 	client := common.SetupClient()
+	dynamicClient := common.SetupDynamicClient()
 	node := util.GetNode(client.CoreV1(), nodeName)
 
+	if dryRun {
+		userConfig := common.UserConfigFromProvisionerConfig(node, namespace, jobImage, provisionerConfig)
+		if err := controller.RunDryRun(client, dynamicClient, userConfig, os.Stdout); err != nil {
+			klog.Fatalf("Error running dry run: %v", err)
+		}
+		return
+	}
+
 	configUpdate := make(chan common.ProvisionerConfiguration)
 	defer close(configUpdate)
 
@@ -97,21 +119,44 @@ func main() {
 	go configWatcher.Run(configUpdate)
 
 	klog.Info("Starting controller\n")
-	procTable := deleter.NewProcTable()
-	go controller.RunLocalController(configUpdate, client, procTable, discoveryPeriod, node, namespace, jobImage, provisionerConfig)
+	var procTable deleter.ProcTable = deleter.NewProcTable()
+	if provisionerConfig.ProcTableCheckpointPath != "" {
+		checkpointedProcTable, err := deleter.NewCheckpointedProcTable(provisionerConfig.ProcTableCheckpointPath, provisionerConfig.InterruptedCleanupPolicy)
+		if err != nil {
+			klog.Fatalf("Error loading proctable checkpoint: %v", err)
+		}
+		procTable = checkpointedProcTable
+		klog.Infof("Checkpointing proctable to %s", provisionerConfig.ProcTableCheckpointPath)
+	}
+	cleanupScheduler := deleter.NewCleanupScheduler(procTable)
+	go controller.RunLocalController(configUpdate, client, dynamicClient, procTable, cleanupScheduler, discoveryPeriod, node, namespace, jobImage, provisionerConfig)
 
 	klog.Infof("Starting metrics server at %s\n", optListenAddress)
 	prometheus.MustRegister([]prometheus.Collector{
 		metrics.PersistentVolumeCapacityBytes,
 		metrics.PersistentVolumeDiscoveryTotal,
 		metrics.PersistentVolumeDiscoveryDurationSeconds,
+		metrics.PersistentVolumeDeviceRemovedTotal,
+		metrics.PersistentVolumeCapacityUpdateTotal,
+		metrics.PersistentVolumeCapacityDriftBytes,
+		metrics.MissingVolumes,
+		metrics.DeviceHealthy,
+		metrics.DeviceMediaErrors,
+		metrics.DeviceBadSectors,
+		metrics.DevicePercentageUsed,
+		metrics.DeviceAvailableSpare,
+		metrics.DeviceTemperatureCelsius,
+		metrics.DeviceIOErrors,
 		metrics.PersistentVolumeDeleteTotal,
 		metrics.PersistentVolumeDeleteDurationSeconds,
 		metrics.PersistentVolumeDeleteFailedTotal,
+		metrics.PersistentVolumeDeleteTimedOutTotal,
+		metrics.PersistentVolumeCleanupProgressRatio,
 		metrics.APIServerRequestsTotal,
 		metrics.APIServerRequestsFailedTotal,
 		metrics.APIServerRequestsDurationSeconds,
 		collectors.NewProcTableCollector(procTable),
+		collectors.NewCleanupSchedulerCollector(cleanupScheduler),
 	}...)
 	http.Handle(optMetricsPath, promhttp.Handler())
 	log.Fatal(http.ListenAndServe(optListenAddress, nil))
@@ -40,4 +40,21 @@ if ! [[ $iterations =~ ^[0-9]+$ ]]; then
     errorExit "Number of iterations is not a number $iterations"
 fi
 
-ionice -c 3 shred -vzf -n $iterations $LOCAL_PV_BLKDEVICE
+# Report the progress of the passes of shred, including the last zeroing one,
+# to the provisioner as "PROGRESS <done bytes> <total bytes>" lines.
+set -o pipefail
+size=$(blockdev --getsize64 $LOCAL_PV_BLKDEVICE)
+ionice -c 3 shred -vzf -n $iterations $LOCAL_PV_BLKDEVICE 2>&1 | while read -r line; do
+    echo "$line"
+    if [[ $line =~ pass\ ([0-9]+)/([0-9]+) ]]; then
+        pass=${BASH_REMATCH[1]}
+        passes=${BASH_REMATCH[2]}
+        percent=0
+        if [[ $line =~ \ ([0-9]+)%$ ]]; then
+            percent=${BASH_REMATCH[1]}
+        fi
+        echo "PROGRESS $(( size * ((pass - 1) * 100 + percent) / 100 )) $(( size * passes ))"
+    fi
+done
+total=$(( size * (iterations + 1) ))
+echo "PROGRESS $total $total"
@@ -0,0 +1,293 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Publisher maintains one CSIStorageCapacity object per storage class of
+// this node, which reports the total and the maximum size of the available
+// PVs created by the provisioner which are not bound nor pre-bound to a
+// claim. The object of a storage class is updated whenever one of its PVs
+// changes.
+type Publisher struct {
+	*common.RuntimeConfig
+
+	// queue holds the names of the storage classes whose capacity must be
+	// published again.
+	queue workqueue.RateLimitingInterface
+
+	pvLister       corelisters.PersistentVolumeLister
+	pvListerSynced cache.InformerSynced
+
+	nodeTopology   *metav1.LabelSelector
+	ownerReference metav1.OwnerReference
+
+	// published holds the last published capacity object of each storage
+	// class. It is only accessed by the worker.
+	published map[string]*storagev1.CSIStorageCapacity
+}
+
+// NewPublisher creates a Publisher which watches the PVs through the
+// informer factory of the given runtime config.
+func NewPublisher(config *common.RuntimeConfig) (*Publisher, error) {
+	nodeValue, found := config.Node.Labels[common.NodeLabelKey]
+	if !found {
+		return nil, fmt.Errorf("Node does not have expected label %s", common.NodeLabelKey)
+	}
+	pvInformer := config.InformerFactory.Core().V1().PersistentVolumes()
+	p := &Publisher{
+		RuntimeConfig: config,
+		queue: workqueue.NewRateLimitingQueueWithConfig(
+			workqueue.DefaultControllerRateLimiter(),
+			workqueue.RateLimitingQueueConfig{
+				Name: "storageCapacityQueue",
+			}),
+		pvLister:       pvInformer.Lister(),
+		pvListerSynced: pvInformer.Informer().HasSynced,
+		nodeTopology: &metav1.LabelSelector{
+			MatchLabels: map[string]string{common.NodeLabelKey: nodeValue},
+		},
+		ownerReference: metav1.OwnerReference{
+			APIVersion: "v1",
+			Kind:       "Node",
+			Name:       config.Node.Name,
+			UID:        config.Node.UID,
+		},
+		published: map[string]*storagev1.CSIStorageCapacity{},
+	}
+	pvInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: p.pvChanged,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			p.pvChanged(oldObj)
+			p.pvChanged(newObj)
+		},
+		DeleteFunc: p.pvChanged,
+	})
+	return p, nil
+}
+
+// Run deletes the capacity objects of the storage classes which are no longer
+// published, publishes the capacity of all storage classes and then keeps the
+// objects up to date until stopCh is closed.
+func (p *Publisher) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer p.queue.ShutDown()
+
+	if ok := cache.WaitForCacheSync(stopCh, p.pvListerSynced); !ok {
+		klog.Errorf("Failed to wait for PV cache to sync, not publishing storage capacity")
+		return
+	}
+	if err := p.deleteStaleCapacities(); err != nil {
+		klog.Errorf("Failed to delete stale storage capacity objects: %v", err)
+	}
+	for class := range p.DiscoveryMap {
+		if p.publishesClass(class) {
+			p.queue.Add(class)
+		}
+	}
+
+	go wait.Until(p.runWorker, time.Second, stopCh)
+	<-stopCh
+}
+
+func (p *Publisher) pvChanged(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pv, ok := obj.(*v1.PersistentVolume)
+	if !ok {
+		klog.Errorf("Object is not a v1.PersistentVolume type %+v", obj)
+		return
+	}
+	if p.publishesClass(pv.Spec.StorageClassName) {
+		p.queue.Add(pv.Spec.StorageClassName)
+	}
+}
+
+// publishesClass returns true if the capacity of the storage class is
+// published. The PVs of classes with dynamic provisioning are only created for
+// claims, so there is no capacity to publish.
+func (p *Publisher) publishesClass(class string) bool {
+	config, ok := p.DiscoveryMap[class]
+	return ok && !config.DynamicProvisioning
+}
+
+func (p *Publisher) runWorker() {
+	for p.processNextWorkItem() {
+	}
+}
+
+func (p *Publisher) processNextWorkItem() bool {
+	key, shutdown := p.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer p.queue.Done(key)
+
+	class := key.(string)
+	if err := p.syncClass(class); err != nil {
+		p.queue.AddRateLimited(key)
+		klog.Errorf("Error publishing storage capacity of class %q: %v, requeuing", class, err)
+		return true
+	}
+	p.queue.Forget(key)
+	return true
+}
+
+// syncClass creates or updates the capacity object of the storage class if
+// its capacity changed.
+func (p *Publisher) syncClass(class string) error {
+	capacity, maxSize, err := p.availableCapacity(class)
+	if err != nil {
+		return err
+	}
+
+	name := capacityName(p.Node.Name, class)
+	published, ok := p.published[class]
+	if !ok {
+		published, err = p.Client.StorageV1().CSIStorageCapacities(p.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			published = nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if published == nil {
+		newCapacity := &storagev1.CSIStorageCapacity{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       p.Namespace,
+				Labels:          map[string]string{common.NodeNameLabel: p.Node.Name},
+				OwnerReferences: []metav1.OwnerReference{p.ownerReference},
+			},
+			NodeTopology:      p.nodeTopology,
+			StorageClassName:  class,
+			Capacity:          resource.NewQuantity(capacity, resource.BinarySI),
+			MaximumVolumeSize: resource.NewQuantity(maxSize, resource.BinarySI),
+		}
+		created, err := p.Client.StorageV1().CSIStorageCapacities(p.Namespace).Create(context.TODO(), newCapacity, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		klog.Infof("Published capacity %d, maximum volume size %d of storage class %q", capacity, maxSize, class)
+		p.published[class] = created
+		return nil
+	}
+
+	if published.Capacity != nil && published.Capacity.Value() == capacity &&
+		published.MaximumVolumeSize != nil && published.MaximumVolumeSize.Value() == maxSize {
+		p.published[class] = published
+		return nil
+	}
+	newCapacity := published.DeepCopy()
+	newCapacity.Capacity = resource.NewQuantity(capacity, resource.BinarySI)
+	newCapacity.MaximumVolumeSize = resource.NewQuantity(maxSize, resource.BinarySI)
+	updated, err := p.Client.StorageV1().CSIStorageCapacities(p.Namespace).Update(context.TODO(), newCapacity, metav1.UpdateOptions{})
+	if err != nil {
+		// Get the object again on retry, it may have been changed or deleted.
+		delete(p.published, class)
+		return err
+	}
+	klog.Infof("Published capacity %d, maximum volume size %d of storage class %q", capacity, maxSize, class)
+	p.published[class] = updated
+	return nil
+}
+
+// availableCapacity returns the total and the maximum capacity of the PVs of
+// the storage class which can still be bound to a claim.
+func (p *Publisher) availableCapacity(class string) (int64, int64, error) {
+	pvs, err := p.pvLister.List(labels.Everything())
+	if err != nil {
+		return 0, 0, err
+	}
+	var capacity, maxSize int64
+	for _, pv := range pvs {
+		if pv.Spec.StorageClassName != class || pv.Spec.Local == nil || pv.DeletionTimestamp != nil {
+			continue
+		}
+		if pv.Status.Phase != v1.VolumeAvailable || pv.Spec.ClaimRef != nil {
+			continue
+		}
+		if _, ok := pv.Annotations[common.AnnVolumeMissing]; ok {
+			continue
+		}
+		// The PVs are listed from the informer since the cache may not be
+		// populated yet, but only those created by this provisioner count.
+		if _, ok := p.Cache.GetPV(pv.Name); !ok && pv.Annotations[common.AnnProvisionedBy] != p.Name {
+			continue
+		}
+		size := pv.Spec.Capacity[v1.ResourceStorage]
+		capacity += size.Value()
+		maxSize = max(maxSize, size.Value())
+	}
+	return capacity, maxSize, nil
+}
+
+// deleteStaleCapacities deletes the capacity objects of this node whose
+// storage class is no longer published.
+func (p *Publisher) deleteStaleCapacities() error {
+	selector := labels.SelectorFromSet(labels.Set{common.NodeNameLabel: p.Node.Name})
+	capacities, err := p.Client.StorageV1().CSIStorageCapacities(p.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	for _, capacity := range capacities.Items {
+		if p.publishesClass(capacity.StorageClassName) {
+			continue
+		}
+		if capacity.Name != capacityName(p.Node.Name, capacity.StorageClassName) {
+			continue
+		}
+		err := p.Client.StorageV1().CSIStorageCapacities(p.Namespace).Delete(context.TODO(), capacity.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		klog.Infof("Deleted stale capacity object of storage class %q", capacity.StorageClassName)
+	}
+	return nil
+}
+
+// capacityName returns the name of the capacity object of the storage class
+// on the node.
+func capacityName(node, class string) string {
+	h := fnv.New64a()
+	h.Write([]byte(node))
+	h.Write([]byte("/"))
+	h.Write([]byte(class))
+	return fmt.Sprintf("local-capacity-%x", h.Sum64())
+}
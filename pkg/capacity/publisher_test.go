@@ -0,0 +1,177 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/cache"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testNamespace       = "kube-system"
+	testProvisionerName = "local-volume-provisioner-test-node"
+)
+
+var testNode = &v1.Node{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:   "test-node",
+		UID:    "test-node-uid",
+		Labels: map[string]string{common.NodeLabelKey: "test-node"},
+	},
+}
+
+func testPV(name, class, size string, phase v1.PersistentVolumePhase, claimRef *v1.ObjectReference) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{common.AnnProvisionedBy: testProvisionerName},
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				Local: &v1.LocalVolumeSource{Path: "/mnt/disks/" + name},
+			},
+			Capacity:         v1.ResourceList{v1.ResourceStorage: resource.MustParse(size)},
+			StorageClassName: class,
+			ClaimRef:         claimRef,
+		},
+		Status: v1.PersistentVolumeStatus{Phase: phase},
+	}
+}
+
+func testSetup(t *testing.T, objects ...runtime.Object) (*fake.Clientset, *Publisher) {
+	client := fake.NewSimpleClientset(objects...)
+	runtimeConfig := &common.RuntimeConfig{
+		UserConfig: &common.UserConfig{
+			Node: testNode,
+			DiscoveryMap: map[string]common.MountConfig{
+				"sc1": {HostDir: "/mnt/disks", MountDir: "/mnt/disks"},
+				"sc2": {HostDir: "/mnt/dynamic", MountDir: "/mnt/dynamic", DynamicProvisioning: true},
+			},
+			Namespace: testNamespace,
+		},
+		Name:            testProvisionerName,
+		Cache:           cache.NewVolumeCache(),
+		Client:          client,
+		InformerFactory: informers.NewSharedInformerFactory(client, 0),
+	}
+	p, err := NewPublisher(runtimeConfig)
+	if err != nil {
+		t.Fatalf("Error setting up test publisher: %v", err)
+	}
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	runtimeConfig.InformerFactory.Start(stopCh)
+	runtimeConfig.InformerFactory.WaitForCacheSync(stopCh)
+	return client, p
+}
+
+func verifyCapacity(t *testing.T, client *fake.Clientset, class string, expectedCapacity, expectedMaxSize string) {
+	capacity, err := client.StorageV1().CSIStorageCapacities(testNamespace).Get(context.TODO(), capacityName(testNode.Name, class), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Error getting capacity of class %q: %v", class, err)
+	}
+	if capacity.StorageClassName != class {
+		t.Errorf("Expected storage class %q, got %q", class, capacity.StorageClassName)
+	}
+	if value := capacity.NodeTopology.MatchLabels[common.NodeLabelKey]; value != testNode.Name {
+		t.Errorf("Expected node topology %s=%s, got %v", common.NodeLabelKey, testNode.Name, capacity.NodeTopology)
+	}
+	if capacity.Capacity.Cmp(resource.MustParse(expectedCapacity)) != 0 {
+		t.Errorf("Expected capacity %s of class %q, got %s", expectedCapacity, class, capacity.Capacity.String())
+	}
+	if capacity.MaximumVolumeSize.Cmp(resource.MustParse(expectedMaxSize)) != 0 {
+		t.Errorf("Expected maximum volume size %s of class %q, got %s", expectedMaxSize, class, capacity.MaximumVolumeSize.String())
+	}
+}
+
+func TestSyncClass(t *testing.T) {
+	boundClaim := &v1.ObjectReference{Namespace: "default", Name: "claim", UID: "claim-uid"}
+	otherPV := testPV("pv5", "sc1", "1Ti", v1.VolumeAvailable, nil)
+	otherPV.Annotations[common.AnnProvisionedBy] = "local-volume-provisioner-other-node"
+	client, p := testSetup(t,
+		testPV("pv1", "sc1", "10Gi", v1.VolumeAvailable, nil),
+		testPV("pv2", "sc1", "20Gi", v1.VolumeAvailable, nil),
+		testPV("pv3", "sc1", "100Gi", v1.VolumeBound, boundClaim),
+		testPV("pv4", "sc1", "50Gi", v1.VolumeAvailable, &v1.ObjectReference{Namespace: testNamespace, Name: common.UnhealthyClaimNamePrefix + "6f1c2a9e-0b7d-4e43-9a51-2d8c3f7e5b10"}),
+		otherPV,
+	)
+
+	if err := p.syncClass("sc1"); err != nil {
+		t.Fatalf("Error syncing class: %v", err)
+	}
+	verifyCapacity(t, client, "sc1", "30Gi", "20Gi")
+
+	// The capacity is updated when a PV is bound.
+	pv2 := testPV("pv2", "sc1", "20Gi", v1.VolumeBound, boundClaim)
+	if _, err := client.CoreV1().PersistentVolumes().Update(context.TODO(), pv2, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Error updating PV: %v", err)
+	}
+	waitForPV(t, p, "pv2", v1.VolumeBound)
+	if err := p.syncClass("sc1"); err != nil {
+		t.Fatalf("Error syncing class: %v", err)
+	}
+	verifyCapacity(t, client, "sc1", "10Gi", "10Gi")
+}
+
+func TestDeleteStaleCapacities(t *testing.T) {
+	staleCapacities := []runtime.Object{}
+	for _, class := range []string{"sc1", "sc2", "sc3"} {
+		staleCapacities = append(staleCapacities, &storagev1.CSIStorageCapacity{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      capacityName(testNode.Name, class),
+				Namespace: testNamespace,
+				Labels:    map[string]string{common.NodeNameLabel: testNode.Name},
+			},
+			StorageClassName: class,
+		})
+	}
+	client, p := testSetup(t, staleCapacities...)
+
+	if err := p.deleteStaleCapacities(); err != nil {
+		t.Fatalf("Error deleting stale capacities: %v", err)
+	}
+	capacities, err := client.StorageV1().CSIStorageCapacities(testNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Error listing capacities: %v", err)
+	}
+	if len(capacities.Items) != 1 || capacities.Items[0].StorageClassName != "sc1" {
+		t.Errorf("Expected only the capacity of class %q to be left, got %+v", "sc1", capacities.Items)
+	}
+}
+
+func waitForPV(t *testing.T, p *Publisher, name string, phase v1.PersistentVolumePhase) {
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		pv, err := p.pvLister.Get(name)
+		return err == nil && pv.Status.Phase == phase, nil
+	})
+	if err != nil {
+		t.Fatalf("PV %q did not reach phase %q: %v", name, phase, err)
+	}
+}
@@ -24,8 +24,13 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/cache"
@@ -38,6 +43,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
@@ -57,8 +63,56 @@ const (
 	// DefaultBlockCleanerCommand is the default block device cleaning command
 	DefaultBlockCleanerCommand = "/scripts/quick_reset.sh"
 
+	// BlockCleanerDiscard discards the whole block device with BLKDISCARD.
+	BlockCleanerDiscard = "discard"
+	// BlockCleanerSecureDiscard securely discards the whole block device with BLKSECDISCARD.
+	BlockCleanerSecureDiscard = "secure-discard"
+	// BlockCleanerZeroOut zeroes the whole block device with BLKZEROOUT.
+	BlockCleanerZeroOut = "zero-out"
+	// BlockCleanerOverwrite overwrites the whole block device with random data
+	// followed by a last pass of zeros.
+	BlockCleanerOverwrite = "overwrite"
+	// BlockCleanerWipeSignatures zeroes the first and last MiB of the block
+	// device, which hold the signatures of filesystems and partition tables.
+	BlockCleanerWipeSignatures = "wipe-signatures"
+
 	// EventVolumeFailedDelete copied from k8s.io/kubernetes/pkg/controller/volume/events
 	EventVolumeFailedDelete = "VolumeFailedDelete"
+	// EventVolumeDeviceRemoved is recorded on a PV whose backing block device was hot-unplugged
+	EventVolumeDeviceRemoved = "VolumeDeviceRemoved"
+	// EventVolumeDeviceMismatch is recorded on a PV whose path is occupied by a different device
+	EventVolumeDeviceMismatch = "VolumeDeviceMismatch"
+	// EventVolumeDeviceMoved is recorded on a PV whose device was found at a different path
+	EventVolumeDeviceMoved = "VolumeDeviceMoved"
+	// EventVolumeCapacityUpdated is recorded on an available PV whose capacity was updated to the measured capacity
+	EventVolumeCapacityUpdated = "VolumeCapacityUpdated"
+	// EventVolumeCapacityDrift is recorded on a bound PV whose measured capacity differs from its capacity
+	EventVolumeCapacityDrift = "VolumeCapacityDrift"
+	// EventVolumeMissing is recorded on a PV whose backing volume vanished
+	EventVolumeMissing = "VolumeMissing"
+	// AnnVolumeMissing is set on PVs whose backing volume vanished, to the reason why it is considered missing
+	AnnVolumeMissing = "local-static-provisioner.sigs.k8s.io/volume-missing"
+	// EventVolumeUnhealthy is recorded on a PV and its bound PVC when the disk of the volume is failing
+	EventVolumeUnhealthy = "VolumeUnhealthy"
+	// EventVolumeHealthy is recorded on a PV whose disk is no longer failing
+	EventVolumeHealthy = "VolumeHealthy"
+	// AnnVolumeUnhealthy is set on PVs whose disk is failing, to the problems found on the disk
+	AnnVolumeUnhealthy = "local-static-provisioner.sigs.k8s.io/unhealthy"
+	// AnnUnhealthyClaim is set on available PVs whose disk is failing to the name of the claim,
+	// which does not exist, that the provisioner pre-bound them to so that they are not scheduled
+	AnnUnhealthyClaim = "local-static-provisioner.sigs.k8s.io/unhealthy-claim"
+	// EventVolumeReserved is recorded on an available PV which was pre-bound to the claim its volume is reserved for
+	EventVolumeReserved = "VolumeReserved"
+	// EventVolumeReservationConflict is recorded on a PV whose volume is reserved for another claim than its own
+	EventVolumeReservationConflict = "VolumeReservationConflict"
+	// EventVolumeCleanupQueued is recorded on a released PV whose cleanup waits for other cleanups to end
+	EventVolumeCleanupQueued = "VolumeCleanupQueued"
+	// EventVolumeCleanupTimedOut is recorded on a released PV whose cleanup was killed by its timeout
+	EventVolumeCleanupTimedOut = "VolumeCleanupTimedOut"
+	// AnnCleanupProgress is set on released PVs being cleaned, to the progress of their cleanup
+	AnnCleanupProgress = "local-static-provisioner.sigs.k8s.io/cleanup-progress"
+	// AnnDeviceFingerprint is the PV annotation recording the identity of the device backing the volume
+	AnnDeviceFingerprint = "local-static-provisioner.sigs.k8s.io/device-fingerprint"
 	// ProvisionerConfigPath points to the path inside of the provisioner container where configMap volume is mounted
 	ProvisionerConfigPath = "/etc/provisioner/config/"
 	// ProvisonerStorageClassConfig defines file name of the file which stores storage class
@@ -66,6 +120,8 @@ const (
 	ProvisonerStorageClassConfig = "storageClassMap"
 	// ProvisionerNodeLabelsForPV contains a list of node labels to be copied to the PVs created by the provisioner
 	ProvisionerNodeLabelsForPV = "nodeLabelsForPV"
+	// ProvisionerNodeLabelsForPVAffinity contains a list of node labels to be required by the node affinity of the PVs
+	ProvisionerNodeLabelsForPVAffinity = "nodeLabelsForPVAffinity"
 	// ProvisionerUseAlphaAPI shows if we need to use alpha API, default to false
 	ProvisionerUseAlphaAPI = "useAlphaAPI"
 	// AlphaStorageNodeAffinityAnnotation defines node affinity policies for a PersistentVolume.
@@ -89,6 +145,65 @@ const (
 
 	// DefaultNamePattern is the default name pattern list (separated by comma) of in PV discovery.
 	DefaultNamePattern = "*"
+
+	// DefaultDiscoveryResyncPeriod is the default period of the full discovery scan
+	// when watch based discovery is enabled.
+	DefaultDiscoveryResyncPeriod = 5 * time.Minute
+
+	// IdentityModePath derives the PV name from the file name in the discovery directory.
+	IdentityModePath = "path"
+	// IdentityModeDevice derives the PV name from the WWN, serial or filesystem UUID of the device.
+	IdentityModeDevice = "device"
+
+	// CapacityRoundingPretty rounds the capacity down to GiB or MiB when it is at least 10 of them.
+	CapacityRoundingPretty = "pretty"
+	// CapacityRoundingExact advertises the capacity in bytes.
+	CapacityRoundingExact = "exact"
+	// CapacityRoundingUnit rounds the capacity down to a multiple of the rounding unit.
+	CapacityRoundingUnit = "unit"
+	// FsCapacityTotal is the total capacity of a filesystem.
+	FsCapacityTotal = "capacity"
+	// FsCapacityAvailable is the capacity of a filesystem available to unprivileged users.
+	FsCapacityAvailable = "available"
+
+	// DevTypeDisk is the device type of whole disks.
+	DevTypeDisk = "disk"
+	// DevTypePartition is the device type of partitions.
+	DevTypePartition = "partition"
+
+	// NamePrefix is the prefix of the names of the volumes created by the
+	// provisioner on the disks, followed by the volume number: the GPT names
+	// of the partitions, the names of the LVM logical volumes and the names
+	// of the directories of directory pools.
+	NamePrefix = "local-static-provisioner-"
+	// MaxPartitions is the maximum number of partitions created on a disk,
+	// the number of entries of a default GPT partition table.
+	MaxPartitions = 128
+
+	// FilesystemLabel is the label of the filesystems created by the
+	// provisioner, short enough for all common filesystems.
+	FilesystemLabel = "local-pv"
+
+	// MissingVolumePolicyAnnotate annotates the available PVs whose volume is missing.
+	MissingVolumePolicyAnnotate = "annotate"
+	// MissingVolumePolicyDelete deletes the available PVs whose volume is missing.
+	MissingVolumePolicyDelete = "delete"
+
+	// InterruptedCleanupRestart restarts the cleanups interrupted by a restart of the provisioner.
+	InterruptedCleanupRestart = "restart"
+	// InterruptedCleanupFail marks the cleanups interrupted by a restart of the provisioner failed.
+	InterruptedCleanupFail = "fail"
+
+	// UnhealthyClaimNamePrefix is the prefix of the name of the claim which
+	// available PVs on failing disks are pre-bound to, so that they are not
+	// scheduled. It is followed by a random UID so that no claim can be
+	// created with that name to bind them. The name is recorded in the
+	// AnnUnhealthyClaim annotation of the PV.
+	UnhealthyClaimNamePrefix = "local-static-provisioner-unhealthy-"
+
+	// DynamicProvisionerName is the provisioner of the storage classes with
+	// dynamic provisioning, shared by the provisioners of all nodes.
+	DynamicProvisionerName = "local-static-provisioner.sigs.k8s.io/dynamic"
 )
 
 // UserConfig stores all the user-defined parameters to the provisioner
@@ -99,6 +214,8 @@ type UserConfig struct {
 	DiscoveryMap map[string]MountConfig
 	// Labels and their values that are added to PVs created by the provisioner
 	NodeLabelsForPV []string
+	// Labels of the node whose values are required by the node affinity of the PVs
+	NodeLabelsForPVAffinity []string
 	// UseAlphaAPI shows if we need to use alpha API
 	UseAlphaAPI bool
 	// UseJobForCleaning indicates if Jobs should be spawned for cleaning block devices (as opposed to process),.
@@ -124,6 +241,55 @@ type UserConfig struct {
 	RemoveNodeNotReadyTaint bool
 	// ProvisionerNotReadyNodeTaintKey is the key of the startup taint that provisioner will remove once it becomes ready.
 	ProvisionerNotReadyNodeTaintKey string
+	// UseWatchForDiscovery indicates if discovery directories and the mount table should be
+	// watched for changes instead of scanning them on every discovery period.
+	UseWatchForDiscovery bool
+	// DiscoveryResyncPeriod is the period of the full discovery scan when UseWatchForDiscovery is set.
+	DiscoveryResyncPeriod metav1.Duration
+	// MissingVolumePolicy is what happens to the available PVs whose volume is missing.
+	MissingVolumePolicy string
+	// HealthCheckPeriod is the period of the disk health checks, disabled if zero.
+	HealthCheckPeriod metav1.Duration
+	// PublishStorageCapacity indicates if the capacity of the available PVs should be
+	// published in CSIStorageCapacity objects.
+	PublishStorageCapacity bool
+	// PublishInventory indicates if the volumes seen by discovery should be listed in the
+	// LocalVolumeInventory of the node.
+	PublishInventory bool
+	// Reservations pre-bind the PVs of specific volumes to specific claims.
+	Reservations []Reservation
+	// MaxConcurrentCleanups is the maximum number of volumes cleaned by a process
+	// at the same time, unlimited if zero.
+	MaxConcurrentCleanups int
+}
+
+// Reservation reserves the volume at Path of a storage class on a node for a
+// claim, so that its PV is always pre-bound to that claim.
+type Reservation struct {
+	// Name of the node of the volume.
+	Node string `json:"node" yaml:"node"`
+	// Storage class of the volume.
+	StorageClass string `json:"storageClass" yaml:"storageClass"`
+	// Path of the volume on the host, which is the local path of its PV.
+	Path string `json:"path" yaml:"path"`
+	// Claim the volume is reserved for.
+	Claim ClaimReference `json:"claim" yaml:"claim"`
+}
+
+// ClaimReference identifies the claim a PV is pre-bound to.
+type ClaimReference struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Name      string `json:"name" yaml:"name"`
+}
+
+// ObjectReference returns the claim reference of a PV pre-bound to the claim.
+func (c *ClaimReference) ObjectReference() *v1.ObjectReference {
+	return &v1.ObjectReference{
+		Kind:       "PersistentVolumeClaim",
+		APIVersion: "v1",
+		Namespace:  c.Namespace,
+		Name:       c.Name,
+	}
 }
 
 // MountConfig stores a configuration for discoverying a specific storageclass
@@ -132,7 +298,8 @@ type MountConfig struct {
 	HostDir string `json:"hostDir" yaml:"hostDir"`
 	// The mount point of the hostpath volume
 	MountDir string `json:"mountDir" yaml:"mountDir"`
-	// The type of block cleaner to use
+	// The type of block cleaner to use, either the name of a built-in block
+	// cleaner followed by its arguments or a command to run
 	BlockCleanerCommand []string `json:"blockCleanerCommand" yaml:"blockCleanerCommand"`
 	// The volume mode of created PersistentVolume object,
 	// default to Filesystem if not specified.
@@ -148,9 +315,203 @@ type MountConfig struct {
 	// NamePattern name pattern check
 	// only discover file name matching pattern("*" by default)
 	NamePattern string `json:"namePattern" yaml:"namePattern"`
+	// IncludeRegex only discovers the entries whose path relative to
+	// MountDir matches one of the regular expressions, all if empty.
+	IncludeRegex []string `json:"includeRegex" yaml:"includeRegex"`
+	// ExcludePatterns skips the entries whose path relative to MountDir
+	// matches one of the glob patterns. Excluded directories are not
+	// descended into.
+	ExcludePatterns []string `json:"excludePatterns" yaml:"excludePatterns"`
+	// MaxDepth is the number of directory levels below MountDir in which
+	// entries are discovered, default to 1. The directories which are not
+	// mount points are descended into until MaxDepth is reached.
+	MaxDepth int `json:"maxDepth" yaml:"maxDepth"`
 	// Additional selector terms to set for node affinity in addition to the provisioner node name.
 	// Useful for shared disks as affinity can not be changed after provisioning the PV.
 	Selector []v1.NodeSelectorTerm `json:"selector" yaml:"selector"`
+	// SelectorRequiresNode combines each of the Selector terms with the node name and affinity
+	// labels of the provisioner node instead of adding them as alternatives to the node.
+	SelectorRequiresNode bool `json:"selectorRequiresNode" yaml:"selectorRequiresNode"`
+	// HotplugRules selects the block devices whose kernel add, change and
+	// remove events trigger discovery of the matching entries in MountDir.
+	// Hotplug discovery is disabled for the class if empty.
+	HotplugRules []HotplugRule `json:"hotplugRules" yaml:"hotplugRules"`
+	// DeviceSelector only discovers the block devices matching the given
+	// attributes. Entries that are not block devices are skipped.
+	DeviceSelector *DeviceSelector `json:"deviceSelector" yaml:"deviceSelector"`
+	// IdentityMode defines how the identity of a volume is derived, either
+	// from the file name ("path", the default) or from the physical device
+	// ("device") so that the PV follows the device across renames.
+	IdentityMode string `json:"identityMode" yaml:"identityMode"`
+	// Partitioning splits the unused whole disks in MountDir into GPT
+	// partitions before discovery, and only the partitions are discovered.
+	Partitioning *Partitioning `json:"partitioning" yaml:"partitioning"`
+	// FormatAndMount formats the raw block devices of a Filesystem class
+	// with FsType and mounts them under a directory managed by the
+	// provisioner, instead of publishing the raw devices.
+	FormatAndMount *FormatAndMount `json:"formatAndMount" yaml:"formatAndMount"`
+	// LVM creates logical volumes in a volume group and discovers them
+	// instead of the entries of a directory. HostDir and MountDir default to
+	// the device directory of the volume group.
+	LVM *LVMConfig `json:"lvm" yaml:"lvm"`
+	// DynamicProvisioning creates a volume of the requested size for each
+	// claim of the storage class which selected this node, instead of
+	// discovering volumes. The volumes are directories with a project quota
+	// under MountDir, or logical volumes if LVM is set.
+	DynamicProvisioning bool `json:"dynamicProvisioning" yaml:"dynamicProvisioning"`
+	// DirectoryPool creates directories with a project quota on the
+	// filesystem mounted at MountDir and discovers them as Filesystem volumes
+	// whose capacity is the quota, instead of requiring mount points.
+	DirectoryPool *DirectoryPool `json:"directoryPool" yaml:"directoryPool"`
+	// VolumeMetadata reads the optional metadata file .<name>.pv.yaml next
+	// to each entry in MountDir, which overrides the attributes of its PV or
+	// excludes it from discovery.
+	VolumeMetadata bool `json:"volumeMetadata" yaml:"volumeMetadata"`
+	// CapacityPolicy defines how the capacity of the PVs is derived from the
+	// size of their volumes.
+	CapacityPolicy *CapacityPolicy `json:"capacityPolicy" yaml:"capacityPolicy"`
+	// CleanupPolicy defines how the released volumes of the storage class
+	// are cleaned.
+	CleanupPolicy *CleanupPolicy `json:"cleanupPolicy" yaml:"cleanupPolicy"`
+}
+
+// CleanupPolicy defines how the cleanups of the volumes of a storage class are
+// scheduled, bounded and retried.
+type CleanupPolicy struct {
+	// MaxConcurrent is the maximum number of volumes of the storage class
+	// cleaned at the same time on a node, unlimited if zero.
+	MaxConcurrent int `json:"maxConcurrent" yaml:"maxConcurrent"`
+	// Priority orders the cleanups waiting for a slot, the ones of the
+	// storage classes with the highest priority start first. Default is 0.
+	Priority int `json:"priority" yaml:"priority"`
+	// Timeout is the maximum duration of a cleanup, after which its block
+	// cleaner or its job is killed. The deletion of the contents of a
+	// Filesystem volume by the provisioner is not interrupted. Unlimited if
+	// zero.
+	Timeout metav1.Duration `json:"timeout" yaml:"timeout"`
+	// MaxRetries is the number of times a failed or timed out cleanup process
+	// is retried, unlimited if nil.
+	MaxRetries *int `json:"maxRetries" yaml:"maxRetries"`
+	// RetryBackoff is the delay between a failed or timed out cleanup process
+	// and its retry.
+	RetryBackoff metav1.Duration `json:"retryBackoff" yaml:"retryBackoff"`
+}
+
+// CapacityPolicy defines the capacity advertised by the PVs of a storage
+// class, which is the size of their volume minus Reserve, rounded down.
+type CapacityPolicy struct {
+	// Rounding is one of pretty (default), exact or unit.
+	Rounding string `json:"rounding" yaml:"rounding"`
+	// RoundingUnit is the unit of the unit rounding.
+	RoundingUnit *resource.Quantity `json:"roundingUnit" yaml:"roundingUnit"`
+	// Reserve is subtracted from the size of the volumes, either a quantity
+	// such as 1Gi or a percentage of the size such as 5%.
+	Reserve string `json:"reserve" yaml:"reserve"`
+	// FsCapacity is the size of Filesystem volumes, either the capacity
+	// (default) or the available bytes of the filesystem.
+	FsCapacity string `json:"fsCapacity" yaml:"fsCapacity"`
+}
+
+// ReservedBytes returns the bytes of a volume of capacityByte which are not
+// advertised by its PV.
+func (p *CapacityPolicy) ReservedBytes(capacityByte int64) (int64, error) {
+	if p == nil || p.Reserve == "" {
+		return 0, nil
+	}
+	if percentage, ok := strings.CutSuffix(p.Reserve, "%"); ok {
+		value, err := strconv.Atoi(percentage)
+		if err != nil || value < 0 || value >= 100 {
+			return 0, fmt.Errorf("invalid reserve %q, percentage must be an integer between 0 and 99", p.Reserve)
+		}
+		return capacityByte * int64(value) / 100, nil
+	}
+	quantity, err := resource.ParseQuantity(p.Reserve)
+	if err != nil {
+		return 0, fmt.Errorf("invalid reserve %q: %v", p.Reserve, err)
+	}
+	if quantity.Sign() < 0 {
+		return 0, fmt.Errorf("invalid reserve %q, quantity must not be negative", p.Reserve)
+	}
+	return quantity.Value(), nil
+}
+
+// DirectoryPool defines the Count directories of Size created on the
+// filesystem of a storage class.
+type DirectoryPool struct {
+	Count int                `json:"count" yaml:"count"`
+	Size  *resource.Quantity `json:"size" yaml:"size"`
+}
+
+// LVMConfig defines the logical volumes created in a volume group, either
+// Count volumes of Size, Count volumes sharing the free space of the volume
+// group equally, or as many volumes of Size as fit. Count and Size are not
+// used with dynamic provisioning, where the claims define the volumes.
+type LVMConfig struct {
+	VolumeGroup string             `json:"volumeGroup" yaml:"volumeGroup"`
+	Count       int                `json:"count" yaml:"count"`
+	Size        *resource.Quantity `json:"size" yaml:"size"`
+	// ThinPool creates thin volumes in the given thin pool of the volume
+	// group, which requires both Count and Size.
+	ThinPool string `json:"thinPool" yaml:"thinPool"`
+	// RecreateOnDelete cleans block volumes by removing and recreating the
+	// thin logical volume instead of running the block cleaner command, which
+	// requires ThinPool.
+	RecreateOnDelete bool `json:"recreateOnDelete" yaml:"recreateOnDelete"`
+}
+
+// FormatAndMount defines where and how the raw block devices of a storage
+// class are formatted and mounted.
+type FormatAndMount struct {
+	// The host directory the devices are mounted under
+	HostDir string `json:"hostDir" yaml:"hostDir"`
+	// HostDir in the provisioner container, which must be mounted with
+	// bidirectional mount propagation. Defaults to HostDir.
+	MountDir string `json:"mountDir" yaml:"mountDir"`
+	// Options passed to mkfs in addition to the filesystem label
+	MkfsOptions []string `json:"mkfsOptions" yaml:"mkfsOptions"`
+	// AllowOverwrite formats devices which already carry a filesystem, RAID
+	// or partition table signature. Filesystems created by the provisioner
+	// are always mounted without formatting.
+	AllowOverwrite bool `json:"allowOverwrite" yaml:"allowOverwrite"`
+}
+
+// Partitioning defines how the whole disks of a storage class are split into
+// partitions, either into Count partitions of equal size or into as many
+// partitions of Size as fit on the disk.
+type Partitioning struct {
+	Count int                `json:"count" yaml:"count"`
+	Size  *resource.Quantity `json:"size" yaml:"size"`
+}
+
+// DeviceSelector selects block devices by the attributes read from sysfs and
+// udev. All the specified fields must match. Model, Vendor, Serial, WWN, ByID
+// and ByPath are glob patterns.
+type DeviceSelector struct {
+	// DevType is "disk" or "partition"
+	DevType string `json:"devType" yaml:"devType"`
+	Model   string `json:"model" yaml:"model"`
+	Vendor  string `json:"vendor" yaml:"vendor"`
+	Serial  string `json:"serial" yaml:"serial"`
+	WWN     string `json:"wwn" yaml:"wwn"`
+	// Rotational selects rotational disks if true, solid state disks if false
+	Rotational *bool `json:"rotational" yaml:"rotational"`
+	// Transport is one of nvme, sata, sas, scsi, usb or virtio
+	Transport string             `json:"transport" yaml:"transport"`
+	MinSize   *resource.Quantity `json:"minSize" yaml:"minSize"`
+	MaxSize   *resource.Quantity `json:"maxSize" yaml:"maxSize"`
+	// ByID is matched against the names of the links to the device in /dev/disk/by-id
+	ByID string `json:"byId" yaml:"byId"`
+	// ByPath is matched against the names of the links to the device in /dev/disk/by-path
+	ByPath string `json:"byPath" yaml:"byPath"`
+}
+
+// HotplugRule matches block device uevents by kernel device name and type.
+type HotplugRule struct {
+	// DevNamePattern is a glob matched against the kernel name of the
+	// device, e.g. "nvme*n1".
+	DevNamePattern string `json:"devNamePattern" yaml:"devNamePattern"`
+	// DevType optionally restricts the rule to "disk" or "partition" devices.
+	DevType string `json:"devType" yaml:"devType"`
 }
 
 // RuntimeConfig stores all the objects that the provisioner needs to run
@@ -160,12 +521,24 @@ type RuntimeConfig struct {
 	Name string
 	// K8s API client
 	Client kubernetes.Interface
+	// K8s API client for the custom resources
+	DynamicClient dynamic.Interface
 	// Cache to store PVs managed by this provisioner
 	Cache *cache.VolumeCache
 	// K8s API layer
 	APIUtil util.APIUtil
 	// Volume util layer
 	VolUtil util.VolumeUtil
+	// Partition util layer
+	PartitionUtil util.PartitionUtil
+	// Format util layer
+	FormatUtil util.FormatUtil
+	// LVM util layer
+	LVMUtil util.LVMUtil
+	// Quota util layer
+	QuotaUtil util.QuotaUtil
+	// Health util layer
+	HealthUtil util.HealthUtil
 	// Recorder is used to record events in the API server
 	Recorder record.EventRecorder
 	// Disable block device discovery and management if true
@@ -174,6 +547,13 @@ type RuntimeConfig struct {
 	Mounter mount.Interface
 	// InformerFactory gives access to informers for the controller.
 	InformerFactory informers.SharedInformerFactory
+	// Never change the disks nor the custom resources if true, the changes
+	// to the PVs are only recorded by APIUtil.
+	DryRun bool
+	// VolumeLock serializes the changes to the volumes of the node made
+	// concurrently by the discovery, the deleter and the dynamic provisioner,
+	// like the creation of logical volumes or the allocation of project IDs.
+	VolumeLock sync.Mutex
 }
 
 // LocalPVConfig defines the parameters for creating a local PV
@@ -194,6 +574,8 @@ type LocalPVConfig struct {
 	Labels          map[string]string
 	SetPVOwnerRef   bool
 	OwnerReference  *metav1.OwnerReference
+	Annotations     map[string]string
+	ClaimRef        *v1.ObjectReference
 }
 
 // BuildConfigFromFlags being defined to enable mocking during unit testing
@@ -212,6 +594,10 @@ type ProvisionerConfiguration struct {
 	// NodeLabelsForPV contains a list of node labels to be copied to the PVs created by the provisioner
 	// +optional
 	NodeLabelsForPV []string `json:"nodeLabelsForPV" yaml:"nodeLabelsForPV"`
+	// NodeLabelsForPVAffinity contains a list of node labels, e.g. zone or rack labels, whose
+	// values on the node are required by the node affinity of the PVs in addition to the hostname
+	// +optional
+	NodeLabelsForPVAffinity []string `json:"nodeLabelsForPVAffinity" yaml:"nodeLabelsForPVAffinity"`
 	// UseAlphaAPI shows if we need to use alpha API, default to false
 	UseAlphaAPI bool `json:"useAlphaAPI" yaml:"useAlphaAPI"`
 	// UseJobForCleaning indicates if Jobs should be spawned for cleaning block devices (as opposed to process),
@@ -240,6 +626,127 @@ type ProvisionerConfiguration struct {
 	// ProvisionerNotReadyNodeTaintKey is the key of the startup taint that provisioner will remove once it becomes ready.
 	// +optional
 	ProvisionerNotReadyNodeTaintKey string `json:"provisionerNotReadyNodeTaintKey" yaml:"provisionerNotReadyNodeTaintKey"`
+	// UseWatchForDiscovery indicates if the discovery directories and the mount table should be
+	// watched, so that new volumes are discovered as soon as they show up. Default is false.
+	// +optional
+	UseWatchForDiscovery bool `json:"useWatchForDiscovery" yaml:"useWatchForDiscovery"`
+	// DiscoveryResyncPeriod is the period of the full discovery scan which is still done as a
+	// safety net when UseWatchForDiscovery is set. Default is 5m.
+	// +optional
+	DiscoveryResyncPeriod metav1.Duration `json:"discoveryResyncPeriod" yaml:"discoveryResyncPeriod"`
+	// MissingVolumePolicy defines what happens to the available PVs whose volume is missing,
+	// either annotate or delete. Default is annotate.
+	// +optional
+	MissingVolumePolicy string `json:"missingVolumePolicy" yaml:"missingVolumePolicy"`
+	// HealthCheckPeriod is the period at which the SMART logs and the I/O error counters of
+	// the disks of the volumes are read. Default is 0, which disables the health checks.
+	// +optional
+	HealthCheckPeriod metav1.Duration `json:"healthCheckPeriod" yaml:"healthCheckPeriod"`
+	// PublishStorageCapacity indicates if the total and the maximum size of the unbound
+	// available PVs of each storage class should be published in a CSIStorageCapacity object
+	// per node and storage class, in the namespace of the provisioner. Default is false.
+	// +optional
+	PublishStorageCapacity bool `json:"publishStorageCapacity" yaml:"publishStorageCapacity"`
+	// PublishInventory indicates if every path seen in the discovery directories should be
+	// listed, with the PV created for it or the reason why it was skipped, in the status of
+	// the LocalVolumeInventory named after the node. Default is false.
+	// +optional
+	PublishInventory bool `json:"publishInventory" yaml:"publishInventory"`
+	// Reservations reserve volumes of the nodes for claims. The PV of a reserved volume is
+	// pre-bound to its claim when it is created, and when it is found available.
+	// +optional
+	Reservations []Reservation `json:"reservations" yaml:"reservations"`
+	// MaxConcurrentCleanups is the maximum number of released volumes cleaned at the same
+	// time on a node when jobs are not used for cleaning. The cleanups over the limit wait in
+	// a queue. Default is 0, which means unlimited.
+	// +optional
+	MaxConcurrentCleanups int `json:"maxConcurrentCleanups" yaml:"maxConcurrentCleanups"`
+	// ProcTableCheckpointPath is the path of a file, on a host path volume, where the state of
+	// the cleanup processes is checkpointed so that it survives restarts of the provisioner.
+	// It is only read when the provisioner starts. Default is empty, which keeps the state in
+	// memory only.
+	// +optional
+	ProcTableCheckpointPath string `json:"procTableCheckpointPath" yaml:"procTableCheckpointPath"`
+	// InterruptedCleanupPolicy defines what happens to the cleanup processes which were
+	// interrupted by a restart of the provisioner, found in the checkpoint at
+	// ProcTableCheckpointPath: restart them, keeping their start time, or fail them, which
+	// counts against the retry policy of their storage class. Default is restart. Resuming
+	// them is not supported: the cleanup commands and scripts don't record their progress,
+	// so a restarted cleanup starts over.
+	// +optional
+	InterruptedCleanupPolicy string `json:"interruptedCleanupPolicy" yaml:"interruptedCleanupPolicy"`
+}
+
+// GenerateNodeSelector returns the node selector term of the PVs created on
+// the node, which requires the hostname of the node and the values of its
+// affinityLabels, e.g. its zone or rack. The labels missing on the node are
+// skipped.
+func GenerateNodeSelector(node *v1.Node, affinityLabels []string) (*v1.NodeSelector, error) {
+	if node.Labels == nil {
+		return nil, fmt.Errorf("Node does not have labels")
+	}
+	nodeValue, found := node.Labels[NodeLabelKey]
+	if !found {
+		return nil, fmt.Errorf("Node does not have expected label %s", NodeLabelKey)
+	}
+
+	requirements := []v1.NodeSelectorRequirement{
+		{
+			Key:      NodeLabelKey,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{nodeValue},
+		},
+	}
+	for _, labelName := range affinityLabels {
+		if slices.ContainsFunc(requirements, func(r v1.NodeSelectorRequirement) bool { return r.Key == labelName }) {
+			continue
+		}
+		labelValue, ok := node.Labels[labelName]
+		if !ok {
+			klog.Warningf("Node %q does not have label %s, not adding it to the node affinity of PVs", node.Name, labelName)
+			continue
+		}
+		requirements = append(requirements, v1.NodeSelectorRequirement{
+			Key:      labelName,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{labelValue},
+		})
+	}
+
+	return &v1.NodeSelector{
+		NodeSelectorTerms: []v1.NodeSelectorTerm{
+			{
+				MatchExpressions: requirements,
+			},
+		},
+	}, nil
+}
+
+// VolumeNodeSelector returns the node selector of the PVs of a storage class,
+// which adds the selector terms of the class as alternatives to the terms of
+// nodeSelector, or ANDs the requirements of nodeSelector into each of them if
+// the class sets SelectorRequiresNode.
+func VolumeNodeSelector(nodeSelector *v1.NodeSelector, config MountConfig) *v1.NodeSelector {
+	selector := config.Selector
+	if len(selector) == 0 {
+		return nodeSelector
+	}
+	if !config.SelectorRequiresNode {
+		return &v1.NodeSelector{
+			NodeSelectorTerms: slices.Concat(nodeSelector.NodeSelectorTerms, selector),
+		}
+	}
+	var requirements []v1.NodeSelectorRequirement
+	for _, term := range nodeSelector.NodeSelectorTerms {
+		requirements = append(requirements, term.MatchExpressions...)
+	}
+	volumeNodeSelector := &v1.NodeSelector{}
+	for _, term := range selector {
+		term := *term.DeepCopy()
+		term.MatchExpressions = slices.Concat(requirements, term.MatchExpressions)
+		volumeNodeSelector.NodeSelectorTerms = append(volumeNodeSelector.NodeSelectorTerms, term)
+	}
+	return volumeNodeSelector
 }
 
 // CreateLocalPVSpec returns a PV spec that can be used for PV creation
@@ -269,9 +776,14 @@ func CreateLocalPVSpec(config *LocalPVConfig) *v1.PersistentVolume {
 			StorageClassName: config.StorageClass,
 			VolumeMode:       &config.VolumeMode,
 			MountOptions:     config.MountOptions,
+			ClaimRef:         config.ClaimRef,
 		},
 	}
 
+	for key, value := range config.Annotations {
+		pv.ObjectMeta.Annotations[key] = value
+	}
+
 	if config.AccessMode == "" {
 		pv.Spec.AccessModes = []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce}
 	}
@@ -293,12 +805,17 @@ func CreateLocalPVSpec(config *LocalPVConfig) *v1.PersistentVolume {
 
 // GetContainerPath gets the local path (within provisioner container) of the PV
 func GetContainerPath(pv *v1.PersistentVolume, config MountConfig) (string, error) {
-	relativePath, err := filepath.Rel(config.HostDir, pv.Spec.Local.Path)
+	hostDir, mountDir := config.HostDir, config.MountDir
+	// Volumes formatted and mounted by the provisioner live in their own directory.
+	if config.FormatAndMount != nil && mount.PathWithinBase(pv.Spec.Local.Path, config.FormatAndMount.HostDir) {
+		hostDir, mountDir = config.FormatAndMount.HostDir, config.FormatAndMount.MountDir
+	}
+	relativePath, err := filepath.Rel(hostDir, pv.Spec.Local.Path)
 	if err != nil {
 		return "", fmt.Errorf("Could not get relative path for pv %q: %v", pv.Name, err)
 	}
 
-	return filepath.Join(config.MountDir, relativePath), nil
+	return filepath.Join(mountDir, relativePath), nil
 }
 
 // GetVolumeConfigFromConfigMap gets volume configuration from given configmap.
@@ -326,6 +843,13 @@ func VolumeConfigToConfigMapData(config *ProvisionerConfiguration) (map[string]s
 		}
 		configMapData[ProvisionerNodeLabelsForPV] = string(nodeLabels)
 	}
+	if len(config.NodeLabelsForPVAffinity) > 0 {
+		nodeLabels, nlErr := yaml.Marshal(config.NodeLabelsForPVAffinity)
+		if nlErr != nil {
+			return nil, fmt.Errorf("unable to Marshal node affinity label: %v", nlErr)
+		}
+		configMapData[ProvisionerNodeLabelsForPVAffinity] = string(nodeLabels)
+	}
 	ver, err := yaml.Marshal(config.UseAlphaAPI)
 	if err != nil {
 		return nil, fmt.Errorf("unable to Marshal API version config: %v", err)
@@ -347,6 +871,22 @@ func ConfigMapDataToVolumeConfig(data map[string]string, provisionerConfig *Prov
 	if err := yaml.Unmarshal([]byte(rawYaml), provisionerConfig); err != nil {
 		return fmt.Errorf("fail to Unmarshal yaml due to: %#v", err)
 	}
+	switch provisionerConfig.MissingVolumePolicy {
+	case "", MissingVolumePolicyAnnotate, MissingVolumePolicyDelete:
+	default:
+		return fmt.Errorf("unsupported missingVolumePolicy %q", provisionerConfig.MissingVolumePolicy)
+	}
+	if err := validateReservations(provisionerConfig.Reservations); err != nil {
+		return err
+	}
+	if provisionerConfig.MaxConcurrentCleanups < 0 {
+		return fmt.Errorf("maxConcurrentCleanups must not be negative")
+	}
+	switch provisionerConfig.InterruptedCleanupPolicy {
+	case "", InterruptedCleanupRestart, InterruptedCleanupFail:
+	default:
+		return fmt.Errorf("unsupported interruptedCleanupPolicy %q", provisionerConfig.InterruptedCleanupPolicy)
+	}
 	for class, config := range provisionerConfig.StorageClassConfig {
 		if config.BlockCleanerCommand == nil {
 			// Supply a default block cleaner command.
@@ -356,6 +896,20 @@ func ConfigMapDataToVolumeConfig(data map[string]string, provisionerConfig *Prov
 			if len(config.BlockCleanerCommand) < 1 {
 				return fmt.Errorf("Invalid empty block cleaner command for class %v", class)
 			}
+			if _, err := ParseBlockCleanerCommand(config.BlockCleanerCommand); err != nil {
+				return fmt.Errorf("Storage Class %v is misconfigured, invalid blockCleanerCommand: %v", class, err)
+			}
+		}
+		if config.LVM != nil {
+			if err := validateLVM(config.LVM, config.DynamicProvisioning); err != nil {
+				return fmt.Errorf("Storage Class %v is misconfigured, invalid lvm: %v", class, err)
+			}
+			if config.HostDir == "" {
+				config.HostDir = "/dev/" + config.LVM.VolumeGroup
+			}
+			if config.MountDir == "" {
+				config.MountDir = config.HostDir
+			}
 		}
 		if config.MountDir == "" || config.HostDir == "" {
 			return fmt.Errorf("Storage Class %v is misconfigured, missing HostDir or MountDir parameter", class)
@@ -375,6 +929,100 @@ func ConfigMapDataToVolumeConfig(data map[string]string, provisionerConfig *Prov
 			return fmt.Errorf("unsupported volume mode %s", config.VolumeMode)
 		}
 
+		for _, rule := range config.HotplugRules {
+			if rule.DevNamePattern == "" {
+				return fmt.Errorf("Storage Class %v is misconfigured, hotplug rule is missing devNamePattern", class)
+			}
+			if _, err := filepath.Match(rule.DevNamePattern, ""); err != nil {
+				return fmt.Errorf("Storage Class %v is misconfigured, invalid hotplug devNamePattern %q: %v", class, rule.DevNamePattern, err)
+			}
+			if rule.DevType != "" && rule.DevType != DevTypeDisk && rule.DevType != DevTypePartition {
+				return fmt.Errorf("Storage Class %v is misconfigured, unsupported hotplug devType %q", class, rule.DevType)
+			}
+		}
+
+		for _, expr := range config.IncludeRegex {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("Storage Class %v is misconfigured, invalid includeRegex %q: %v", class, expr, err)
+			}
+		}
+		for _, pattern := range config.ExcludePatterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("Storage Class %v is misconfigured, invalid excludePatterns %q: %v", class, pattern, err)
+			}
+		}
+		if config.MaxDepth < 0 {
+			return fmt.Errorf("Storage Class %v is misconfigured, maxDepth must not be negative", class)
+		}
+		if config.MaxDepth > 1 && (config.LVM != nil || config.DirectoryPool != nil || config.Partitioning != nil || config.FormatAndMount != nil || config.DynamicProvisioning) {
+			return fmt.Errorf("Storage Class %v is misconfigured, maxDepth does not support lvm, directoryPool, partitioning, formatAndMount or dynamicProvisioning", class)
+		}
+
+		if err := validateCapacityPolicy(config.CapacityPolicy); err != nil {
+			return fmt.Errorf("Storage Class %v is misconfigured, invalid capacityPolicy: %v", class, err)
+		}
+
+		if err := validateCleanupPolicy(config.CleanupPolicy); err != nil {
+			return fmt.Errorf("Storage Class %v is misconfigured, invalid cleanupPolicy: %v", class, err)
+		}
+		// The deletion of the contents of Filesystem volumes and the
+		// recreation of logical volumes can't be interrupted.
+		if config.CleanupPolicy != nil && config.CleanupPolicy.Timeout.Duration > 0 &&
+			(volumeMode == v1.PersistentVolumeFilesystem || (config.LVM != nil && config.LVM.RecreateOnDelete)) {
+			return fmt.Errorf("Storage Class %v is misconfigured, cleanupPolicy timeout is only supported by the block cleaners of Block classes", class)
+		}
+
+		if config.IdentityMode != "" && config.IdentityMode != IdentityModePath && config.IdentityMode != IdentityModeDevice {
+			return fmt.Errorf("Storage Class %v is misconfigured, unsupported identityMode %q", class, config.IdentityMode)
+		}
+
+		if err := validateDeviceSelector(config.DeviceSelector); err != nil {
+			return fmt.Errorf("Storage Class %v is misconfigured, invalid deviceSelector: %v", class, err)
+		}
+
+		if err := validatePartitioning(config.Partitioning); err != nil {
+			return fmt.Errorf("Storage Class %v is misconfigured, invalid partitioning: %v", class, err)
+		}
+
+		if config.FormatAndMount != nil {
+			if volumeMode != v1.PersistentVolumeFilesystem || config.FsType == "" {
+				return fmt.Errorf("Storage Class %v is misconfigured, formatAndMount requires volumeMode Filesystem and fsType", class)
+			}
+			if config.FormatAndMount.HostDir == "" || config.FormatAndMount.HostDir == config.HostDir {
+				return fmt.Errorf("Storage Class %v is misconfigured, formatAndMount requires a hostDir other than the one of the class", class)
+			}
+			if config.FormatAndMount.MountDir == "" {
+				config.FormatAndMount.MountDir = config.FormatAndMount.HostDir
+			}
+			config.FormatAndMount.HostDir = normalizePath(config.FormatAndMount.HostDir)
+			config.FormatAndMount.MountDir = normalizePath(config.FormatAndMount.MountDir)
+		}
+
+		if config.DirectoryPool != nil {
+			if err := validateDirectoryPool(config.DirectoryPool); err != nil {
+				return fmt.Errorf("Storage Class %v is misconfigured, invalid directoryPool: %v", class, err)
+			}
+			if volumeMode != v1.PersistentVolumeFilesystem {
+				return fmt.Errorf("Storage Class %v is misconfigured, directoryPool requires volumeMode Filesystem", class)
+			}
+			if config.LVM != nil || config.Partitioning != nil || config.FormatAndMount != nil || config.DynamicProvisioning {
+				return fmt.Errorf("Storage Class %v is misconfigured, directoryPool does not support lvm, partitioning, formatAndMount or dynamicProvisioning", class)
+			}
+		}
+
+		if config.VolumeMetadata && (config.LVM != nil || config.DynamicProvisioning) {
+			return fmt.Errorf("Storage Class %v is misconfigured, volumeMetadata does not support lvm or dynamicProvisioning", class)
+		}
+
+		if config.DynamicProvisioning {
+			if config.Partitioning != nil || config.DeviceSelector != nil || len(config.HotplugRules) > 0 {
+				return fmt.Errorf("Storage Class %v is misconfigured, dynamicProvisioning does not support partitioning, deviceSelector or hotplugRules", class)
+			}
+			if config.LVM == nil && volumeMode != v1.PersistentVolumeFilesystem {
+				return fmt.Errorf("Storage Class %v is misconfigured, dynamicProvisioning of directories requires volumeMode Filesystem", class)
+			}
+		}
+
 		provisionerConfig.StorageClassConfig[class] = config
 		klog.V(5).Infof("StorageClass %q configured with MountDir %q, HostDir %q, VolumeMode %q, FsType %q, BlockCleanerCommand %q, NamePattern %q",
 			class,
@@ -388,6 +1036,182 @@ func ConfigMapDataToVolumeConfig(data map[string]string, provisionerConfig *Prov
 	return nil
 }
 
+func validateReservations(reservations []Reservation) error {
+	reserved := map[Reservation]bool{}
+	for i := range reservations {
+		r := &reservations[i]
+		if r.Node == "" || r.StorageClass == "" || r.Path == "" || r.Claim.Namespace == "" || r.Claim.Name == "" {
+			return fmt.Errorf("Reservation %d is misconfigured, node, storageClass, path and claim namespace and name are required", i)
+		}
+		r.Path = normalizePath(filepath.Clean(r.Path))
+		volume := Reservation{Node: r.Node, StorageClass: r.StorageClass, Path: r.Path}
+		if reserved[volume] {
+			return fmt.Errorf("Reservation %d is misconfigured, volume %q of storage class %v on node %v is already reserved", i, r.Path, r.StorageClass, r.Node)
+		}
+		reserved[volume] = true
+	}
+	return nil
+}
+
+func validateDeviceSelector(selector *DeviceSelector) error {
+	if selector == nil {
+		return nil
+	}
+	if selector.DevType != "" && selector.DevType != DevTypeDisk && selector.DevType != DevTypePartition {
+		return fmt.Errorf("unsupported devType %q", selector.DevType)
+	}
+	for _, pattern := range []string{selector.Model, selector.Vendor, selector.Serial, selector.WWN, selector.ByID, selector.ByPath} {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	switch selector.Transport {
+	case "", util.TransportNVMe, util.TransportSATA, util.TransportSAS, util.TransportSCSI, util.TransportUSB, util.TransportVirtio:
+	default:
+		return fmt.Errorf("unsupported transport %q", selector.Transport)
+	}
+	if selector.MinSize != nil && selector.MaxSize != nil && selector.MinSize.Cmp(*selector.MaxSize) > 0 {
+		return fmt.Errorf("minSize %s is greater than maxSize %s", selector.MinSize.String(), selector.MaxSize.String())
+	}
+	return nil
+}
+
+func validatePartitioning(partitioning *Partitioning) error {
+	if partitioning == nil {
+		return nil
+	}
+	if (partitioning.Count == 0) == (partitioning.Size == nil) {
+		return fmt.Errorf("exactly one of count and size must be set")
+	}
+	if partitioning.Count < 0 || partitioning.Count > MaxPartitions {
+		return fmt.Errorf("count %d is not between 1 and %d", partitioning.Count, MaxPartitions)
+	}
+	if partitioning.Size != nil && partitioning.Size.Value() < 1024*1024 {
+		return fmt.Errorf("size %s is smaller than 1Mi", partitioning.Size.String())
+	}
+	return nil
+}
+
+// IsBuiltinBlockCleaner returns true if name is the name of a built-in block
+// cleaner.
+func IsBuiltinBlockCleaner(name string) bool {
+	switch name {
+	case BlockCleanerDiscard, BlockCleanerSecureDiscard, BlockCleanerZeroOut, BlockCleanerOverwrite, BlockCleanerWipeSignatures:
+		return true
+	}
+	return false
+}
+
+// ParseBlockCleanerCommand returns the number of passes of the built-in block
+// cleaner of command, which only the overwrite cleaner accepts as argument and
+// defaults to 1. Commands which are not built-in block cleaners are run as is.
+func ParseBlockCleanerCommand(command []string) (int, error) {
+	if len(command) == 0 || !IsBuiltinBlockCleaner(command[0]) {
+		return 0, nil
+	}
+	args := command[1:]
+	if command[0] != BlockCleanerOverwrite {
+		if len(args) > 0 {
+			return 0, fmt.Errorf("block cleaner %q takes no arguments", command[0])
+		}
+		return 1, nil
+	}
+	if len(args) == 0 {
+		return 1, nil
+	}
+	passes, err := strconv.Atoi(args[0])
+	if len(args) > 1 || err != nil || passes < 1 {
+		return 0, fmt.Errorf("block cleaner %q takes the number of passes as only argument", command[0])
+	}
+	return passes, nil
+}
+
+func validateCapacityPolicy(policy *CapacityPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	switch policy.Rounding {
+	case "", CapacityRoundingPretty, CapacityRoundingExact:
+		if policy.RoundingUnit != nil {
+			return fmt.Errorf("roundingUnit requires rounding %q", CapacityRoundingUnit)
+		}
+	case CapacityRoundingUnit:
+		if policy.RoundingUnit == nil || policy.RoundingUnit.Sign() <= 0 {
+			return fmt.Errorf("roundingUnit must be positive")
+		}
+	default:
+		return fmt.Errorf("unsupported rounding %q", policy.Rounding)
+	}
+	if _, err := policy.ReservedBytes(0); err != nil {
+		return err
+	}
+	switch policy.FsCapacity {
+	case "", FsCapacityTotal, FsCapacityAvailable:
+	default:
+		return fmt.Errorf("unsupported fsCapacity %q", policy.FsCapacity)
+	}
+	return nil
+}
+
+func validateCleanupPolicy(policy *CleanupPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxConcurrent < 0 {
+		return fmt.Errorf("maxConcurrent must not be negative")
+	}
+	if policy.Timeout.Duration < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if policy.MaxRetries != nil && *policy.MaxRetries < 0 {
+		return fmt.Errorf("maxRetries must not be negative")
+	}
+	if policy.RetryBackoff.Duration < 0 {
+		return fmt.Errorf("retryBackoff must not be negative")
+	}
+	return nil
+}
+
+func validateDirectoryPool(pool *DirectoryPool) error {
+	if pool.Count <= 0 {
+		return fmt.Errorf("count %d is not positive", pool.Count)
+	}
+	if pool.Size == nil || pool.Size.Sign() <= 0 {
+		return fmt.Errorf("size must be positive")
+	}
+	return nil
+}
+
+func validateLVM(lvm *LVMConfig, dynamic bool) error {
+	if lvm.VolumeGroup == "" {
+		return fmt.Errorf("missing volumeGroup")
+	}
+	if dynamic {
+		if lvm.Count != 0 || lvm.Size != nil || lvm.RecreateOnDelete {
+			return fmt.Errorf("count, size and recreateOnDelete are not supported with dynamicProvisioning")
+		}
+		return nil
+	}
+	if lvm.Count < 0 {
+		return fmt.Errorf("negative count %d", lvm.Count)
+	}
+	if lvm.Count == 0 && lvm.Size == nil {
+		return fmt.Errorf("at least one of count and size must be set")
+	}
+	if lvm.ThinPool != "" && (lvm.Count == 0 || lvm.Size == nil) {
+		return fmt.Errorf("thin volumes require both count and size")
+	}
+	if lvm.Size != nil && lvm.Size.Sign() <= 0 {
+		return fmt.Errorf("size %s is not positive", lvm.Size.String())
+	}
+	if lvm.RecreateOnDelete && lvm.ThinPool == "" {
+		// A thick logical volume created again gets the same extents back,
+		// with the data of the previous claim.
+		return fmt.Errorf("recreateOnDelete requires a thinPool")
+	}
+	return nil
+}
+
 // normalizePath makes sure the given path is a valid path on Windows too
 // by making sure all instances of `/` are replaced with `\\`, and the
 // path beings with `c:`
@@ -418,6 +1242,7 @@ func UserConfigFromProvisionerConfig(node *v1.Node, namespace, jobImage string,
 		Node:                            node,
 		DiscoveryMap:                    config.StorageClassConfig,
 		NodeLabelsForPV:                 config.NodeLabelsForPV,
+		NodeLabelsForPVAffinity:         config.NodeLabelsForPVAffinity,
 		UseAlphaAPI:                     config.UseAlphaAPI,
 		UseJobForCleaning:               config.UseJobForCleaning,
 		MinResyncPeriod:                 config.MinResyncPeriod,
@@ -429,6 +1254,14 @@ func UserConfigFromProvisionerConfig(node *v1.Node, namespace, jobImage string,
 		SetPVOwnerRef:                   config.SetPVOwnerRef,
 		RemoveNodeNotReadyTaint:         config.RemoveNodeNotReadyTaint,
 		ProvisionerNotReadyNodeTaintKey: config.ProvisionerNotReadyNodeTaintKey,
+		UseWatchForDiscovery:            config.UseWatchForDiscovery,
+		DiscoveryResyncPeriod:           config.DiscoveryResyncPeriod,
+		MissingVolumePolicy:             config.MissingVolumePolicy,
+		HealthCheckPeriod:               config.HealthCheckPeriod,
+		PublishStorageCapacity:          config.PublishStorageCapacity,
+		PublishInventory:                config.PublishInventory,
+		Reservations:                    config.Reservations,
+		MaxConcurrentCleanups:           config.MaxConcurrentCleanups,
 	}
 }
 
@@ -457,6 +1290,23 @@ func LoadProvisionerConfigs(configPath string, provisionerConfig *ProvisionerCon
 
 // SetupClient created client using either in-cluster configuration or if KUBECONFIG environment variable is specified then using that config.
 func SetupClient() *kubernetes.Clientset {
+	clientset, err := kubernetes.NewForConfig(setupClientConfig())
+	if err != nil {
+		klog.Fatalf("Error creating clientset: %v\n", err)
+	}
+	return clientset
+}
+
+// SetupDynamicClient returns a dynamic client for the custom resources of the provisioner.
+func SetupDynamicClient() dynamic.Interface {
+	client, err := dynamic.NewForConfig(setupClientConfig())
+	if err != nil {
+		klog.Fatalf("Error creating dynamic client: %v\n", err)
+	}
+	return client
+}
+
+func setupClientConfig() *rest.Config {
 	var config *rest.Config
 	var err error
 
@@ -475,12 +1325,7 @@ func SetupClient() *kubernetes.Clientset {
 		}
 		klog.Infof("Creating client using in-cluster config")
 	}
-
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		klog.Fatalf("Error creating clientset: %v\n", err)
-	}
-	return clientset
+	return config
 }
 
 // GenerateMountName generates a volumeMount.name for pod spec, based on volume configuration.
@@ -519,6 +1364,83 @@ func GetVolumeMode(volUtil util.VolumeUtil, fullPath string) (v1.PersistentVolum
 	return "", fmt.Errorf("Block device check for %q failed: %s", fullPath, errblk)
 }
 
+// GetVolumeFingerprint returns a fingerprint identifying the physical device
+// backing the volume at fullPath independently of the path: the WWN or the
+// model and serial of a block device, or the UUID of its filesystem.
+func GetVolumeFingerprint(volUtil util.VolumeUtil, fullPath string, volMode v1.PersistentVolumeMode) (string, error) {
+	if volMode == v1.PersistentVolumeFilesystem {
+		uuid, err := volUtil.GetFsUUID(fullPath)
+		if err != nil {
+			return "", err
+		}
+		return "fsuuid:" + uuid, nil
+	}
+
+	attrs, err := volUtil.GetDeviceAttributes(fullPath)
+	if err != nil {
+		return "", err
+	}
+	var fingerprint string
+	switch {
+	case attrs.WWN != "":
+		fingerprint = "wwn:" + attrs.WWN
+	case attrs.Serial != "":
+		fingerprint = "serial:" + attrs.Model + ":" + attrs.Serial
+	case attrs.FsUUID != "":
+		return "fsuuid:" + attrs.FsUUID, nil
+	default:
+		return "", fmt.Errorf("no WWN, serial or filesystem UUID found for %q", fullPath)
+	}
+	// Partitions share the WWN and serial of their disk.
+	if attrs.PartitionNumber > 0 {
+		fingerprint = fmt.Sprintf("%s:part%d", fingerprint, attrs.PartitionNumber)
+	}
+	return fingerprint, nil
+}
+
+// GetProjectIDs returns the project IDs of the entries of the directory at
+// fullPath by name, 0 for the entries without a project.
+func GetProjectIDs(volUtil util.VolumeUtil, quotaUtil util.QuotaUtil, fullPath string) (map[string]uint32, error) {
+	files, err := volUtil.ReadDir(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	projectIDs := map[string]uint32{}
+	for _, file := range files {
+		projectID, err := quotaUtil.GetProjectID(filepath.Join(fullPath, file))
+		if err != nil {
+			return nil, err
+		}
+		projectIDs[file] = projectID
+	}
+	return projectIDs, nil
+}
+
+// NewProjectID returns a project ID for the directory at fullPath which is
+// neither one of the given project IDs nor a project of the filesystem of the
+// directory, which may be shared with other classes. It is derived from the
+// path, so that concurrent provisioners are unlikely to pick the same one.
+func NewProjectID(quotaUtil util.QuotaUtil, fullPath string, projectIDs map[string]uint32) (uint32, error) {
+	quotas, err := quotaUtil.ListProjectQuotas(filepath.Dir(fullPath))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list projects of the filesystem of %q: %v", fullPath, err)
+	}
+	used := map[uint32]bool{0: true}
+	for projectID := range quotas {
+		used[projectID] = true
+	}
+	for _, projectID := range projectIDs {
+		used[projectID] = true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(fullPath))
+	projectID := h.Sum32()
+	for used[projectID] {
+		projectID++
+	}
+	return projectID, nil
+}
+
 // AnyNodeExists checks to see if a Node exists in the Indexer of a NodeLister.
 // If this fails, it uses the well known label `kubernetes.io/hostname` to find the Node.
 // It aborts early if an unexpected error occurs and it's uncertain if a node would exist or not.
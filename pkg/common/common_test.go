@@ -92,6 +92,7 @@ func TestLoadProvisionerConfigs(t *testing.T) {
 	defer func() {
 		os.RemoveAll(tmpConfigPath)
 	}()
+	maxRetries := 3
 	testcases := []struct {
 		data        map[string]string
 		expected    ProvisionerConfiguration
@@ -276,6 +277,681 @@ func TestLoadProvisionerConfigs(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   volumeMode: Block
+   hotplugRules:
+     - devNamePattern: "nvme*n1"
+       devType: disk
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:             "/mnt/disks",
+						MountDir:            "/mnt/disks",
+						BlockCleanerCommand: []string{"/scripts/quick_reset.sh"},
+						VolumeMode:          "Block",
+						NamePattern:         "*",
+						HotplugRules: []HotplugRule{
+							{DevNamePattern: "nvme*n1", DevType: DevTypeDisk},
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			nil,
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   volumeMode: Block
+   hotplugRules:
+     - devNamePattern: "nvme*n1"
+       devType: loop
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:    "/mnt/disks",
+						MountDir:   "/mnt/disks",
+						VolumeMode: "Block",
+						HotplugRules: []HotplugRule{
+							{DevNamePattern: "nvme*n1", DevType: "loop"},
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, unsupported hotplug devType %q", "loop"),
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   hostDir: /dev
+   mountDir: /dev
+   volumeMode: Block
+   deviceSelector:
+     transport: nvme
+     rotational: false
+     minSize: 1Ti
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:             "/dev",
+						MountDir:            "/dev",
+						BlockCleanerCommand: []string{"/scripts/quick_reset.sh"},
+						VolumeMode:          "Block",
+						NamePattern:         "*",
+						DeviceSelector: &DeviceSelector{
+							Transport:  "nvme",
+							Rotational: &[]bool{false}[0],
+							MinSize:    &[]resource.Quantity{resource.MustParse("1Ti")}[0],
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			nil,
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   hostDir: /dev
+   mountDir: /dev
+   volumeMode: Block
+   deviceSelector:
+     transport: ide
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:    "/dev",
+						MountDir:   "/dev",
+						VolumeMode: "Block",
+						DeviceSelector: &DeviceSelector{
+							Transport: "ide",
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, invalid deviceSelector: %v", fmt.Errorf("unsupported transport %q", "ide")),
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   volumeMode: Block
+   partitioning:
+     count: 4
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:             "/mnt/disks",
+						MountDir:            "/mnt/disks",
+						BlockCleanerCommand: []string{"/scripts/quick_reset.sh"},
+						VolumeMode:          "Block",
+						NamePattern:         "*",
+						Partitioning: &Partitioning{
+							Count: 4,
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			nil,
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   volumeMode: Block
+   partitioning:
+     count: 4
+     size: 100Gi
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:    "/mnt/disks",
+						MountDir:   "/mnt/disks",
+						VolumeMode: "Block",
+						Partitioning: &Partitioning{
+							Count: 4,
+							Size:  &[]resource.Quantity{resource.MustParse("100Gi")}[0],
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, invalid partitioning: %v", fmt.Errorf("exactly one of count and size must be set")),
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   fsType: ext4
+   formatAndMount:
+     hostDir: /mnt/formatted
+     mkfsOptions: ["-m", "0"]
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:             "/mnt/disks",
+						MountDir:            "/mnt/disks",
+						BlockCleanerCommand: []string{"/scripts/quick_reset.sh"},
+						VolumeMode:          "Filesystem",
+						FsType:              "ext4",
+						NamePattern:         "*",
+						FormatAndMount: &FormatAndMount{
+							HostDir:     "/mnt/formatted",
+							MountDir:    "/mnt/formatted",
+							MkfsOptions: []string{"-m", "0"},
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			nil,
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   volumeMode: Block
+   fsType: ext4
+   formatAndMount:
+     hostDir: /mnt/formatted
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:    "/mnt/disks",
+						MountDir:   "/mnt/disks",
+						VolumeMode: "Block",
+						FsType:     "ext4",
+						FormatAndMount: &FormatAndMount{
+							HostDir: "/mnt/formatted",
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, formatAndMount requires volumeMode Filesystem and fsType"),
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   volumeMode: Block
+   lvm:
+     volumeGroup: vg0
+     count: 8
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:             "/dev/vg0",
+						MountDir:            "/dev/vg0",
+						BlockCleanerCommand: []string{"/scripts/quick_reset.sh"},
+						VolumeMode:          "Block",
+						NamePattern:         "*",
+						LVM: &LVMConfig{
+							VolumeGroup: "vg0",
+							Count:       8,
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			nil,
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   volumeMode: Block
+   lvm:
+     volumeGroup: vg0
+     size: 10Gi
+     thinPool: pool0
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						VolumeMode: "Block",
+						LVM: &LVMConfig{
+							VolumeGroup: "vg0",
+							Size:        &[]resource.Quantity{resource.MustParse("10Gi")}[0],
+							ThinPool:    "pool0",
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, invalid lvm: %v", fmt.Errorf("thin volumes require both count and size")),
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   volumeMode: Block
+   lvm:
+     volumeGroup: vg0
+     count: 4
+     recreateOnDelete: true
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						VolumeMode: "Block",
+						LVM: &LVMConfig{
+							VolumeGroup:      "vg0",
+							Count:            4,
+							RecreateOnDelete: true,
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, invalid lvm: %v", fmt.Errorf("recreateOnDelete requires a thinPool")),
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   hostDir: /mnt/pool
+   mountDir: /mnt/pool
+   volumeMode: Block
+   dynamicProvisioning: true
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:             "/mnt/pool",
+						MountDir:            "/mnt/pool",
+						VolumeMode:          "Block",
+						DynamicProvisioning: true,
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, dynamicProvisioning of directories requires volumeMode Filesystem"),
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   hostDir: /mnt/pool
+   mountDir: /mnt/pool
+   directoryPool:
+     count: 4
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:       "/mnt/pool",
+						MountDir:      "/mnt/pool",
+						DirectoryPool: &DirectoryPool{Count: 4},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, invalid directoryPool: %v", fmt.Errorf("size must be positive")),
+		},
+		{
+			map[string]string{"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   dynamicProvisioning: true
+   volumeMetadata: true
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:             "/mnt/disks",
+						MountDir:            "/mnt/disks",
+						DynamicProvisioning: true,
+						VolumeMetadata:      true,
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, volumeMetadata does not support lvm or dynamicProvisioning"),
+		},
+		{
+			map[string]string{
+				"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+`,
+				"missingVolumePolicy": "remove",
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:  "/mnt/disks",
+						MountDir: "/mnt/disks",
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+				MissingVolumePolicy: "remove",
+			},
+			fmt.Errorf("unsupported missingVolumePolicy %q", "remove"),
+		},
+		{
+			map[string]string{
+				"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+`,
+				// Clears the policy of the previous test case
+				"missingVolumePolicy": "",
+				"reservations": `- node: node1
+  storageClass: local-storage
+  path: /mnt/disks/disk1
+  claim:
+    namespace: db
+    name: data-db-0
+- node: node1
+  storageClass: local-storage
+  path: /mnt/disks/disk1/
+  claim:
+    namespace: db
+    name: data-db-1
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:  "/mnt/disks",
+						MountDir: "/mnt/disks",
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+				Reservations: []Reservation{
+					{Node: "node1", StorageClass: "local-storage", Path: "/mnt/disks/disk1", Claim: ClaimReference{Namespace: "db", Name: "data-db-0"}},
+					{Node: "node1", StorageClass: "local-storage", Path: "/mnt/disks/disk1", Claim: ClaimReference{Namespace: "db", Name: "data-db-1"}},
+				},
+			},
+			fmt.Errorf("Reservation %d is misconfigured, volume %q of storage class %v on node %v is already reserved", 1, "/mnt/disks/disk1", "local-storage", "node1"),
+		},
+		{
+			map[string]string{
+				"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   includeRegex: ["^rack[0-9]+/(slot"]
+   maxDepth: 2
+`,
+				// Clears the reservations of the previous test case
+				"reservations": "",
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:      "/mnt/disks",
+						MountDir:     "/mnt/disks",
+						IncludeRegex: []string{"^rack[0-9]+/(slot"},
+						MaxDepth:     2,
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, invalid includeRegex %q: %v", "^rack[0-9]+/(slot", "error parsing regexp: missing closing ): `^rack[0-9]+/(slot`"),
+		},
+		{
+			map[string]string{
+				"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   capacityPolicy:
+     rounding: exact
+     reserve: 120%
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:  "/mnt/disks",
+						MountDir: "/mnt/disks",
+						CapacityPolicy: &CapacityPolicy{
+							Rounding: CapacityRoundingExact,
+							Reserve:  "120%",
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, invalid capacityPolicy: invalid reserve %q, percentage must be an integer between 0 and 99", "120%"),
+		},
+		{
+			map[string]string{
+				"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   blockCleanerCommand: ["overwrite", "many"]
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:             "/mnt/disks",
+						MountDir:            "/mnt/disks",
+						BlockCleanerCommand: []string{"overwrite", "many"},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, invalid blockCleanerCommand: block cleaner %q takes the number of passes as only argument", "overwrite"),
+		},
+		{
+			map[string]string{
+				"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   volumeMode: Block
+   cleanupPolicy:
+     maxConcurrent: 2
+     priority: 1
+     timeout: 2h
+     maxRetries: 3
+     retryBackoff: 5m
+`,
+				"maxConcurrentCleanups": "4",
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:             "/mnt/disks",
+						MountDir:            "/mnt/disks",
+						BlockCleanerCommand: []string{"/scripts/quick_reset.sh"},
+						VolumeMode:          "Block",
+						NamePattern:         "*",
+						CleanupPolicy: &CleanupPolicy{
+							MaxConcurrent: 2,
+							Priority:      1,
+							Timeout:       metav1.Duration{Duration: 2 * time.Hour},
+							MaxRetries:    &maxRetries,
+							RetryBackoff:  metav1.Duration{Duration: 5 * time.Minute},
+						},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+				MaxConcurrentCleanups: 4,
+			},
+			nil,
+		},
+		{
+			map[string]string{
+				"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   cleanupPolicy:
+     maxConcurrent: -1
+`,
+				// Clears the limit of the previous test case
+				"maxConcurrentCleanups": "",
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:       "/mnt/disks",
+						MountDir:      "/mnt/disks",
+						CleanupPolicy: &CleanupPolicy{MaxConcurrent: -1},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, invalid cleanupPolicy: maxConcurrent must not be negative"),
+		},
+		{
+			map[string]string{
+				"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   cleanupPolicy:
+     timeout: -1m
+`,
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:       "/mnt/disks",
+						MountDir:      "/mnt/disks",
+						CleanupPolicy: &CleanupPolicy{Timeout: metav1.Duration{Duration: -time.Minute}},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, invalid cleanupPolicy: timeout must not be negative"),
+		},
+		{
+			map[string]string{
+				"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+`,
+				"procTableCheckpointPath":  "/var/lib/local-static-provisioner/proctable.json",
+				"interruptedCleanupPolicy": "resume",
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:  "/mnt/disks",
+						MountDir: "/mnt/disks",
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+				ProcTableCheckpointPath:  "/var/lib/local-static-provisioner/proctable.json",
+				InterruptedCleanupPolicy: "resume",
+			},
+			fmt.Errorf("unsupported interruptedCleanupPolicy %q", "resume"),
+		},
+		{
+			map[string]string{
+				"storageClassMap": `local-storage:
+   hostDir: /mnt/disks
+   mountDir: /mnt/disks
+   cleanupPolicy:
+     timeout: 1h
+`,
+				// Clears the settings of the previous test case
+				"procTableCheckpointPath":  "",
+				"interruptedCleanupPolicy": "",
+			},
+			ProvisionerConfiguration{
+				StorageClassConfig: map[string]MountConfig{
+					"local-storage": {
+						HostDir:       "/mnt/disks",
+						MountDir:      "/mnt/disks",
+						CleanupPolicy: &CleanupPolicy{Timeout: metav1.Duration{Duration: time.Hour}},
+					},
+				},
+				UseAlphaAPI: true,
+				MinResyncPeriod: metav1.Duration{
+					Duration: time.Hour + time.Minute*30,
+				},
+			},
+			fmt.Errorf("Storage Class local-storage is misconfigured, cleanupPolicy timeout is only supported by the block cleaners of Block classes"),
+		},
 	}
 	for _, v := range testcases {
 		for name, value := range v.data {
@@ -320,6 +996,17 @@ func TestVolumeConfigToConfigMapData(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			provisionerConfig: &ProvisionerConfiguration{
+				NodeLabelsForPVAffinity: []string{"topology.kubernetes.io/zone"},
+			},
+			expected: map[string]string{
+				"storageClassMap":         "null\n",
+				"nodeLabelsForPVAffinity": "- topology.kubernetes.io/zone\n",
+				"useAlphaAPI":             "false\n",
+			},
+			expectedErr: nil,
+		},
 	}
 	for _, test := range testcases {
 		mapData, err := VolumeConfigToConfigMapData(test.provisionerConfig)
@@ -329,6 +1016,70 @@ func TestVolumeConfigToConfigMapData(t *testing.T) {
 	}
 }
 
+func TestGenerateNodeSelector(t *testing.T) {
+	hostnameRequirement := v1.NodeSelectorRequirement{Key: NodeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{"node1"}}
+	zoneRequirement := v1.NodeSelectorRequirement{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"zone1"}}
+	rackRequirement := v1.NodeSelectorRequirement{Key: "example.com/rack", Operator: v1.NodeSelectorOpIn, Values: []string{"rack1"}}
+	nodeLabels := map[string]string{
+		NodeLabelKey:          "node1",
+		v1.LabelTopologyZone:  "zone1",
+		"example.com/rack":    "rack1",
+		"example.com/ignored": "value",
+	}
+	testcases := map[string]struct {
+		labels         map[string]string
+		affinityLabels []string
+		expected       []v1.NodeSelectorRequirement
+		expectErr      bool
+	}{
+		"hostname only": {
+			labels:   nodeLabels,
+			expected: []v1.NodeSelectorRequirement{hostnameRequirement},
+		},
+		"affinity labels in order": {
+			labels:         nodeLabels,
+			affinityLabels: []string{"example.com/rack", v1.LabelTopologyZone},
+			expected:       []v1.NodeSelectorRequirement{hostnameRequirement, rackRequirement, zoneRequirement},
+		},
+		"missing label": {
+			labels:         nodeLabels,
+			affinityLabels: []string{"example.com/missing", v1.LabelTopologyZone},
+			expected:       []v1.NodeSelectorRequirement{hostnameRequirement, zoneRequirement},
+		},
+		"duplicate labels": {
+			labels:         nodeLabels,
+			affinityLabels: []string{NodeLabelKey, v1.LabelTopologyZone, v1.LabelTopologyZone},
+			expected:       []v1.NodeSelectorRequirement{hostnameRequirement, zoneRequirement},
+		},
+		"no hostname label": {
+			labels:         map[string]string{v1.LabelTopologyZone: "zone1"},
+			affinityLabels: []string{v1.LabelTopologyZone},
+			expectErr:      true,
+		},
+		"no labels": {
+			expectErr: true,
+		},
+	}
+	for name, test := range testcases {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: test.labels}}
+		selector, err := GenerateNodeSelector(node, test.affinityLabels)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got none", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		expected := &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{{MatchExpressions: test.expected}}}
+		if !reflect.DeepEqual(selector, expected) {
+			t.Errorf("%s: expected node selector %+v, got %+v", name, expected, selector)
+		}
+	}
+}
+
 func TestCreateLocalPVSpec(t *testing.T) {
 	volumeModeBlock := v1.PersistentVolumeBlock
 	ownerReference := &metav1.OwnerReference{}
@@ -440,6 +1191,27 @@ func TestGetContainerPath(t *testing.T) {
 			expected:    "",
 			expectedErr: fmt.Errorf("Could not get relative path for pv %q: %v", "wrongpath", errors.New("Rel: can't make wrongpath relative to /mnt/disks")),
 		},
+		{
+			pv: &v1.PersistentVolume{
+				Spec: v1.PersistentVolumeSpec{
+					PersistentVolumeSource: v1.PersistentVolumeSource{
+						Local: &v1.LocalVolumeSource{
+							Path: "/mnt/formatted/disk1",
+						},
+					},
+				},
+			},
+			config: MountConfig{
+				HostDir:  "/mnt/disks",
+				MountDir: "/discovery/disks",
+				FormatAndMount: &FormatAndMount{
+					HostDir:  "/mnt/formatted",
+					MountDir: "/discovery/formatted",
+				},
+			},
+			expected:    "/discovery/formatted/disk1",
+			expectedErr: nil,
+		},
 	}
 	for _, test := range testcases {
 		path, err := GetContainerPath(test.pv, test.config)
@@ -517,6 +1289,40 @@ func TestGetVolumeMode(t *testing.T) {
 	}
 }
 
+func TestGetVolumeFingerprint(t *testing.T) {
+	fakeVolUtil := util.NewFakeVolumeUtil(false, map[string][]*util.FakeDirEntry{
+		"/dev": {
+			{Name: "sda", VolumeType: util.FakeEntryBlock, DeviceAttributes: &util.DeviceAttributes{WWN: "0x5000c500a1b2c3d4", Serial: "Z1234"}},
+			{Name: "sda1", VolumeType: util.FakeEntryBlock, DeviceAttributes: &util.DeviceAttributes{WWN: "0x5000c500a1b2c3d4", PartitionNumber: 1}},
+			{Name: "sdb", VolumeType: util.FakeEntryBlock, DeviceAttributes: &util.DeviceAttributes{Model: "HDD", Serial: "Z5678"}},
+			{Name: "vda", VolumeType: util.FakeEntryBlock, DeviceAttributes: &util.DeviceAttributes{FsUUID: "0b4b7e1c"}},
+			{Name: "vdb", VolumeType: util.FakeEntryBlock},
+		},
+		"/mnt/disks": {
+			{Name: "vol1", VolumeType: util.FakeEntryFile, FsUUID: "9e8d7c6b"},
+		},
+	})
+	testcases := []struct {
+		fullPath    string
+		volMode     v1.PersistentVolumeMode
+		expected    string
+		expectedErr error
+	}{
+		{"/dev/sda", v1.PersistentVolumeBlock, "wwn:0x5000c500a1b2c3d4", nil},
+		{"/dev/sda1", v1.PersistentVolumeBlock, "wwn:0x5000c500a1b2c3d4:part1", nil},
+		{"/dev/sdb", v1.PersistentVolumeBlock, "serial:HDD:Z5678", nil},
+		{"/dev/vda", v1.PersistentVolumeBlock, "fsuuid:0b4b7e1c", nil},
+		{"/dev/vdb", v1.PersistentVolumeBlock, "", fmt.Errorf("no WWN, serial or filesystem UUID found for %q", "/dev/vdb")},
+		{"/mnt/disks/vol1", v1.PersistentVolumeFilesystem, "fsuuid:9e8d7c6b", nil},
+	}
+	for _, test := range testcases {
+		fingerprint, err := GetVolumeFingerprint(fakeVolUtil, test.fullPath, test.volMode)
+		if fingerprint != test.expected || !reflect.DeepEqual(err, test.expectedErr) {
+			t.Errorf("fullPath: %v, expected fingerprint: %v, got fingerprint: %v, expected error: %v, got error: %v", test.fullPath, test.expected, fingerprint, test.expectedErr, err)
+		}
+	}
+}
+
 func TestAnyNodeExists(t *testing.T) {
 	nodeName := "test-node"
 	nodeWithName := &v1.Node{
@@ -17,7 +17,9 @@ limitations under the License.
 package controller
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"time"
@@ -25,9 +27,13 @@ import (
 	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/cache"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/capacity"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/deleter"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/discovery"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/dryrun"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/dynamic"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/health"
 	nodetaint "sigs.k8s.io/sig-storage-local-static-provisioner/pkg/node-taint"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/populator"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
@@ -35,12 +41,14 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apiserver/pkg/server/healthz"
+	dynamicclient "k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/mount"
+	"sigs.k8s.io/yaml"
 )
 
 // signal represents an indication to from client to terminate a service and waits for a callback
@@ -69,12 +77,12 @@ func (s *signal) close() {
 // It launches the main sync loop and if there is an updated configuration from the ConfigWatcher,
 // it will inform the main sync loop to terminate and then will launch a new sync loop with the
 // updated configuration.
-func RunLocalController(configUpdate <-chan common.ProvisionerConfiguration, client *kubernetes.Clientset, ptable deleter.ProcTable, discoveryPeriod time.Duration, node *v1.Node, namespace, jobImage string, config common.ProvisionerConfiguration) {
+func RunLocalController(configUpdate <-chan common.ProvisionerConfiguration, client *kubernetes.Clientset, dynamicClient dynamicclient.Interface, ptable deleter.ProcTable, scheduler *deleter.CleanupScheduler, discoveryPeriod time.Duration, node *v1.Node, namespace, jobImage string, config common.ProvisionerConfiguration) {
 	s := newSignal()
 	defer s.close()
 
 	startController := func(config common.ProvisionerConfiguration) {
-		StartLocalController(s, client, ptable, discoveryPeriod, common.UserConfigFromProvisionerConfig(node, namespace, jobImage, config))
+		StartLocalController(s, client, dynamicClient, ptable, scheduler, discoveryPeriod, common.UserConfigFromProvisionerConfig(node, namespace, jobImage, config))
 	}
 	go startController(config)
 
@@ -87,49 +95,127 @@ func RunLocalController(configUpdate <-chan common.ProvisionerConfiguration, cli
 	}
 }
 
-// StartLocalController starts the sync loop for the local PV discovery and deleter
-func StartLocalController(signal *signal, client *kubernetes.Clientset, ptable deleter.ProcTable, discoveryPeriod time.Duration, config *common.UserConfig) {
-	klog.Info("Initializing volume cache\n")
-
-	informerStopChan := make(chan struct{})
-	jobControllerStopChan := make(chan struct{})
-
-	var provisionerName string
+// getProvisionerName returns the name of the provisioner of the node.
+func getProvisionerName(config *common.UserConfig) string {
 	if config.UseNodeNameOnly {
-		provisionerName = fmt.Sprintf("local-volume-provisioner-%v", config.Node.Name)
-	} else {
-		provisionerName = fmt.Sprintf("local-volume-provisioner-%v-%v", config.Node.Name, config.Node.UID)
+		return fmt.Sprintf("local-volume-provisioner-%v", config.Node.Name)
 	}
+	return fmt.Sprintf("local-volume-provisioner-%v-%v", config.Node.Name, config.Node.UID)
+}
 
-	broadcaster := record.NewBroadcaster()
-	broadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: v1core.New(client.CoreV1().RESTClient()).Events("")})
-	recorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: provisionerName})
-
+// newRuntimeConfig creates the runtime configuration of the provisioner
+// without its APIUtil.
+func newRuntimeConfig(client *kubernetes.Clientset, dynamicClient dynamicclient.Interface, provisionerName string, recorder record.EventRecorder, config *common.UserConfig) (*common.RuntimeConfig, error) {
 	// We choose a random resync period between MinResyncPeriod and 2 *
 	// MinResyncPeriod, so that local provisioners deployed on multiple nodes
 	// at same time don't list the apiserver simultaneously.
 	resyncPeriod := time.Duration(config.MinResyncPeriod.Seconds()*(1+rand.Float64())) * time.Second
 
-	var err error
 	volumeUtil, err := util.NewVolumeUtil()
 	if err != nil {
-		klog.Fatalf("Error initializing VolumeUtil: %v", err)
+		return nil, err
 	}
 
-	runtimeConfig := &common.RuntimeConfig{
+	return &common.RuntimeConfig{
 		UserConfig:      config,
 		Cache:           cache.NewVolumeCache(),
 		VolUtil:         volumeUtil,
-		APIUtil:         util.NewAPIUtil(client),
+		PartitionUtil:   util.NewPartitionUtil(),
+		FormatUtil:      util.NewFormatUtil(),
+		LVMUtil:         util.NewLVMUtil(),
+		QuotaUtil:       util.NewQuotaUtil(),
+		HealthUtil:      util.NewHealthUtil(),
 		Client:          client,
+		DynamicClient:   dynamicClient,
 		Name:            provisionerName,
 		Recorder:        recorder,
 		Mounter:         mount.New("" /* default mount path */),
 		InformerFactory: informers.NewSharedInformerFactory(client, resyncPeriod),
+	}, nil
+}
+
+// RunDryRun runs the discovery and the deletion of the PVs once with config,
+// without changing the cluster or the disks, and writes the changes they
+// would make to the PVs to out.
+func RunDryRun(client *kubernetes.Clientset, dynamicClient dynamicclient.Interface, config *common.UserConfig, out io.Writer) error {
+	provisionerName := getProvisionerName(config)
+	// Events are logged instead of being sent to the API server.
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartLogging(klog.Infof)
+	defer broadcaster.Shutdown()
+	recorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: provisionerName})
+
+	runtimeConfig, err := newRuntimeConfig(client, dynamicClient, provisionerName, recorder, config)
+	if err != nil {
+		return fmt.Errorf("error initializing VolumeUtil: %v", err)
+	}
+	plan := &dryrun.Plan{}
+	runtimeConfig.APIUtil = dryrun.NewAPIUtil(runtimeConfig.Cache, plan)
+	runtimeConfig.DryRun = true
+
+	populator.NewPopulator(runtimeConfig)
+	cleanupTracker := &deleter.CleanupStatusTracker{ProcTable: deleter.NewProcTable()}
+	discoverer, err := discovery.NewDiscoverer(runtimeConfig, cleanupTracker)
+	if err != nil {
+		return fmt.Errorf("error initializing discoverer: %v", err)
+	}
+	discoverer.Plan = plan
+	deleter := deleter.NewDeleter(runtimeConfig, cleanupTracker)
+
+	informerStopChan := make(chan struct{})
+	defer close(informerStopChan)
+	runtimeConfig.InformerFactory.Start(informerStopChan)
+	for v, synced := range runtimeConfig.InformerFactory.WaitForCacheSync(wait.NeverStop) {
+		if !synced {
+			return fmt.Errorf("error syncing informer for %v", v)
+		}
+	}
+
+	discoverer.DiscoverLocalVolumes()
+	deleter.DeletePVs()
+
+	data, err := yaml.Marshal(plan.Changes())
+	if err != nil {
+		return fmt.Errorf("error marshaling plan: %v", err)
+	}
+	if _, err := out.Write(data); err != nil {
+		return fmt.Errorf("error writing plan: %v", err)
+	}
+	if err := discoverer.Readyz.Check(nil); err != nil {
+		return fmt.Errorf("plan is incomplete, discovery failed: %v", err)
+	}
+	return nil
+}
+
+// StartLocalController starts the sync loop for the local PV discovery and deleter
+func StartLocalController(signal *signal, client *kubernetes.Clientset, dynamicClient dynamicclient.Interface, ptable deleter.ProcTable, scheduler *deleter.CleanupScheduler, discoveryPeriod time.Duration, config *common.UserConfig) {
+	klog.Info("Initializing volume cache\n")
+
+	informerStopChan := make(chan struct{})
+	jobControllerStopChan := make(chan struct{})
+
+	provisionerName := getProvisionerName(config)
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: v1core.New(client.CoreV1().RESTClient()).Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: provisionerName})
+
+	runtimeConfig, err := newRuntimeConfig(client, dynamicClient, provisionerName, recorder, config)
+	if err != nil {
+		klog.Fatalf("Error initializing VolumeUtil: %v", err)
 	}
+	runtimeConfig.APIUtil = util.NewAPIUtil(client)
 
 	populator.NewPopulator(runtimeConfig)
 
+	var capacityPublisher *capacity.Publisher
+	if config.PublishStorageCapacity {
+		capacityPublisher, err = capacity.NewPublisher(runtimeConfig)
+		if err != nil {
+			klog.Fatalf("Error initializing storage capacity publisher: %v", err)
+		}
+		klog.Infof("Enabling storage capacity publishing")
+	}
+
 	var jobController deleter.JobController
 	if runtimeConfig.UseJobForCleaning {
 		labels := map[string]string{common.NodeNameLabel: config.Node.Name}
@@ -139,7 +225,8 @@ func StartLocalController(signal *signal, client *kubernetes.Clientset, ptable d
 		}
 		klog.Infof("Enabling Jobs based cleaning.")
 	}
-	cleanupTracker := &deleter.CleanupStatusTracker{ProcTable: ptable, JobController: jobController}
+	scheduler.SetLimits(config)
+	cleanupTracker := &deleter.CleanupStatusTracker{ProcTable: ptable, JobController: jobController, Scheduler: scheduler}
 
 	discoverer, err := discovery.NewDiscoverer(runtimeConfig, cleanupTracker)
 	if err != nil {
@@ -149,6 +236,23 @@ func StartLocalController(signal *signal, client *kubernetes.Clientset, ptable d
 
 	deleter := deleter.NewDeleter(runtimeConfig, cleanupTracker)
 
+	// The dynamic provisioner runs its own informers and workers until the
+	// controller is stopped.
+	dynamicCtx, stopDynamic := context.WithCancel(context.Background())
+	defer stopDynamic()
+	if dynamic.DynamicProvisioningConfigured(config.DiscoveryMap) {
+		serverVersion, err := client.Discovery().ServerVersion()
+		if err != nil {
+			klog.Fatalf("Error getting server version: %v", err)
+		}
+		provisioner, err := dynamic.NewProvisioner(runtimeConfig)
+		if err != nil {
+			klog.Fatalf("Error initializing dynamic provisioner: %v", err)
+		}
+		go dynamic.NewController(client, provisioner, serverVersion.GitVersion).Run(dynamicCtx)
+		klog.Infof("Enabling dynamic provisioning")
+	}
+
 	// Start informers after all event listeners are registered.
 	runtimeConfig.InformerFactory.Start(informerStopChan)
 	// Wait for all started informers' cache were synced.
@@ -161,10 +265,49 @@ func StartLocalController(signal *signal, client *kubernetes.Clientset, ptable d
 	if jobController != nil {
 		go jobController.Run(jobControllerStopChan)
 	}
+	if capacityPublisher != nil {
+		go capacityPublisher.Run(informerStopChan)
+	}
 	klog.Info("Controller started\n")
 
 	nodeTaintRemover := nodetaint.NewRemover(runtimeConfig)
 
+	// In watch mode, new volumes are discovered from path events and the full
+	// discovery scan only runs every resync period as a safety net.
+	var pathWatcher discovery.PathWatcher
+	var pathEvents <-chan discovery.PathEvent
+	discoveryResyncPeriod := discoveryPeriod
+	if config.UseWatchForDiscovery {
+		pathWatcher, err = discovery.NewPathWatcher(config.DiscoveryMap, runtimeConfig.Mounter)
+		if err != nil {
+			klog.Errorf("Error initializing path watcher, falling back to periodic discovery: %v", err)
+		} else {
+			pathEvents = pathWatcher.Events()
+			discoveryResyncPeriod = config.DiscoveryResyncPeriod.Duration
+			if discoveryResyncPeriod == 0 {
+				discoveryResyncPeriod = common.DefaultDiscoveryResyncPeriod
+			}
+			klog.Infof("Enabling watch based discovery with resync period %v", discoveryResyncPeriod)
+		}
+	}
+	var hotplugWatcher discovery.PathWatcher
+	var hotplugEvents <-chan discovery.PathEvent
+	if discovery.HotplugRulesConfigured(config.DiscoveryMap) {
+		hotplugWatcher, err = discovery.NewHotplugWatcher(config.DiscoveryMap)
+		if err != nil {
+			klog.Errorf("Error initializing hotplug watcher, hot-plugged devices will be found by periodic discovery: %v", err)
+		} else {
+			hotplugEvents = hotplugWatcher.Events()
+			klog.Infof("Enabling hotplug discovery of block devices")
+		}
+	}
+	var healthMonitor *health.Monitor
+	if config.HealthCheckPeriod.Duration > 0 {
+		healthMonitor = health.NewMonitor(runtimeConfig)
+		klog.Infof("Enabling disk health checks with period %v", config.HealthCheckPeriod.Duration)
+	}
+	var lastDiscovery, lastHealthCheck time.Time
+
 	for {
 		select {
 		case stopped := <-signal.closing:
@@ -172,16 +315,39 @@ func StartLocalController(signal *signal, client *kubernetes.Clientset, ptable d
 			if jobController != nil {
 				close(jobControllerStopChan)
 			}
+			if pathWatcher != nil {
+				pathWatcher.Stop()
+			}
+			if hotplugWatcher != nil {
+				hotplugWatcher.Stop()
+			}
 			stopped <- struct{}{}
 			klog.Info("Controller stopped\n")
 			return
 		default:
 			deleter.DeletePVs()
-			discoverer.DiscoverLocalVolumes()
+			if time.Since(lastDiscovery) >= discoveryResyncPeriod {
+				discoverer.DiscoverLocalVolumes()
+				lastDiscovery = time.Now()
+			}
+			if healthMonitor != nil && time.Since(lastHealthCheck) >= config.HealthCheckPeriod.Duration {
+				healthMonitor.CheckVolumes()
+				lastHealthCheck = time.Now()
+			}
 			if !nodeTaintRemover.ShouldRemoveTaint() && discoverer.Readyz.Check(nil) == nil {
 				nodeTaintRemover.RemoveTaintWithBackoff()
 			}
-			time.Sleep(discoveryPeriod)
+			if pathEvents == nil && hotplugEvents == nil {
+				time.Sleep(discoveryPeriod)
+				continue
+			}
+			select {
+			case event := <-pathEvents:
+				discoverer.DiscoverLocalVolumesAtPaths(discovery.DrainPathEvents(event, pathEvents))
+			case event := <-hotplugEvents:
+				discoverer.DiscoverLocalVolumesAtPaths(discovery.DrainPathEvents(event, hotplugEvents))
+			case <-time.After(discoveryPeriod):
+			}
 		}
 	}
 }
@@ -0,0 +1,271 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deleter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+const (
+	// BlockCleanerBinary is the binary of the provisioner in its image, which
+	// runs the built-in block cleaners of cleanup jobs.
+	BlockCleanerBinary = "/local-provisioner"
+	// CleanBlockDeviceFlag is the flag of the provisioner binary which makes
+	// it clean the block device of the LOCAL_PV_BLKDEVICE environment variable
+	// with the built-in block cleaner given as arguments.
+	CleanBlockDeviceFlag = "clean-block-device"
+
+	// signatureBytes is the size of the regions at the start and the end of
+	// a block device wiped by the wipe-signatures cleaner.
+	signatureBytes = 1 << 20
+	// directIOAlignment is the alignment of the buffer, the offset and the
+	// length of direct writes, a multiple of the logical block size of the
+	// devices.
+	directIOAlignment = 4096
+	// progressLogPeriod is the minimum period between two logs of the
+	// progress of a cleanup.
+	progressLogPeriod = 30 * time.Second
+	// ScriptProgressPrefix starts the lines of the standard output of a
+	// cleanup script reporting its progress, followed by the bytes it
+	// processed and the total bytes to process, e.g. "PROGRESS 1024 4096".
+	ScriptProgressPrefix = "PROGRESS"
+
+	// progressAnnotationPeriod is the minimum period between two updates of
+	// the cleanup progress annotation of a PV, to bound the load on the API
+	// server.
+	progressAnnotationPeriod = time.Minute
+	// scriptWaitDelay bounds the wait for the output of a cleanup script once
+	// it exited or was killed, which its orphaned descendants may keep open.
+	scriptWaitDelay = 10 * time.Second
+)
+
+// ErrUnsupported is returned by the built-in block cleaners when the device
+// or the platform does not support their operation.
+var ErrUnsupported = errors.New("operation not supported")
+
+// ProgressFunc receives the number of bytes of the device processed so far by
+// a block cleaner and the total number of bytes to process.
+type ProgressFunc func(doneBytes, totalBytes int64)
+
+// BlockCleaner cleans the block devices of released PVs.
+type BlockCleaner interface {
+	// Clean cleans the block device at devPath, reporting its progress to
+	// progress. It stops with the error of ctx once ctx is done.
+	Clean(ctx context.Context, devPath string, progress ProgressFunc) error
+}
+
+// CleanerError is the error of a block cleaner.
+type CleanerError struct {
+	// Cleaner is the name of the block cleaner.
+	Cleaner string
+	// Device is the path of the block device.
+	Device string
+	Err    error
+}
+
+func (e *CleanerError) Error() string {
+	return fmt.Sprintf("block cleaner %q failed to clean %q: %v", e.Cleaner, e.Device, e.Err)
+}
+
+func (e *CleanerError) Unwrap() error {
+	return e.Err
+}
+
+// NewBlockCleaner returns the block cleaner of command, which is either the
+// name of a built-in block cleaner followed by its arguments or a command run
+// with the path of the device in the LOCAL_PV_BLKDEVICE environment variable.
+func NewBlockCleaner(command []string) (BlockCleaner, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("empty block cleaner command")
+	}
+	passes, err := common.ParseBlockCleanerCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	switch command[0] {
+	case common.BlockCleanerDiscard, common.BlockCleanerSecureDiscard, common.BlockCleanerZeroOut:
+		return &ioctlCleaner{name: command[0]}, nil
+	case common.BlockCleanerOverwrite:
+		return &overwriteCleaner{name: command[0], passes: passes}, nil
+	case common.BlockCleanerWipeSignatures:
+		return &overwriteCleaner{name: command[0], passes: 1, signaturesOnly: true}, nil
+	default:
+		return &scriptCleaner{command: command}, nil
+	}
+}
+
+// CleanBlockDevice cleans the block device at devPath with the block cleaner
+// of command, logs its progress and reports it to progress if not nil.
+func CleanBlockDevice(ctx context.Context, devPath string, command []string, progress ProgressFunc) error {
+	cleaner, err := NewBlockCleaner(command)
+	if err != nil {
+		return err
+	}
+	logger := logProgress(devPath)
+	return cleaner.Clean(ctx, devPath, func(doneBytes, totalBytes int64) {
+		logger(doneBytes, totalBytes)
+		if progress != nil {
+			progress(doneBytes, totalBytes)
+		}
+	})
+}
+
+// logProgress returns a ProgressFunc logging the progress of the cleanup of
+// devPath at most every progressLogPeriod, and once it is complete.
+func logProgress(devPath string) ProgressFunc {
+	var lastLog time.Time
+	return func(doneBytes, totalBytes int64) {
+		if doneBytes < totalBytes && time.Since(lastLog) < progressLogPeriod {
+			return
+		}
+		lastLog = time.Now()
+		klog.Infof("Cleanup of %q: %d of %d bytes processed", devPath, doneBytes, totalBytes)
+	}
+}
+
+// ioctlCleaner cleans a block device with the discard or zero out ioctl of its
+// name.
+type ioctlCleaner struct {
+	name string
+}
+
+// overwriteCleaner overwrites a block device with passes-1 passes of random
+// data and a last pass of zeros, or only its signatures if signaturesOnly.
+type overwriteCleaner struct {
+	name           string
+	passes         int
+	signaturesOnly bool
+}
+
+// byteRange is a range of bytes of a block device.
+type byteRange struct {
+	offset int64
+	length int64
+}
+
+// overwriteRanges returns the ranges of a device of size bytes overwritten by
+// the cleaner. They start at offsets aligned for direct writes.
+func (c *overwriteCleaner) overwriteRanges(size int64) []byteRange {
+	if !c.signaturesOnly || size <= 2*signatureBytes {
+		return []byteRange{{offset: 0, length: size}}
+	}
+	offset := (size - signatureBytes) / directIOAlignment * directIOAlignment
+	return []byteRange{
+		{offset: 0, length: signatureBytes},
+		{offset: offset, length: size - offset},
+	}
+}
+
+// scriptCleaner cleans a block device by running command.
+type scriptCleaner struct {
+	command []string
+}
+
+func (c *scriptCleaner) Clean(ctx context.Context, devPath string, progress ProgressFunc) error {
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", common.LocalPVEnv, devPath))
+	setProcessGroup(cmd)
+	cmd.WaitDelay = scriptWaitDelay
+	var wg sync.WaitGroup
+	// Wait for stderr & stdout  go routines
+	wg.Add(2)
+
+	// The output is copied by cmd, so that Wait stops waiting for it after
+	// WaitDelay.
+	outReader, outWriter := io.Pipe()
+	cmd.Stdout = outWriter
+
+	go func() {
+		defer wg.Done()
+		outScanner := bufio.NewScanner(outReader)
+		for outScanner.Scan() {
+			outstr := outScanner.Text()
+			if doneBytes, totalBytes, ok := parseScriptProgress(outstr); ok {
+				if progress != nil {
+					progress(doneBytes, totalBytes)
+				}
+				continue
+			}
+			klog.Infof("Cleanup of %q: StdoutBuf - %q", devPath, outstr)
+		}
+		io.Copy(io.Discard, outReader)
+	}()
+
+	errReader, errWriter := io.Pipe()
+	cmd.Stderr = errWriter
+
+	go func() {
+		defer wg.Done()
+		errScanner := bufio.NewScanner(errReader)
+		for errScanner.Scan() {
+			errstr := errScanner.Text()
+			klog.Infof("Cleanup of %q: StderrBuf - %q", devPath, errstr)
+		}
+		io.Copy(io.Discard, errReader)
+	}()
+
+	err := cmd.Start()
+	if err == nil {
+		err = cmd.Wait()
+	}
+	outWriter.Close()
+	errWriter.Close()
+	wg.Wait()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		klog.Warningf("Cleanup of %q: output still open %v after the command exited", devPath, scriptWaitDelay)
+		err = nil
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return &CleanerError{Cleaner: c.command[0], Device: devPath, Err: ctx.Err()}
+		}
+		return &CleanerError{Cleaner: c.command[0], Device: devPath, Err: err}
+	}
+
+	return nil
+}
+
+// parseScriptProgress parses a progress line of a cleanup script, which
+// starts with ScriptProgressPrefix.
+func parseScriptProgress(line string) (int64, int64, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 || fields[0] != ScriptProgressPrefix {
+		return 0, 0, false
+	}
+	doneBytes, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	totalBytes, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil || totalBytes <= 0 || doneBytes < 0 || doneBytes > totalBytes {
+		return 0, 0, false
+	}
+	return doneBytes, totalBytes, true
+}
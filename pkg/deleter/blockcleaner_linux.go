@@ -0,0 +1,229 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deleter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+const (
+	// ioctlChunkBytes is the size of the ranges discarded or zeroed by one
+	// ioctl, so that the cleanup can report its progress and be canceled.
+	ioctlChunkBytes = 1 << 30
+	// writeChunkBytes is the size of the writes of the overwrite cleaners.
+	writeChunkBytes = 1 << 20
+)
+
+// setProcessGroup runs cmd in a process group of its own, which is killed
+// when the context of cmd is done, so that no child of a block cleaner script
+// outlives it.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return unix.Kill(-cmd.Process.Pid, unix.SIGKILL)
+	}
+}
+
+func (c *ioctlCleaner) Clean(ctx context.Context, devPath string, progress ProgressFunc) error {
+	var request uintptr
+	switch c.name {
+	case common.BlockCleanerDiscard:
+		request = unix.BLKDISCARD
+	case common.BlockCleanerSecureDiscard:
+		request = unix.BLKSECDISCARD
+	case common.BlockCleanerZeroOut:
+		request = unix.BLKZEROOUT
+	default:
+		return &CleanerError{Cleaner: c.name, Device: devPath, Err: ErrUnsupported}
+	}
+
+	file, err := os.OpenFile(devPath, os.O_WRONLY, 0)
+	if err != nil {
+		return &CleanerError{Cleaner: c.name, Device: devPath, Err: err}
+	}
+	defer file.Close()
+	size, err := deviceSize(file)
+	if err != nil {
+		return &CleanerError{Cleaner: c.name, Device: devPath, Err: err}
+	}
+
+	klog.Infof("Cleaning %q of %d bytes with %s", devPath, size, c.name)
+	for offset := int64(0); offset < size; offset += ioctlChunkBytes {
+		if err := ctx.Err(); err != nil {
+			return &CleanerError{Cleaner: c.name, Device: devPath, Err: err}
+		}
+		byteRange := [2]uint64{uint64(offset), uint64(min(ioctlChunkBytes, size-offset))}
+		if _, _, errno := unix.Syscall(unix.SYS_IOCTL, file.Fd(), request, uintptr(unsafe.Pointer(&byteRange[0]))); errno != 0 {
+			return &CleanerError{Cleaner: c.name, Device: devPath, Err: ioctlError(errno)}
+		}
+		progress(offset+int64(byteRange[1]), size)
+	}
+	if size == 0 {
+		progress(0, 0)
+	}
+	return nil
+}
+
+// ioctlError returns ErrUnsupported if errno means that the device does not
+// support the ioctl.
+func ioctlError(errno unix.Errno) error {
+	switch errno {
+	case unix.EOPNOTSUPP, unix.ENOTTY:
+		return fmt.Errorf("%w: %v", ErrUnsupported, errno)
+	}
+	return errno
+}
+
+func (c *overwriteCleaner) Clean(ctx context.Context, devPath string, progress ProgressFunc) error {
+	file, direct, err := openDirect(devPath)
+	if err != nil {
+		return &CleanerError{Cleaner: c.name, Device: devPath, Err: err}
+	}
+	defer file.Close()
+	// tail writes the end of a device whose size is not aligned for direct
+	// writes.
+	var tail *os.File
+	defer func() {
+		if tail != nil {
+			tail.Close()
+		}
+	}()
+	size, err := deviceSize(file)
+	if err != nil {
+		return &CleanerError{Cleaner: c.name, Device: devPath, Err: err}
+	}
+
+	ranges := c.overwriteRanges(size)
+	var totalBytes int64
+	for _, r := range ranges {
+		totalBytes += r.length
+	}
+	totalBytes *= int64(c.passes)
+
+	klog.Infof("Cleaning %q of %d bytes with %s in %d passes", devPath, size, c.name, c.passes)
+	buf := alignedBuffer(writeChunkBytes)
+	var doneBytes int64
+	for pass := 1; pass <= c.passes; pass++ {
+		random := pass < c.passes
+		clear(buf)
+		var seed [32]byte
+		if random {
+			for i := range seed {
+				seed[i] = byte(rand.Uint32())
+			}
+		}
+		source := rand.NewChaCha8(seed)
+		for _, r := range ranges {
+			for offset := r.offset; offset < r.offset+r.length; offset += writeChunkBytes {
+				if err := ctx.Err(); err != nil {
+					return &CleanerError{Cleaner: c.name, Device: devPath, Err: err}
+				}
+				chunk := buf[:min(writeChunkBytes, r.offset+r.length-offset)]
+				if random {
+					source.Read(chunk)
+				}
+				aligned := len(chunk)
+				if direct {
+					aligned = aligned / directIOAlignment * directIOAlignment
+				}
+				if _, err := file.WriteAt(chunk[:aligned], offset); err != nil {
+					return &CleanerError{Cleaner: c.name, Device: devPath, Err: err}
+				}
+				if aligned < len(chunk) {
+					if tail == nil {
+						if tail, err = os.OpenFile(devPath, os.O_WRONLY, 0); err != nil {
+							return &CleanerError{Cleaner: c.name, Device: devPath, Err: err}
+						}
+					}
+					if _, err := tail.WriteAt(chunk[aligned:], offset+int64(aligned)); err != nil {
+						return &CleanerError{Cleaner: c.name, Device: devPath, Err: err}
+					}
+				}
+				doneBytes += int64(len(chunk))
+				progress(doneBytes, totalBytes)
+			}
+		}
+		if err := file.Sync(); err != nil {
+			return &CleanerError{Cleaner: c.name, Device: devPath, Err: err}
+		}
+		if tail != nil {
+			if err := tail.Sync(); err != nil {
+				return &CleanerError{Cleaner: c.name, Device: devPath, Err: err}
+			}
+		}
+	}
+	if totalBytes == 0 {
+		progress(0, 0)
+	}
+	return nil
+}
+
+// openDirect opens devPath for writing with direct I/O, so that overwriting a
+// device does not evict the page cache of the node, and returns whether it
+// did. Files which do not support direct I/O are opened for buffered writes
+// synced after each pass.
+func openDirect(devPath string) (*os.File, bool, error) {
+	file, err := os.OpenFile(devPath, os.O_WRONLY|unix.O_DIRECT, 0)
+	if errors.Is(err, unix.EINVAL) {
+		file, err = os.OpenFile(devPath, os.O_WRONLY, 0)
+		return file, false, err
+	}
+	return file, err == nil, err
+}
+
+// alignedBuffer returns a buffer of size bytes aligned for direct I/O.
+func alignedBuffer(size int) []byte {
+	buf := make([]byte, size+directIOAlignment)
+	offset := 0
+	if remainder := int(uintptr(unsafe.Pointer(&buf[0])) % directIOAlignment); remainder != 0 {
+		offset = directIOAlignment - remainder
+	}
+	return buf[offset : offset+size : offset+size]
+}
+
+// deviceSize returns the size in bytes of the block device or regular file.
+func deviceSize(file *os.File) (int64, error) {
+	var st unix.Stat_t
+	if err := unix.Fstat(int(file.Fd()), &st); err != nil {
+		return 0, err
+	}
+	if st.Mode&unix.S_IFMT == unix.S_IFREG {
+		return st.Size, nil
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFBLK {
+		return 0, fmt.Errorf("not a block device")
+	}
+	var size int64
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, file.Fd(), unix.BLKGETSIZE64, uintptr(unsafe.Pointer(&size))); errno != 0 {
+		return 0, errno
+	}
+	return size, nil
+}
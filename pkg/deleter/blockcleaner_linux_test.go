@@ -0,0 +1,155 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deleter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+// newTestDevice creates a file of size bytes filled with 0xff, standing for a
+// block device.
+func newTestDevice(t *testing.T, size int) string {
+	devPath := filepath.Join(t.TempDir(), "disk")
+	if err := os.WriteFile(devPath, bytes.Repeat([]byte{0xff}, size), 0644); err != nil {
+		t.Fatalf("Failed to create test device: %v", err)
+	}
+	return devPath
+}
+
+func TestOverwriteCleaner(t *testing.T) {
+	// The end of the device is not aligned for direct writes.
+	size := 3*writeChunkBytes + 4096 + 100
+	devPath := newTestDevice(t, size)
+	cleaner, err := NewBlockCleaner([]string{common.BlockCleanerOverwrite, "2"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doneBytes, totalBytes int64
+	err = cleaner.Clean(context.Background(), devPath, func(done, total int64) {
+		doneBytes, totalBytes = done, total
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if doneBytes != 2*int64(size) || totalBytes != 2*int64(size) {
+		t.Errorf("Expected progress of %d bytes, got %d of %d", 2*size, doneBytes, totalBytes)
+	}
+	data, err := os.ReadFile(devPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, make([]byte, size)) {
+		t.Errorf("Expected device to be zeroed by the last pass")
+	}
+}
+
+func TestWipeSignaturesCleaner(t *testing.T) {
+	size := 4 * signatureBytes
+	devPath := newTestDevice(t, size)
+	cleaner, err := NewBlockCleaner([]string{common.BlockCleanerWipeSignatures})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cleaner.Clean(context.Background(), devPath, func(int64, int64) {}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(devPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(data[:signatureBytes], make([]byte, signatureBytes)) || !bytes.Equal(data[size-signatureBytes:], make([]byte, signatureBytes)) {
+		t.Errorf("Expected first and last MiB to be zeroed")
+	}
+	if !bytes.Equal(data[signatureBytes:size-signatureBytes], bytes.Repeat([]byte{0xff}, size-2*signatureBytes)) {
+		t.Errorf("Expected the rest of the device to be untouched")
+	}
+}
+
+func TestBlockCleaner_Canceled(t *testing.T) {
+	devPath := newTestDevice(t, writeChunkBytes)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cleaner, err := NewBlockCleaner([]string{common.BlockCleanerOverwrite})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cleaner.Clean(ctx, devPath, func(int64, int64) {}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cleanup to be canceled, got %v", err)
+	}
+}
+
+func TestIoctlCleaner_Unsupported(t *testing.T) {
+	// Regular files don't support the block device ioctls.
+	devPath := newTestDevice(t, 4096)
+	cleaner, err := NewBlockCleaner([]string{common.BlockCleanerDiscard})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cleaner.Clean(context.Background(), devPath, func(int64, int64) {}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected unsupported error, got %v", err)
+	}
+}
+
+func TestScriptCleaner_TimeoutKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	// The background sleep keeps the output of the script open, the cleanup
+	// only ends if it is killed with the script.
+	cleaner := &scriptCleaner{command: []string{"sh", "-c", "sleep 60 & wait"}}
+	done := make(chan error)
+	go func() {
+		done <- cleaner.Clean(ctx, "/dev/test", nil)
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected cleanup to time out, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Cleanup was not killed by its timeout")
+	}
+}
+
+func TestScriptCleaner_Progress(t *testing.T) {
+	script := `echo "PROGRESS 1024 4096"; echo "pass 1/2"; echo "PROGRESS 8192 4096"; echo "PROGRESS 4096 4096"`
+	cleaner := &scriptCleaner{command: []string{"sh", "-c", script}}
+	var reports [][2]int64
+	err := cleaner.Clean(context.Background(), "/dev/test", func(doneBytes, totalBytes int64) {
+		reports = append(reports, [2]int64{doneBytes, totalBytes})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The invalid progress lines are only logged.
+	expected := [][2]int64{{1024, 4096}, {4096, 4096}}
+	if !reflect.DeepEqual(reports, expected) {
+		t.Errorf("Expected progress %v, got %v", expected, reports)
+	}
+}
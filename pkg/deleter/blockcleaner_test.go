@@ -0,0 +1,155 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deleter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewBlockCleaner(t *testing.T) {
+	testcases := map[string]struct {
+		command     []string
+		expected    BlockCleaner
+		expectedErr bool
+	}{
+		"script": {
+			command:  []string{"/scripts/shred.sh", "2"},
+			expected: &scriptCleaner{command: []string{"/scripts/shred.sh", "2"}},
+		},
+		"discard": {
+			command:  []string{common.BlockCleanerDiscard},
+			expected: &ioctlCleaner{name: common.BlockCleanerDiscard},
+		},
+		"zero out with arguments": {
+			command:     []string{common.BlockCleanerZeroOut, "2"},
+			expectedErr: true,
+		},
+		"overwrite": {
+			command:  []string{common.BlockCleanerOverwrite},
+			expected: &overwriteCleaner{name: common.BlockCleanerOverwrite, passes: 1},
+		},
+		"overwrite in passes": {
+			command:  []string{common.BlockCleanerOverwrite, "3"},
+			expected: &overwriteCleaner{name: common.BlockCleanerOverwrite, passes: 3},
+		},
+		"overwrite in no passes": {
+			command:     []string{common.BlockCleanerOverwrite, "0"},
+			expectedErr: true,
+		},
+		"wipe signatures": {
+			command:  []string{common.BlockCleanerWipeSignatures},
+			expected: &overwriteCleaner{name: common.BlockCleanerWipeSignatures, passes: 1, signaturesOnly: true},
+		},
+		"empty": {
+			command:     []string{},
+			expectedErr: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			cleaner, err := NewBlockCleaner(tc.command)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(cleaner, tc.expected) {
+				t.Errorf("Expected cleaner %+v, got %+v", tc.expected, cleaner)
+			}
+		})
+	}
+}
+
+func TestOverwriteRanges(t *testing.T) {
+	cleaner := &overwriteCleaner{name: common.BlockCleanerWipeSignatures, passes: 1, signaturesOnly: true}
+	expected := []byteRange{{offset: 0, length: signatureBytes}, {offset: 9 * signatureBytes, length: signatureBytes}}
+	if ranges := cleaner.overwriteRanges(10 * signatureBytes); !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("Expected ranges %v, got %v", expected, ranges)
+	}
+	// The last range starts at an offset aligned for direct writes
+	expected = []byteRange{{offset: 0, length: signatureBytes}, {offset: 9*signatureBytes - directIOAlignment, length: signatureBytes + directIOAlignment - 512}}
+	if ranges := cleaner.overwriteRanges(10*signatureBytes - 512); !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("Expected ranges %v, got %v", expected, ranges)
+	}
+	// Small devices are wiped entirely
+	expected = []byteRange{{offset: 0, length: signatureBytes}}
+	if ranges := cleaner.overwriteRanges(signatureBytes); !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("Expected ranges %v, got %v", expected, ranges)
+	}
+}
+
+func TestScriptCleaner(t *testing.T) {
+	cleaner := &scriptCleaner{command: []string{"sh", "-c", "test \"$" + common.LocalPVEnv + "\" = /dev/test"}}
+	if err := cleaner.Clean(context.Background(), "/dev/test", nil); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	var cleanerErr *CleanerError
+	if err := cleaner.Clean(context.Background(), "/dev/other", nil); !errors.As(err, &cleanerErr) || cleanerErr.Device != "/dev/other" {
+		t.Errorf("Expected CleanerError of device /dev/other, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cleaner = &scriptCleaner{command: []string{"sleep", "10"}}
+	if err := cleaner.Clean(ctx, "/dev/test", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cleanup to be canceled, got %v", err)
+	}
+}
+
+func TestNewCleanupJob_BuiltinBlockCleaner(t *testing.T) {
+	pv := &v1.PersistentVolume{}
+	pv.Name = "pv1"
+	config := common.MountConfig{
+		HostDir:             "/mnt/disks",
+		MountDir:            "/discoveryPath",
+		BlockCleanerCommand: []string{common.BlockCleanerOverwrite, "2"},
+	}
+	job, err := NewCleanupJob(pv, v1.PersistentVolumeBlock, "provisioner", nil, "node1", "ns1", "/discoveryPath/disk1", config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{BlockCleanerBinary, "--" + CleanBlockDeviceFlag, "--", common.BlockCleanerOverwrite, "2"}
+	if command := job.Spec.Template.Spec.Containers[0].Command; !reflect.DeepEqual(command, expected) {
+		t.Errorf("Expected command %v, got %v", expected, command)
+	}
+}
+
+func TestNewCleanupJob_Timeout(t *testing.T) {
+	pv := &v1.PersistentVolume{}
+	pv.Name = "pv1"
+	config := common.MountConfig{
+		HostDir:             "/mnt/disks",
+		MountDir:            "/discoveryPath",
+		BlockCleanerCommand: []string{"/scripts/shred.sh", "2"},
+		CleanupPolicy:       &common.CleanupPolicy{Timeout: meta_v1.Duration{Duration: 2 * time.Hour}},
+	}
+	job, err := NewCleanupJob(pv, v1.PersistentVolumeBlock, "provisioner", nil, "node1", "ns1", "/discoveryPath/disk1", config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deadline := job.Spec.ActiveDeadlineSeconds; deadline == nil || *deadline != 7200 {
+		t.Errorf("Expected active deadline of 7200 seconds, got %v", deadline)
+	}
+}
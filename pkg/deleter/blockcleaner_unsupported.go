@@ -0,0 +1,40 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deleter
+
+import (
+	"context"
+	"os/exec"
+)
+
+// setProcessGroup is defined here for platforms without process groups, where
+// only the block cleaner script itself is killed when its context is done.
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// Clean is defined here for platforms without the block device ioctls.
+func (c *ioctlCleaner) Clean(ctx context.Context, devPath string, progress ProgressFunc) error {
+	return &CleanerError{Cleaner: c.name, Device: devPath, Err: ErrUnsupported}
+}
+
+// Clean is defined here for platforms without direct I/O to block devices.
+func (c *overwriteCleaner) Clean(ctx context.Context, devPath string, progress ProgressFunc) error {
+	return &CleanerError{Cleaner: c.name, Device: devPath, Err: ErrUnsupported}
+}
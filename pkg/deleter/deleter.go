@@ -17,17 +17,16 @@ limitations under the License.
 package deleter
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/metrics"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -47,6 +46,10 @@ const (
 	CSFailed
 	// CSSucceeded Cleanup process has ended successfully.
 	CSSucceeded
+	// CSQueued Cleanup process waits for a slot to start.
+	CSQueued
+	// CSTimedOut Cleanup process was killed by its timeout.
+	CSTimedOut
 )
 
 // Deleter handles PV cleanup and object deletion
@@ -68,6 +71,10 @@ func NewDeleter(config *common.RuntimeConfig, cleanupTracker *CleanupStatusTrack
 // DeletePVs will scan through all the existing PVs that are released, and cleanup and
 // delete them
 func (d *Deleter) DeletePVs() {
+	d.CleanupStatus.ProcTable.Prune(func(pvName string) bool {
+		_, exists := d.Cache.GetPV(pvName)
+		return exists
+	})
 	for _, pv := range d.Cache.ListPVs() {
 		if pv.Status.Phase != v1.VolumeReleased {
 			continue
@@ -94,7 +101,7 @@ func (d *Deleter) DeletePVs() {
 					mode = "unknown"
 				}
 				deleteType := metrics.DeleteTypeProcess
-				if d.shouldRunJob(mode) {
+				if d.shouldRunJob(mode, d.DiscoveryMap[pv.Spec.StorageClassName]) {
 					deleteType = metrics.DeleteTypeJob
 				}
 				metrics.PersistentVolumeDeleteFailedTotal.WithLabelValues(string(mode), deleteType).Inc()
@@ -129,8 +136,14 @@ func (d *Deleter) getVolMode(pv *v1.PersistentVolume) (v1.PersistentVolumeMode,
 	return volMode, nil
 }
 
-func (d *Deleter) shouldRunJob(mode v1.PersistentVolumeMode) bool {
-	return mode == v1.PersistentVolumeBlock && d.RuntimeConfig.UseJobForCleaning
+func (d *Deleter) shouldRunJob(mode v1.PersistentVolumeMode, config common.MountConfig) bool {
+	return mode == v1.PersistentVolumeBlock && d.RuntimeConfig.UseJobForCleaning && !recreatesLogicalVolume(config) && !d.DryRun
+}
+
+// recreatesLogicalVolume returns true if block volumes of the class are
+// wiped by recreating their logical volume instead of the block cleaner.
+func recreatesLogicalVolume(config common.MountConfig) bool {
+	return config.LVM != nil && config.LVM.RecreateOnDelete
 }
 
 func (d *Deleter) deletePV(pv *v1.PersistentVolume) error {
@@ -151,7 +164,7 @@ func (d *Deleter) deletePV(pv *v1.PersistentVolume) error {
 	if err != nil {
 		return fmt.Errorf("failed to get volume mode of path %q: %v", mountPath, err)
 	}
-	runjob := d.shouldRunJob(volMode)
+	runjob := d.shouldRunJob(volMode, config)
 
 	// Exit if cleaning is still in progress.
 	if d.CleanupStatus.InProgress(pv.Name, runjob) {
@@ -164,6 +177,11 @@ func (d *Deleter) deletePV(pv *v1.PersistentVolume) error {
 		return err
 	}
 
+	mode := string(volMode)
+	deleteType := metrics.DeleteTypeProcess
+	if runjob {
+		deleteType = metrics.DeleteTypeJob
+	}
 	switch state {
 	case CSSucceeded:
 		// Found a completed cleaning entry
@@ -175,11 +193,6 @@ func (d *Deleter) deletePV(pv *v1.PersistentVolume) error {
 				return fmt.Errorf("Error deleting PV %q: %v", pv.Name, err.Error())
 			}
 		}
-		mode := string(volMode)
-		deleteType := metrics.DeleteTypeProcess
-		if runjob {
-			deleteType = metrics.DeleteTypeJob
-		}
 		metrics.PersistentVolumeDeleteTotal.WithLabelValues(mode, deleteType).Inc()
 		if startTime != nil {
 			var capacityBytes int64
@@ -196,45 +209,123 @@ func (d *Deleter) deletePV(pv *v1.PersistentVolume) error {
 		return nil
 	case CSFailed:
 		klog.Infof("Cleanup for pv %s failed. Restarting cleanup", pv.Name)
+	case CSTimedOut:
+		klog.Warningf("Cleanup for pv %s timed out. Restarting cleanup", pv.Name)
+		metrics.PersistentVolumeDeleteTimedOutTotal.WithLabelValues(mode, deleteType).Inc()
+		d.RuntimeConfig.Recorder.Eventf(pv, v1.EventTypeWarning, common.EventVolumeCleanupTimedOut,
+			"Cleanup of volume was killed by its timeout")
 	case CSNotFound:
 		klog.Infof("Start cleanup for pv %s", pv.Name)
 	default:
 		return fmt.Errorf("Unexpected state %d for pv %s", state, pv.Name)
 	}
 
-	if volMode == v1.PersistentVolumeBlock {
+	// Never clean a different device than the one the PV was created for.
+	if expected, ok := pv.Annotations[common.AnnDeviceFingerprint]; ok {
+		fingerprint, err := common.GetVolumeFingerprint(d.VolUtil, mountPath, volMode)
+		if err != nil {
+			return fmt.Errorf("failed to get device identity of path %q: %v", mountPath, err)
+		}
+		if fingerprint != expected {
+			return fmt.Errorf("refusing to clean path %q, expected device %q but found %q", mountPath, expected, fingerprint)
+		}
+	}
+
+	if volMode == v1.PersistentVolumeBlock && !recreatesLogicalVolume(config) {
 		if len(config.BlockCleanerCommand) < 1 {
 			return fmt.Errorf("Blockcleaner command was empty for pv %q mountPath %s but mount dir is %s", pv.Name,
 				mountPath, config.MountDir)
 		}
 	}
 
+	if d.DryRun {
+		// The volume is left untouched, the PV is deleted as if it was
+		// cleaned.
+		klog.Infof("Dry run, not cleaning pv %s", pv.Name)
+		return d.APIUtil.DeletePV(pv.Name)
+	}
+
 	if runjob {
 		// If we are dealing with block volumes and using jobs based cleaning for it.
 		return d.runJob(pv, volMode, mountPath, config)
 	}
 
-	return d.runProcess(pv, volMode, mountPath, config)
+	if retry, err := d.checkRetryPolicy(pv, config); !retry {
+		return err
+	}
+	return d.runProcess(pv, volMode, config)
 }
 
-func (d *Deleter) runProcess(pv *v1.PersistentVolume, volMode v1.PersistentVolumeMode, mountPath string,
-	config common.MountConfig) error {
+// checkRetryPolicy returns true if the cleanup process of pv may start, which is
+// not the case before the retry backoff of its last failure has elapsed, and an
+// error the first time it is found to have failed more times than the retry
+// policy allows.
+func (d *Deleter) checkRetryPolicy(pv *v1.PersistentVolume, config common.MountConfig) (bool, error) {
+	failures, lastFailure := d.CleanupStatus.ProcTable.Failures(pv.Name)
+	// The failures before the creation of the PV are the ones of a previous PV of
+	// the same volume.
+	if failures == 0 || config.CleanupPolicy == nil || lastFailure.Before(pv.CreationTimestamp.Time) {
+		return true, nil
+	}
+	policy := config.CleanupPolicy
+	if policy.MaxRetries != nil && failures > *policy.MaxRetries {
+		if !d.CleanupStatus.ProcTable.GiveUp(pv.Name) {
+			klog.V(4).Infof("Cleanup for pv %s gave up after %d retries", pv.Name, *policy.MaxRetries)
+			return false, nil
+		}
+		return false, fmt.Errorf("cleanup failed %d times, giving up after %d retries", failures, *policy.MaxRetries)
+	}
+	if wait := policy.RetryBackoff.Duration - time.Since(lastFailure); wait > 0 {
+		klog.V(4).Infof("Retrying cleanup for pv %s in %v", pv.Name, wait)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (d *Deleter) runProcess(pv *v1.PersistentVolume, volMode v1.PersistentVolumeMode, config common.MountConfig) error {
 	// Run as exec script.
-	err := d.CleanupStatus.ProcTable.MarkRunning(pv.Name)
+	position, err := d.CleanupStatus.StartProcess(pv.Name, pv.Spec.StorageClassName, config, func(config common.MountConfig) {
+		// The configuration may have changed while the cleanup was queued.
+		mountPath, err := common.GetContainerPath(pv, config)
+		if err != nil {
+			klog.Errorf("Error cleaning PV %q: %v", pv.Name, err)
+			if err := d.CleanupStatus.ProcTable.MarkFailed(pv.Name); err != nil {
+				klog.Error(err)
+			}
+			return
+		}
+		d.asyncCleanPV(pv, volMode, mountPath, config)
+	})
 	if err != nil {
 		return err
 	}
-
-	go d.asyncCleanPV(pv, volMode, mountPath, config)
+	if position > 0 {
+		klog.Infof("Cleanup for pv %s is queued at position %d", pv.Name, position)
+		d.RuntimeConfig.Recorder.Eventf(pv, v1.EventTypeNormal, common.EventVolumeCleanupQueued,
+			"Volume is waiting for other cleanups to end, at position %d in the queue", position)
+	}
 	return nil
 }
 
 func (d *Deleter) asyncCleanPV(pv *v1.PersistentVolume, volMode v1.PersistentVolumeMode, mountPath string,
 	config common.MountConfig) {
 
-	err := d.cleanPV(pv, volMode, mountPath, config)
+	defer metrics.PersistentVolumeCleanupProgressRatio.DeleteLabelValues(pv.Name)
+	ctx := context.Background()
+	if config.CleanupPolicy != nil && config.CleanupPolicy.Timeout.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.CleanupPolicy.Timeout.Duration)
+		defer cancel()
+	}
+	err := d.cleanPV(ctx, pv, volMode, mountPath, config)
 	if err != nil {
 		klog.Error(err)
+		if ctx.Err() == context.DeadlineExceeded {
+			if err := d.CleanupStatus.ProcTable.MarkTimedOut(pv.Name); err != nil {
+				klog.Error(err)
+			}
+			return
+		}
 		// Set process as failed.
 		if err := d.CleanupStatus.ProcTable.MarkFailed(pv.Name); err != nil {
 			klog.Error(err)
@@ -247,7 +338,7 @@ func (d *Deleter) asyncCleanPV(pv *v1.PersistentVolume, volMode v1.PersistentVol
 	}
 }
 
-func (d *Deleter) cleanPV(pv *v1.PersistentVolume, volMode v1.PersistentVolumeMode, mountPath string,
+func (d *Deleter) cleanPV(ctx context.Context, pv *v1.PersistentVolume, volMode v1.PersistentVolumeMode, mountPath string,
 	config common.MountConfig) error {
 	// Make absolutely sure here that we are not deleting anything outside of mounted dir
 	if !strings.HasPrefix(mountPath, config.MountDir) {
@@ -260,7 +351,7 @@ func (d *Deleter) cleanPV(pv *v1.PersistentVolume, volMode v1.PersistentVolumeMo
 	case v1.PersistentVolumeFilesystem:
 		err = d.cleanFilePV(pv, mountPath, config)
 	case v1.PersistentVolumeBlock:
-		err = d.cleanBlockPV(pv, mountPath, config)
+		err = d.cleanBlockPV(ctx, pv, mountPath, config)
 	default:
 		err = fmt.Errorf("Unexpected volume mode %q for deleting path %q", volMode, pv.Spec.Local.Path)
 	}
@@ -271,16 +362,60 @@ func (d *Deleter) cleanFilePV(pv *v1.PersistentVolume, mountPath string, config
 	klog.Infof("Deleting PV file volume %q contents at hostpath %q, mountpath %q", pv.Name, pv.Spec.Local.Path,
 		mountPath)
 	hostPath := pv.Spec.Local.Path
-	return d.VolUtil.DeleteContents(hostPath, mountPath)
+	if err := d.VolUtil.DeleteContents(hostPath, mountPath); err != nil {
+		return err
+	}
+	if config.DirectoryPool != nil {
+		return d.restoreProjectQuota(mountPath, config)
+	}
+	return nil
 }
 
-func (d *Deleter) cleanBlockPV(pv *v1.PersistentVolume, blkdevPath string, config common.MountConfig) error {
+// restoreProjectQuota makes sure the directory of a directory pool keeps its
+// project and the quota of the pool once its contents are deleted, so that
+// the capacity of the PV is enforced for the next claim. It holds the volume
+// lock so that concurrent cleanups never assign the same new project.
+func (d *Deleter) restoreProjectQuota(mountPath string, config common.MountConfig) error {
+	d.VolumeLock.Lock()
+	defer d.VolumeLock.Unlock()
+	projectID, err := d.QuotaUtil.GetProjectID(mountPath)
+	if err != nil {
+		return fmt.Errorf("failed to get project ID of %q: %v", mountPath, err)
+	}
+	if projectID == 0 {
+		parentDir := filepath.Dir(mountPath)
+		projectIDs, err := common.GetProjectIDs(d.VolUtil, d.QuotaUtil, parentDir)
+		if err != nil {
+			return fmt.Errorf("failed to get project IDs under %q: %v", parentDir, err)
+		}
+		projectID, err = common.NewProjectID(d.QuotaUtil, mountPath, projectIDs)
+		if err != nil {
+			return err
+		}
+		klog.Warningf("Directory %q lost its project, assigning project %d", mountPath, projectID)
+	}
+	if err := d.QuotaUtil.SetProjectQuota(mountPath, projectID, config.DirectoryPool.Size.Value()); err != nil {
+		return fmt.Errorf("failed to set quota of %q: %v", mountPath, err)
+	}
+	return nil
+}
+
+func (d *Deleter) cleanBlockPV(ctx context.Context, pv *v1.PersistentVolume, blkdevPath string, config common.MountConfig) error {
 	cleaningInfo := fmt.Errorf("Starting cleanup of Block PV %q, this may take a while", pv.Name)
 	d.RuntimeConfig.Recorder.Eventf(pv, v1.EventTypeNormal, common.VolumeDelete, cleaningInfo.Error())
 	klog.Infof("Deleting PV block volume %q device hostpath %q, mountpath %q", pv.Name, pv.Spec.Local.Path,
 		blkdevPath)
 
-	err := d.execScript(pv.Name, blkdevPath, config.BlockCleanerCommand[0], config.BlockCleanerCommand[1:]...)
+	if recreatesLogicalVolume(config) {
+		if err := d.recreateLogicalVolume(filepath.Base(blkdevPath), config.LVM); err != nil {
+			klog.Error(err)
+			return err
+		}
+		klog.Infof("Completed cleanup of pv %q", pv.Name)
+		return nil
+	}
+
+	err := CleanBlockDevice(ctx, blkdevPath, config.BlockCleanerCommand, reportProgress(d.RuntimeConfig, pv.Name))
 	if err != nil {
 		klog.Error(err)
 		return err
@@ -290,52 +425,33 @@ func (d *Deleter) cleanBlockPV(pv *v1.PersistentVolume, blkdevPath string, confi
 	return nil
 }
 
-func (d *Deleter) execScript(pvName string, blkdevPath string, exe string, exeArgs ...string) error {
-	cmd := exec.Command(exe, exeArgs...)
-	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", common.LocalPVEnv, blkdevPath))
-	var wg sync.WaitGroup
-	// Wait for stderr & stdout  go routines
-	wg.Add(2)
-
-	outReader, err := cmd.StdoutPipe()
+// recreateLogicalVolume wipes a thin logical volume by removing it and
+// creating it again with the same size in its thin pool. It holds the volume
+// lock so that the discovery does not create it in the meantime.
+func (d *Deleter) recreateLogicalVolume(name string, lvm *common.LVMConfig) error {
+	d.VolumeLock.Lock()
+	defer d.VolumeLock.Unlock()
+	volumes, err := d.LVMUtil.ListLogicalVolumes(lvm.VolumeGroup)
 	if err != nil {
-		return err
+		return fmt.Errorf("volume group %q logical volumes error: %v", lvm.VolumeGroup, err)
 	}
-
-	go func() {
-		defer wg.Done()
-		outScanner := bufio.NewScanner(outReader)
-		for outScanner.Scan() {
-			outstr := outScanner.Text()
-			klog.Infof("Cleanup pv %q: StdoutBuf - %q", pvName, outstr)
+	var volume *util.LogicalVolume
+	for i := range volumes {
+		if volumes[i].Name == name {
+			volume = &volumes[i]
+			break
 		}
-	}()
-
-	errReader, err := cmd.StderrPipe()
-	if err != nil {
-		return err
 	}
-
-	go func() {
-		defer wg.Done()
-		errScanner := bufio.NewScanner(errReader)
-		for errScanner.Scan() {
-			errstr := errScanner.Text()
-			klog.Infof("Cleanup pv %q: StderrBuf - %q", pvName, errstr)
-		}
-	}()
-
-	err = cmd.Start()
-	if err != nil {
-		return err
+	if volume == nil {
+		return fmt.Errorf("logical volume %q not found in volume group %q", name, lvm.VolumeGroup)
 	}
-
-	wg.Wait()
-	err = cmd.Wait()
-	if err != nil {
-		return err
+	klog.Infof("Recreating logical volume %q of %d bytes in volume group %q", name, volume.SizeBytes, lvm.VolumeGroup)
+	if err := d.LVMUtil.RemoveLogicalVolume(lvm.VolumeGroup, name); err != nil {
+		return fmt.Errorf("failed to remove logical volume %q from volume group %q: %v", name, lvm.VolumeGroup, err)
+	}
+	if err := d.LVMUtil.CreateLogicalVolume(lvm.VolumeGroup, name, volume.SizeBytes, volume.ThinPool); err != nil {
+		return fmt.Errorf("failed to create logical volume %q in volume group %q: %v", name, lvm.VolumeGroup, err)
 	}
-
 	return nil
 }
 
@@ -374,6 +490,23 @@ func (d *Deleter) runJob(pv *v1.PersistentVolume, volMode v1.PersistentVolumeMod
 type CleanupStatusTracker struct {
 	ProcTable     ProcTable
 	JobController JobController
+	// Scheduler bounds the number of cleanup processes running at the same time.
+	// Processes start right away if it is nil.
+	Scheduler *CleanupScheduler
+}
+
+// StartProcess starts clean in a goroutine with config, or queues it if the scheduler
+// has no slot left, in which case it gets the configuration of the storage class when
+// it starts. It returns the position of the cleanup in the queue, or 0 if it was started.
+func (c *CleanupStatusTracker) StartProcess(pvName, class string, config common.MountConfig, clean func(config common.MountConfig)) (int, error) {
+	if c.Scheduler != nil {
+		return c.Scheduler.Schedule(pvName, class, clean)
+	}
+	if err := c.ProcTable.MarkRunning(pvName); err != nil {
+		return 0, err
+	}
+	go clean(config)
+	return 0, nil
 }
 
 // InProgress returns true if the cleaning for the specified PV is in progress.
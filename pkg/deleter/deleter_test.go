@@ -26,11 +26,13 @@ import (
 
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/cache"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/dryrun"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
 
 	batch_v1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -74,6 +76,10 @@ type testVol struct {
 	VolumeMode        string
 	reclaimPolicy     v1.PersistentVolumeReclaimPolicy
 	deletionTimestamp *meta_v1.Time
+	// Device fingerprint annotation of the PV
+	fingerprint string
+	// Filesystem UUID currently found at the path of the PV
+	fsUUID string
 }
 
 func TestDeleteVolumes_Basic(t *testing.T) {
@@ -304,6 +310,34 @@ func TestDeleteVolumes_CleanupFails(t *testing.T) {
 	verifyPVExists(t, test)
 }
 
+func TestDeleteVolumes_DeviceMismatch(t *testing.T) {
+	vols := map[string]*testVol{
+		"pv4": {
+			pvPhase:     v1.VolumeReleased,
+			fingerprint: "fsuuid:0b4b7e1c-8d2e-4a57-9b3c-3f1f4c6a2d10",
+			fsUUID:      "5c1d9a2e-7f3b-4e8a-a6d4-1b2c3d4e5f60",
+		},
+		"pv5": {
+			pvPhase:     v1.VolumeReleased,
+			fingerprint: "fsuuid:9e8d7c6b-5a4f-4e3d-2c1b-0a9f8e7d6c5b",
+			fsUUID:      "9e8d7c6b-5a4f-4e3d-2c1b-0a9f8e7d6c5b",
+		},
+	}
+	// A different device found at the path of pv4 must not be cleaned
+	test := &testConfig{
+		vols:               vols,
+		expectedDeletedPVs: map[string]string{"pv5": ""},
+	}
+	d := testSetupForProcCleaning(t, test, nil)
+
+	d.DeletePVs()
+	waitForAsyncToComplete(t, d, "pv5")
+	if test.procTable.MarkRunningCount != 1 {
+		t.Errorf("Unexpected MarkRunning count %d", test.procTable.MarkRunningCount)
+	}
+	verifyDeletedPVs(t, test)
+}
+
 func TestDeleteBlock_BasicProcessExec(t *testing.T) {
 	vols := map[string]*testVol{
 		"pv4": {
@@ -375,6 +409,196 @@ func TestDeleteBlock_FailedProcess(t *testing.T) {
 
 }
 
+func TestDeleteBlock_TimedOutProcess(t *testing.T) {
+	vols := map[string]*testVol{
+		"pv4": {
+			pvPhase:    v1.VolumeReleased,
+			VolumeMode: util.FakeEntryBlock,
+		},
+	}
+	test := &testConfig{vols: vols}
+	d := testSetupForProcCleaning(t, test, []string{"sh", "-c", "sleep 60"})
+	maxRetries := 0
+	setCleanupPolicy(d, &common.CleanupPolicy{Timeout: meta_v1.Duration{Duration: 200 * time.Millisecond}, MaxRetries: &maxRetries})
+
+	if err := d.deletePV(test.generatedPVs["pv4"]); err != nil {
+		t.Fatal(err)
+	}
+	waitForFailures(t, d, "pv4", 1)
+
+	// The timed out cleanup is reported, and not retried.
+	if err := d.deletePV(test.generatedPVs["pv4"]); err == nil {
+		t.Errorf("Expected error as the cleanup may not be retried")
+	}
+	// It is only reported once.
+	if err := d.deletePV(test.generatedPVs["pv4"]); err != nil {
+		t.Errorf("Expected no error once the cleanup was given up, got %v", err)
+	}
+	if stats := test.procTable.Stats(); stats.TimedOut != 1 {
+		t.Errorf("Expected 1 timed out cleanup, got %+v", stats)
+	}
+	if test.procTable.MarkRunningCount != 1 {
+		t.Errorf("Unexpected MarkRunning count %d", test.procTable.MarkRunningCount)
+	}
+	timedOutEvents := 0
+	recorderChan := d.RuntimeConfig.Recorder.(*record.FakeRecorder).Events
+	for len(recorderChan) > 0 {
+		if strings.Contains(<-recorderChan, common.EventVolumeCleanupTimedOut) {
+			timedOutEvents++
+		}
+	}
+	if timedOutEvents != 1 {
+		t.Errorf("Expected 1 %s event, got %d", common.EventVolumeCleanupTimedOut, timedOutEvents)
+	}
+	verifyPVExists(t, test)
+}
+
+func TestDeleteBlock_RetryBackoff(t *testing.T) {
+	vols := map[string]*testVol{
+		"pv4": {
+			pvPhase:    v1.VolumeReleased,
+			VolumeMode: util.FakeEntryBlock,
+		},
+	}
+	test := &testConfig{vols: vols}
+	d := testSetupForProcCleaning(t, test, []string{"sh", "-c", "exit 10"})
+	setCleanupPolicy(d, &common.CleanupPolicy{RetryBackoff: meta_v1.Duration{Duration: time.Hour}})
+
+	if err := d.deletePV(test.generatedPVs["pv4"]); err != nil {
+		t.Fatal(err)
+	}
+	waitForFailures(t, d, "pv4", 1)
+
+	// The failed cleanup waits for its backoff before it is retried.
+	for i := 0; i < 2; i++ {
+		if err := d.deletePV(test.generatedPVs["pv4"]); err != nil {
+			t.Error(err)
+		}
+	}
+	if test.procTable.MarkRunningCount != 1 {
+		t.Errorf("Unexpected MarkRunning count %d", test.procTable.MarkRunningCount)
+	}
+
+	setCleanupPolicy(d, &common.CleanupPolicy{})
+	if err := d.deletePV(test.generatedPVs["pv4"]); err != nil {
+		t.Error(err)
+	}
+	if test.procTable.MarkRunningCount != 2 {
+		t.Errorf("Unexpected MarkRunning count %d", test.procTable.MarkRunningCount)
+	}
+	waitForFailures(t, d, "pv4", 2)
+}
+
+func TestDeleteBlock_RetryPolicyOfPreviousPV(t *testing.T) {
+	vols := map[string]*testVol{
+		"pv4": {
+			pvPhase:    v1.VolumeReleased,
+			VolumeMode: util.FakeEntryBlock,
+		},
+	}
+	test := &testConfig{vols: vols}
+	d := testSetupForProcCleaning(t, test, []string{"sh", "-c", "exit 10"})
+	maxRetries := 0
+	setCleanupPolicy(d, &common.CleanupPolicy{MaxRetries: &maxRetries})
+
+	// The cleanup of a previous PV of the volume failed.
+	test.procTable.MarkRunning("pv4")
+	test.procTable.MarkFailed("pv4")
+	test.procTable.RemoveEntry("pv4")
+	pv := test.generatedPVs["pv4"].DeepCopy()
+	pv.CreationTimestamp = meta_v1.NewTime(time.Now().Add(time.Second))
+
+	if err := d.deletePV(pv); err != nil {
+		t.Error(err)
+	}
+	if test.procTable.MarkRunningCount != 2 {
+		t.Errorf("Unexpected MarkRunning count %d", test.procTable.MarkRunningCount)
+	}
+	waitForFailures(t, d, "pv4", 2)
+}
+
+func TestDeleteBlock_RecreateLogicalVolume(t *testing.T) {
+	vols := map[string]*testVol{
+		"pv4": {
+			pvPhase:    v1.VolumeReleased,
+			VolumeMode: util.FakeEntryBlock,
+		},
+	}
+	// The volume should be deleted after its logical volume was recreated
+	expectedDeletedPVs := map[string]string{"pv4": ""}
+	test := &testConfig{vols: vols, expectedDeletedPVs: expectedDeletedPVs}
+	// The block cleaner command is not needed
+	d := testSetupForProcCleaning(t, test, nil)
+	lvmUtil := util.NewFakeLVMUtil(&util.VolumeGroup{Name: "vg0", SizeBytes: 100 << 30, FreeBytes: 90 << 30})
+	lvmUtil.LogicalVolumes["vg0"] = []util.LogicalVolume{{Name: "entry-pv4", SizeBytes: 10 << 30, ThinPool: "pool0"}}
+	d.LVMUtil = lvmUtil
+	config := d.DiscoveryMap[testStorageClass]
+	config.LVM = &common.LVMConfig{VolumeGroup: "vg0", Count: 1, ThinPool: "pool0", RecreateOnDelete: true}
+	d.DiscoveryMap[testStorageClass] = config
+
+	err := d.deletePV(test.generatedPVs["pv4"])
+	if err != nil {
+		t.Error(err)
+	}
+
+	waitForAsyncToComplete(t, d)
+
+	if test.procTable.MarkDoneCount != 1 {
+		t.Errorf("Unexpected MarkDone count %d", test.procTable.MarkDoneCount)
+	}
+	if !reflect.DeepEqual(lvmUtil.Removed, []string{"entry-pv4"}) || !reflect.DeepEqual(lvmUtil.Created, []string{"entry-pv4"}) {
+		t.Errorf("Expected logical volume entry-pv4 to be recreated, removed %v, created %v", lvmUtil.Removed, lvmUtil.Created)
+	}
+	expectedVolumes := []util.LogicalVolume{{Name: "entry-pv4", SizeBytes: 10 << 30, ThinPool: "pool0"}}
+	if !reflect.DeepEqual(lvmUtil.LogicalVolumes["vg0"], expectedVolumes) {
+		t.Errorf("Expected logical volumes %v, got %v", expectedVolumes, lvmUtil.LogicalVolumes["vg0"])
+	}
+
+	verifyDeletedPVs(t, test)
+}
+
+func TestDeleteVolumes_DirectoryPool(t *testing.T) {
+	vols := map[string]*testVol{
+		"pv4": {
+			pvPhase: v1.VolumeReleased,
+		},
+		"pv5": {
+			pvPhase: v1.VolumeReleased,
+		},
+	}
+	expectedDeletedPVs := map[string]string{"pv4": "", "pv5": ""}
+	test := &testConfig{vols: vols, expectedDeletedPVs: expectedDeletedPVs}
+	d := testSetupForProcCleaning(t, test, nil)
+	quotaUtil := util.NewFakeQuotaUtil()
+	d.QuotaUtil = quotaUtil
+	size := resource.MustParse("10Gi")
+	config := d.DiscoveryMap[testStorageClass]
+	config.DirectoryPool = &common.DirectoryPool{Count: 2, Size: &size}
+	d.DiscoveryMap[testStorageClass] = config
+	// The limit of pv4 was changed and pv5 lost its project
+	pv4Path := filepath.Join(testMountDir, "test1", "entry-pv4")
+	pv5Path := filepath.Join(testMountDir, "test1", "entry-pv5")
+	quotaUtil.ProjectIDs[pv4Path] = 42
+	quotaUtil.Limits[42] = 1 << 30
+
+	d.DeletePVs()
+	waitForAsyncToComplete(t, d, "pv4", "pv5")
+	verifyDeletedPVs(t, test)
+
+	if quotaUtil.ProjectIDs[pv4Path] != 42 {
+		t.Errorf("Expected project 42 of %q to be preserved, got %d", pv4Path, quotaUtil.ProjectIDs[pv4Path])
+	}
+	pv5ProjectID := quotaUtil.ProjectIDs[pv5Path]
+	if pv5ProjectID == 0 || pv5ProjectID == 42 {
+		t.Errorf("Expected a new project for %q, got %d", pv5Path, pv5ProjectID)
+	}
+	for _, projectID := range []uint32{42, pv5ProjectID} {
+		if quotaUtil.Limits[projectID] != size.Value() {
+			t.Errorf("Expected limit %d of project %d, got %d", size.Value(), projectID, quotaUtil.Limits[projectID])
+		}
+	}
+}
+
 func TestDeleteBlock_DuplicateAttempts(t *testing.T) {
 	vols := map[string]*testVol{
 		"pv4": {
@@ -411,6 +635,46 @@ func TestDeleteBlock_DuplicateAttempts(t *testing.T) {
 	}
 }
 
+func TestDeleteBlock_QueuedProcess(t *testing.T) {
+	vols := map[string]*testVol{
+		"pv4": {
+			pvPhase:    v1.VolumeReleased,
+			VolumeMode: util.FakeEntryBlock,
+		},
+		"pv5": {
+			pvPhase:    v1.VolumeReleased,
+			VolumeMode: util.FakeEntryBlock,
+		},
+	}
+	expectedDeletedPVs := map[string]string{"pv4": "", "pv5": ""}
+	test := &testConfig{vols: vols, expectedDeletedPVs: expectedDeletedPVs}
+	d := testSetupForProcCleaning(t, test, []string{"sh", "-c", "sleep 0.5"})
+	d.CleanupStatus.Scheduler = NewCleanupScheduler(test.procTable)
+	d.CleanupStatus.Scheduler.SetLimits(&common.UserConfig{MaxConcurrentCleanups: 1, DiscoveryMap: d.DiscoveryMap})
+
+	d.DeletePVs()
+	// One of the cleanups waits for the other one to end.
+	if test.procTable.MarkQueuedCount != 1 {
+		t.Errorf("Unexpected MarkQueued count %d", test.procTable.MarkQueuedCount)
+	}
+	queuedEvents := 0
+	recorderChan := d.RuntimeConfig.Recorder.(*record.FakeRecorder).Events
+	for len(recorderChan) > 0 {
+		if strings.Contains(<-recorderChan, common.EventVolumeCleanupQueued) {
+			queuedEvents++
+		}
+	}
+	if queuedEvents != 1 {
+		t.Errorf("Expected 1 %s event, got %d", common.EventVolumeCleanupQueued, queuedEvents)
+	}
+
+	waitForAsyncToComplete(t, d, "pv4", "pv5")
+	if test.procTable.MarkRunningCount != 2 {
+		t.Errorf("Unexpected MarkRunning count %d", test.procTable.MarkRunningCount)
+	}
+	verifyDeletedPVs(t, test)
+}
+
 func TestDeleteBlock_Jobs(t *testing.T) {
 	vols := map[string]*testVol{
 		"pv4": {
@@ -512,6 +776,34 @@ func TestDeleteBlock_DuplicateAttempts_Jobs(t *testing.T) {
 	}
 }
 
+func TestDeleteBlock_DryRun(t *testing.T) {
+	vols := map[string]*testVol{
+		"pv4": {
+			pvPhase:    v1.VolumeReleased,
+			VolumeMode: util.FakeEntryBlock,
+		},
+	}
+	test := &testConfig{vols: vols, volDeleteShouldFail: true}
+	d := testSetupForJobCleaning(t, test, []string{"sh", "-c", "exit 1"})
+	plan := &dryrun.Plan{}
+	d.APIUtil = dryrun.NewAPIUtil(test.cache, plan)
+	d.DryRun = true
+	test.clientset.ClearActions()
+
+	d.DeletePVs()
+	if len(test.clientset.Actions()) != 0 {
+		t.Errorf("Expected no API requests in dry run, got %v", test.clientset.Actions())
+	}
+	if test.procTable.MarkRunningCount != 0 {
+		t.Errorf("Expected no cleanup process in dry run, got %d", test.procTable.MarkRunningCount)
+	}
+	changes := plan.Changes()
+	if len(changes) != 1 || changes[0].Action != dryrun.ActionDelete || changes[0].PersistentVolume != "pv4" {
+		t.Errorf("Expected deletion of PV pv4 in plan, got %+v", changes)
+	}
+	verifyPVExists(t, test)
+}
+
 func testSetupForProcCleaning(t *testing.T, config *testConfig, cleanupCmd []string) *Deleter {
 	return testSetup(t, config, cleanupCmd, false)
 }
@@ -584,6 +876,9 @@ func testSetup(t *testing.T, config *testConfig, cleanupCmd []string, useJobForC
 		} else {
 			lpvConfig.ReclaimPolicy = v1.PersistentVolumeReclaimDelete
 		}
+		if vol.fingerprint != "" {
+			lpvConfig.Annotations = map[string]string{common.AnnDeviceFingerprint: vol.fingerprint}
+		}
 		pv := common.CreateLocalPVSpec(&lpvConfig)
 		pv.Status.Phase = vol.pvPhase
 		pv.DeletionTimestamp = vol.deletionTimestamp
@@ -599,7 +894,7 @@ func testSetup(t *testing.T, config *testConfig, cleanupCmd []string, useJobForC
 			vol.VolumeMode = util.FakeEntryFile
 		}
 		newVols["test1"] = append(newVols["test1"], &util.FakeDirEntry{Name: "entry-" + pvName, Hash: 0xf34b8003,
-			VolumeType: vol.VolumeMode})
+			VolumeType: vol.VolumeMode, FsUUID: vol.fsUUID})
 	}
 	// Update volume util
 	config.volUtil.AddNewDirEntries(testMountDir, newVols)
@@ -667,6 +962,22 @@ func verifyPVExists(t *testing.T, config *testConfig) {
 }
 
 // waitForAsyncToComplete Since commands are all async, this function helps wait for commands to complete.
+func setCleanupPolicy(d *Deleter, policy *common.CleanupPolicy) {
+	config := d.DiscoveryMap[testStorageClass]
+	config.CleanupPolicy = policy
+	d.DiscoveryMap[testStorageClass] = config
+}
+
+func waitForFailures(t *testing.T, d *Deleter, pvName string, failures int) {
+	for count := 0; count < 50; count++ {
+		if n, _ := d.CleanupStatus.ProcTable.Failures(pvName); n >= failures {
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %d failed cleanups of pv %s", failures, pvName)
+}
+
 func waitForAsyncToComplete(t *testing.T, d *Deleter, pvNames ...string) {
 	for count := 0; count < 30 && !d.CleanupStatus.ProcTable.IsEmpty(); count++ {
 		time.Sleep(200 * time.Millisecond)
@@ -70,6 +70,9 @@ type jobController struct {
 	namespace string
 	queue     workqueue.RateLimitingInterface
 	jobLister batchlisters.JobLister
+	// progressUpdates are the times the cleanup progress annotations were
+	// last updated from the status of the jobs, only used by the worker.
+	progressUpdates map[string]time.Time
 }
 
 // NewJobController instantiates  a new job controller.
@@ -116,10 +119,11 @@ func NewJobController(labelmap map[string]string, config *common.RuntimeConfig)
 	})
 
 	return &jobController{
-		RuntimeConfig: config,
-		namespace:     namespace,
-		queue:         queue,
-		jobLister:     batchlisters.NewJobLister(informer.GetIndexer()),
+		RuntimeConfig:   config,
+		namespace:       namespace,
+		queue:           queue,
+		jobLister:       batchlisters.NewJobLister(informer.GetIndexer()),
+		progressUpdates: map[string]time.Time{},
 	}, nil
 
 }
@@ -179,6 +183,7 @@ func (c *jobController) processItem(key string) error {
 	job, err := c.jobLister.Jobs(namespace).Get(name)
 	if errors.IsNotFound(err) {
 		klog.Infof("Job %s has been deleted", key)
+		delete(c.progressUpdates, key)
 		return nil
 	}
 	if err != nil {
@@ -192,6 +197,8 @@ func (c *jobController) processItem(key string) error {
 		return nil
 	}
 
+	c.reportJobProgress(key, job)
+
 	if job.Status.Succeeded == 0 {
 		klog.Infof("Job %s has not yet completed successfully", key)
 		return nil
@@ -201,6 +208,20 @@ func (c *jobController) processItem(key string) error {
 	return nil
 }
 
+// reportJobProgress sets the cleanup progress annotation of the PV of job to
+// its status, at most every progressAnnotationPeriod while the job is active.
+func (c *jobController) reportJobProgress(key string, job *batch_v1.Job) {
+	pvName, ok := job.Labels[PVLabel]
+	if !ok {
+		return
+	}
+	if job.Status.Active > 0 && time.Since(c.progressUpdates[key]) < progressAnnotationPeriod {
+		return
+	}
+	c.progressUpdates[key] = time.Now()
+	annotateCleanupProgress(c.RuntimeConfig, pvName, formatJobStatus(job))
+}
+
 // IsCleaningJobRunning returns true if a cleaning job is running for the specified PV.
 func (c *jobController) IsCleaningJobRunning(pvName string) bool {
 	jobName := generateCleaningJobName(pvName)
@@ -215,7 +236,18 @@ func (c *jobController) IsCleaningJobRunning(pvName string) bool {
 		return true
 	}
 
-	return job.Status.Succeeded <= 0
+	return job.Status.Succeeded <= 0 && !jobTimedOut(job)
+}
+
+// jobTimedOut returns true if the job failed because it ran past its active deadline.
+func jobTimedOut(job *batch_v1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batch_v1.JobFailed && condition.Status == apiv1.ConditionTrue &&
+			condition.Reason == batch_v1.JobReasonDeadlineExceeded {
+			return true
+		}
+	}
+	return false
 }
 
 // RemoveJob returns true and deletes the job if the cleaning job has completed.
@@ -240,16 +272,21 @@ func (c *jobController) RemoveJob(pvName string) (CleanupState, *time.Time, erro
 		}
 	}
 
+	state := CSSucceeded
 	if job.Status.Succeeded == 0 {
-		// Jobs has not yet succeeded. We assume failed jobs to be still running, until addressed by admin.
-		return CSUnknown, nil, fmt.Errorf("Error deleting Job %q: Cannot remove job that has not succeeded", job.Name)
+		if !jobTimedOut(job) {
+			// Jobs has not yet succeeded. We assume failed jobs to be still running, until addressed by admin.
+			return CSUnknown, nil, fmt.Errorf("Error deleting Job %q: Cannot remove job that has not succeeded", job.Name)
+		}
+		// Jobs killed by their deadline are removed, so that the cleanup is restarted.
+		state = CSTimedOut
 	}
 
 	if err := c.RuntimeConfig.APIUtil.DeleteJob(job.Name, c.namespace); err != nil {
 		return CSUnknown, nil, fmt.Errorf("Error deleting Job %q: %s", job.Name, err.Error())
 	}
 
-	return CSSucceeded, startTime, nil
+	return state, startTime, nil
 }
 
 // NewCleanupJob creates manifest for a cleaning job.
@@ -265,6 +302,11 @@ func NewCleanupJob(pv *apiv1.PersistentVolume, volMode apiv1.PersistentVolumeMod
 	}
 	if volMode == apiv1.PersistentVolumeBlock {
 		jobContainer.Command = config.BlockCleanerCommand
+		if len(config.BlockCleanerCommand) > 0 && common.IsBuiltinBlockCleaner(config.BlockCleanerCommand[0]) {
+			// Built-in block cleaners are run by the provisioner binary of
+			// the image.
+			jobContainer.Command = append([]string{BlockCleanerBinary, "--" + CleanBlockDeviceFlag, "--"}, config.BlockCleanerCommand...)
+		}
 		jobContainer.Env = []apiv1.EnvVar{{Name: common.LocalPVEnv, Value: mountPath}}
 	} else if volMode == apiv1.PersistentVolumeFilesystem {
 		// We only have one way to clean filesystem, so no need to customize
@@ -337,6 +379,10 @@ func NewCleanupJob(pv *apiv1.PersistentVolume, volMode apiv1.PersistentVolumeMod
 	job.ObjectMeta = podTemplate.ObjectMeta
 	job.Spec.Template.Spec = podTemplate.Spec
 	job.Spec.Template.Spec.RestartPolicy = apiv1.RestartPolicyOnFailure
+	if config.CleanupPolicy != nil && config.CleanupPolicy.Timeout.Duration > 0 {
+		deadline := max(int64(config.CleanupPolicy.Timeout.Seconds()), 1)
+		job.Spec.ActiveDeadlineSeconds = &deadline
+	}
 
 	return job, nil
 }
@@ -27,18 +27,31 @@ type ProcTable interface {
 	// CleanupBlockPV deletes block based PV
 	IsRunning(pvName string) bool
 	IsEmpty() bool
+	MarkQueued(pvName string) error
 	MarkRunning(pvName string) error
 	MarkFailed(pvName string) error
+	MarkTimedOut(pvName string) error
 	MarkSucceeded(pvName string) error
+	// Failures returns the number of cleanups of the PV which failed or timed out in a
+	// row and the time of the last one.
+	Failures(pvName string) (int, time.Time)
+	// GiveUp records that the cleanup of the PV is not retried anymore after its last
+	// failure, and returns true unless it was already recorded.
+	GiveUp(pvName string) bool
 	RemoveEntry(pvName string) (CleanupState, *time.Time, error)
+	// Prune forgets what is kept about the PVs which don't exist anymore
+	// between their cleanups.
+	Prune(exists func(pvName string) bool)
 	Stats() ProcTableStats
 }
 
 // ProcTableStats represents stats of ProcTable.
 type ProcTableStats struct {
+	Queued    int
 	Running   int
 	Succeeded int
 	Failed    int
+	TimedOut  int
 }
 
 var _ ProcTable = &ProcTableImpl{}
@@ -49,25 +62,35 @@ type ProcEntry struct {
 	Status    CleanupState
 }
 
+// failureRecord records the cleanups of a PV which failed or timed out in a row.
+type failureRecord struct {
+	count int
+	last  time.Time
+	// gaveUp is set once the cleanup is not retried anymore.
+	gaveUp bool
+}
+
 // ProcTableImpl Implementation of BLockCleaner interface
 type ProcTableImpl struct {
 	mutex     sync.RWMutex
 	procTable map[string]ProcEntry
+	failures  map[string]failureRecord
 	succeeded int
 	failed    int
+	timedOut  int
 }
 
 // NewProcTable returns a BlockCleaner
 func NewProcTable() *ProcTableImpl {
-	return &ProcTableImpl{procTable: make(map[string]ProcEntry)}
+	return &ProcTableImpl{procTable: make(map[string]ProcEntry), failures: make(map[string]failureRecord)}
 }
 
-// IsRunning Check if cleanup process is still running
+// IsRunning Check if cleanup process is still running or queued
 func (v *ProcTableImpl) IsRunning(pvName string) bool {
 	v.mutex.RLock()
 	defer v.mutex.RUnlock()
 
-	if entry, ok := v.procTable[pvName]; !ok || entry.Status != CSRunning {
+	if entry, ok := v.procTable[pvName]; !ok || (entry.Status != CSRunning && entry.Status != CSQueued) {
 		return false
 	}
 
@@ -81,15 +104,33 @@ func (v *ProcTableImpl) IsEmpty() bool {
 	return len(v.procTable) == 0
 }
 
-// MarkRunning Indicate that process is running.
-func (v *ProcTableImpl) MarkRunning(pvName string) error {
+// MarkQueued Indicate that process waits to run.
+func (v *ProcTableImpl) MarkQueued(pvName string) error {
 	v.mutex.Lock()
 	defer v.mutex.Unlock()
 	_, ok := v.procTable[pvName]
 	if ok {
+		return fmt.Errorf("Failed to mark queued of %q as it is already running, should never happen", pvName)
+	}
+	v.procTable[pvName] = ProcEntry{StartTime: time.Now(), Status: CSQueued}
+	return nil
+}
+
+// MarkRunning Indicate that process is running, the process may have been queued.
+// A queued process keeps the time it was queued as start time, so that the
+// delete duration includes the time spent in the queue.
+func (v *ProcTableImpl) MarkRunning(pvName string) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	entry, ok := v.procTable[pvName]
+	if ok && entry.Status != CSQueued {
 		return fmt.Errorf("Failed to mark running of %q as it is already running, should never happen", pvName)
 	}
-	v.procTable[pvName] = ProcEntry{StartTime: time.Now(), Status: CSRunning}
+	if !ok {
+		entry.StartTime = time.Now()
+	}
+	entry.Status = CSRunning
+	v.procTable[pvName] = entry
 	return nil
 }
 
@@ -98,19 +139,55 @@ func (v *ProcTableImpl) MarkFailed(pvName string) error {
 	return v.markStatus(pvName, CSFailed)
 }
 
+// MarkTimedOut Indicate the process was killed by its timeout.
+func (v *ProcTableImpl) MarkTimedOut(pvName string) error {
+	return v.markStatus(pvName, CSTimedOut)
+}
+
 // MarkSucceeded Indicate the process has succeeded in its run.
 func (v *ProcTableImpl) MarkSucceeded(pvName string) error {
 	return v.markStatus(pvName, CSSucceeded)
 }
 
+// Failures returns the number of cleanups of the PV which failed or timed out in a row
+// and the time of the last one.
+func (v *ProcTableImpl) Failures(pvName string) (int, time.Time) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	record := v.failures[pvName]
+	return record.count, record.last
+}
+
+// GiveUp records that the cleanup of the PV is not retried anymore after its last
+// failure, and returns true unless it was already recorded.
+func (v *ProcTableImpl) GiveUp(pvName string) bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	record, ok := v.failures[pvName]
+	if !ok || record.gaveUp {
+		return false
+	}
+	record.gaveUp = true
+	v.failures[pvName] = record
+	return true
+}
+
 func (v *ProcTableImpl) markStatus(pvName string, status CleanupState) error {
 	v.mutex.Lock()
 	defer v.mutex.Unlock()
 	defer func() {
-		if status == CSSucceeded {
+		switch status {
+		case CSSucceeded:
 			v.succeeded++
-		} else if status == CSFailed {
-			v.failed++
+			delete(v.failures, pvName)
+		case CSFailed, CSTimedOut:
+			if status == CSFailed {
+				v.failed++
+			} else {
+				v.timedOut++
+			}
+			record := v.failures[pvName]
+			v.failures[pvName] = failureRecord{count: record.count + 1, last: time.Now()}
 		}
 	}()
 	entry, ok := v.procTable[pvName]
@@ -132,7 +209,7 @@ func (v *ProcTableImpl) RemoveEntry(pvName string) (CleanupState, *time.Time, er
 	if !ok {
 		return CSNotFound, nil, nil
 	}
-	if entry.Status == CSRunning {
+	if entry.Status == CSRunning || entry.Status == CSQueued {
 		return CSUnknown, nil, fmt.Errorf("cannot remove proctable entry for %q when it is still running", pvName)
 	}
 	if entry.Status == CSUnknown {
@@ -142,28 +219,64 @@ func (v *ProcTableImpl) RemoveEntry(pvName string) (CleanupState, *time.Time, er
 	return entry.Status, &entry.StartTime, nil
 }
 
+// Prune forgets the finished cleanups and the failures of the PVs which don't
+// exist anymore.
+func (v *ProcTableImpl) Prune(exists func(pvName string) bool) {
+	v.prune(exists)
+}
+
+// prune forgets the finished cleanups and the failures of the PVs which don't
+// exist anymore, and returns whether it forgot any.
+func (v *ProcTableImpl) prune(exists func(pvName string) bool) bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	pruned := false
+	for pvName, entry := range v.procTable {
+		if entry.Status != CSQueued && entry.Status != CSRunning && !exists(pvName) {
+			delete(v.procTable, pvName)
+			pruned = true
+		}
+	}
+	for pvName := range v.failures {
+		if _, ok := v.procTable[pvName]; !ok && !exists(pvName) {
+			delete(v.failures, pvName)
+			pruned = true
+		}
+	}
+	return pruned
+}
+
 // Stats returns stats of ProcTable.
 func (v *ProcTableImpl) Stats() ProcTableStats {
 	v.mutex.RLock()
 	defer v.mutex.RUnlock()
-	running := 0
+	queued, running := 0, 0
 	for _, entry := range v.procTable {
-		if entry.Status == CSRunning {
+		switch entry.Status {
+		case CSQueued:
+			queued++
+		case CSRunning:
 			running++
 		}
 	}
 	return ProcTableStats{
+		Queued:    queued,
 		Running:   running,
 		Succeeded: v.succeeded,
 		Failed:    v.failed,
+		TimedOut:  v.timedOut,
 	}
 }
 
 // FakeProcTableImpl creates a mock proc table that enables testing.
 type FakeProcTableImpl struct {
 	realTable ProcTable
+	// mutex protects the counters updated by concurrent cleanups
+	mutex sync.Mutex
 	// IsRunningCount keeps count of number of times IsRunning() was called
 	IsRunningCount int
+	// MarkQueuedCount keeps count of number of times MarkQueued() was called
+	MarkQueuedCount int
 	// MarkRunningCount keeps count of number of times MarkRunning() was called
 	MarkRunningCount int
 	// MarkDoneCount keeps count of number of times MarkDone() was called
@@ -183,7 +296,7 @@ func NewFakeProcTable() *FakeProcTableImpl {
 
 // IsRunning Check if cleanup process is still running
 func (f *FakeProcTableImpl) IsRunning(pvName string) bool {
-	f.IsRunningCount++
+	f.count(&f.IsRunningCount)
 	return f.realTable.IsRunning(pvName)
 }
 
@@ -192,32 +305,66 @@ func (f *FakeProcTableImpl) IsEmpty() bool {
 	return f.realTable.IsEmpty()
 }
 
+// MarkQueued Indicate that process waits to run.
+func (f *FakeProcTableImpl) MarkQueued(pvName string) error {
+	f.count(&f.MarkQueuedCount)
+	return f.realTable.MarkQueued(pvName)
+}
+
 // MarkRunning Indicate that process is running.
 func (f *FakeProcTableImpl) MarkRunning(pvName string) error {
-	f.MarkRunningCount++
+	f.count(&f.MarkRunningCount)
 	return f.realTable.MarkRunning(pvName)
 }
 
 // MarkFailed Indicate the process has failed.
 func (f *FakeProcTableImpl) MarkFailed(pvName string) error {
-	f.MarkDoneCount++
+	f.count(&f.MarkDoneCount)
 	return f.realTable.MarkFailed(pvName)
 }
 
+// MarkTimedOut Indicate the process was killed by its timeout.
+func (f *FakeProcTableImpl) MarkTimedOut(pvName string) error {
+	f.count(&f.MarkDoneCount)
+	return f.realTable.MarkTimedOut(pvName)
+}
+
+// Failures returns the number of cleanups which failed or timed out in a row.
+func (f *FakeProcTableImpl) Failures(pvName string) (int, time.Time) {
+	return f.realTable.Failures(pvName)
+}
+
+// GiveUp records that the cleanup is not retried anymore.
+func (f *FakeProcTableImpl) GiveUp(pvName string) bool {
+	return f.realTable.GiveUp(pvName)
+}
+
 // MarkSucceeded Indicate the process has succeeded.
 func (f *FakeProcTableImpl) MarkSucceeded(pvName string) error {
-	f.MarkDoneCount++
+	f.count(&f.MarkDoneCount)
 	return f.realTable.MarkSucceeded(pvName)
 }
 
 // RemoveEntry removes the entry from the proc table.
 func (f *FakeProcTableImpl) RemoveEntry(pvName string) (CleanupState, *time.Time, error) {
-	f.RemoveCount++
+	f.count(&f.RemoveCount)
 	return f.realTable.RemoveEntry(pvName)
 }
 
+// Prune forgets the PVs which don't exist anymore.
+func (f *FakeProcTableImpl) Prune(exists func(pvName string) bool) {
+	f.realTable.Prune(exists)
+}
+
 // Stats returns stats of ProcTable.
 func (f *FakeProcTableImpl) Stats() ProcTableStats {
-	f.StatsCount++
+	f.count(&f.StatsCount)
 	return f.realTable.Stats()
 }
+
+// count increments counter of the fake.
+func (f *FakeProcTableImpl) count(counter *int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	*counter++
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deleter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+// checkpointStates are the names of the states in the checkpoints.
+var checkpointStates = map[CleanupState]string{
+	CSQueued:    "queued",
+	CSRunning:   "running",
+	CSFailed:    "failed",
+	CSTimedOut:  "timedOut",
+	CSSucceeded: "succeeded",
+}
+
+// procTableCheckpoint is the content of the checkpoint file of a
+// CheckpointedProcTable.
+type procTableCheckpoint struct {
+	Entries  map[string]checkpointEntry   `json:"entries"`
+	Failures map[string]checkpointFailure `json:"failures,omitempty"`
+	// Restarted are the start times of the interrupted cleanups which are
+	// restarted, kept for their next run.
+	Restarted map[string]time.Time `json:"restarted,omitempty"`
+}
+
+type checkpointEntry struct {
+	StartTime time.Time `json:"startTime"`
+	State     string    `json:"state"`
+}
+
+type checkpointFailure struct {
+	Count  int       `json:"count"`
+	Last   time.Time `json:"last"`
+	GaveUp bool      `json:"gaveUp,omitempty"`
+}
+
+// CheckpointedProcTable is a ProcTable checkpointed to a file after each
+// change, so that the cleanups survive restarts of the provisioner.
+type CheckpointedProcTable struct {
+	*ProcTableImpl
+	// mutex orders the checkpoints as the changes they record.
+	mutex sync.Mutex
+	path  string
+	// restarted are the start times of the interrupted cleanups which are
+	// restarted.
+	restarted map[string]time.Time
+}
+
+var _ ProcTable = &CheckpointedProcTable{}
+
+// NewCheckpointedProcTable returns a ProcTable checkpointed to the file at
+// path, which is loaded if it exists. The cleanups which were queued or
+// running when it was checkpointed were interrupted, they are restarted or
+// marked failed according to interruptedPolicy.
+func NewCheckpointedProcTable(path string, interruptedPolicy string) (*CheckpointedProcTable, error) {
+	t := &CheckpointedProcTable{
+		ProcTableImpl: NewProcTable(),
+		path:          path,
+		restarted:     map[string]time.Time{},
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read proctable checkpoint %q: %v", path, err)
+	default:
+		checkpoint := procTableCheckpoint{}
+		if err := json.Unmarshal(data, &checkpoint); err != nil {
+			klog.Errorf("Ignoring invalid proctable checkpoint %q: %v", path, err)
+		} else {
+			t.restore(checkpoint, interruptedPolicy)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory of proctable checkpoint %q: %v", path, err)
+	}
+	if err := t.save(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// MarkQueued Indicate that process waits to run.
+func (t *CheckpointedProcTable) MarkQueued(pvName string) error {
+	return t.update(func() error { return t.ProcTableImpl.MarkQueued(pvName) })
+}
+
+// MarkRunning Indicate that process is running. The restarted cleanups keep the start
+// time of their interrupted run.
+func (t *CheckpointedProcTable) MarkRunning(pvName string) error {
+	return t.update(func() error {
+		if err := t.ProcTableImpl.MarkRunning(pvName); err != nil {
+			return err
+		}
+		if startTime, ok := t.restarted[pvName]; ok {
+			delete(t.restarted, pvName)
+			t.ProcTableImpl.mutex.Lock()
+			t.ProcTableImpl.procTable[pvName] = ProcEntry{StartTime: startTime, Status: CSRunning}
+			t.ProcTableImpl.mutex.Unlock()
+		}
+		return nil
+	})
+}
+
+// MarkFailed Indicate the process has failed in its run.
+func (t *CheckpointedProcTable) MarkFailed(pvName string) error {
+	return t.update(func() error { return t.ProcTableImpl.MarkFailed(pvName) })
+}
+
+// MarkTimedOut Indicate the process was killed by its timeout.
+func (t *CheckpointedProcTable) MarkTimedOut(pvName string) error {
+	return t.update(func() error { return t.ProcTableImpl.MarkTimedOut(pvName) })
+}
+
+// MarkSucceeded Indicate the process has succeeded in its run.
+func (t *CheckpointedProcTable) MarkSucceeded(pvName string) error {
+	return t.update(func() error { return t.ProcTableImpl.MarkSucceeded(pvName) })
+}
+
+// GiveUp records that the cleanup is not retried anymore, so that it is only
+// reported once, also across restarts.
+func (t *CheckpointedProcTable) GiveUp(pvName string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.ProcTableImpl.GiveUp(pvName) {
+		return false
+	}
+	if err := t.save(); err != nil {
+		klog.Error(err)
+	}
+	return true
+}
+
+// RemoveEntry Removes proctable entry and returns final state and start time of cleanup.
+func (t *CheckpointedProcTable) RemoveEntry(pvName string) (CleanupState, *time.Time, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	state, startTime, err := t.ProcTableImpl.RemoveEntry(pvName)
+	if err == nil && state != CSNotFound {
+		if err := t.save(); err != nil {
+			klog.Error(err)
+		}
+	}
+	return state, startTime, err
+}
+
+// Prune forgets the failures and the interrupted cleanups of the PVs which
+// don't exist anymore, e.g. which were deleted while the provisioner was down.
+func (t *CheckpointedProcTable) Prune(exists func(pvName string) bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	pruned := t.ProcTableImpl.prune(exists)
+	for pvName := range t.restarted {
+		if !exists(pvName) {
+			klog.Infof("Forgetting interrupted cleanup of deleted pv %s", pvName)
+			delete(t.restarted, pvName)
+			pruned = true
+		}
+	}
+	if pruned {
+		if err := t.save(); err != nil {
+			klog.Error(err)
+		}
+	}
+}
+
+// update applies change and checkpoints the table if it succeeded. Failing to
+// checkpoint does not fail the change, the table in memory stays authoritative.
+func (t *CheckpointedProcTable) update(change func() error) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if err := change(); err != nil {
+		return err
+	}
+	if err := t.save(); err != nil {
+		klog.Error(err)
+	}
+	return nil
+}
+
+// restore loads checkpoint into the table, reconciling the interrupted
+// cleanups according to interruptedPolicy.
+func (t *CheckpointedProcTable) restore(checkpoint procTableCheckpoint, interruptedPolicy string) {
+	v := t.ProcTableImpl
+	for pvName, failure := range checkpoint.Failures {
+		v.failures[pvName] = failureRecord{count: failure.Count, last: failure.Last, gaveUp: failure.GaveUp}
+	}
+	for pvName, startTime := range checkpoint.Restarted {
+		t.restarted[pvName] = startTime
+	}
+	for pvName, entry := range checkpoint.Entries {
+		state := CSUnknown
+		for s, name := range checkpointStates {
+			if name == entry.State {
+				state = s
+			}
+		}
+		switch state {
+		case CSUnknown:
+			klog.Warningf("Ignoring proctable checkpoint entry of pv %s in unknown state %q", pvName, entry.State)
+		case CSQueued, CSRunning:
+			if interruptedPolicy == common.InterruptedCleanupFail {
+				klog.Warningf("Marking interrupted cleanup of pv %s failed", pvName)
+				v.procTable[pvName] = ProcEntry{StartTime: entry.StartTime, Status: CSFailed}
+				record := v.failures[pvName]
+				v.failures[pvName] = failureRecord{count: record.count + 1, last: time.Now()}
+				continue
+			}
+			klog.Infof("Restarting interrupted cleanup of pv %s", pvName)
+			t.restarted[pvName] = entry.StartTime
+		default:
+			v.procTable[pvName] = ProcEntry{StartTime: entry.StartTime, Status: state}
+		}
+	}
+}
+
+// save writes the checkpoint of the table, replacing the previous one
+// atomically. Must be called with the mutex held.
+func (t *CheckpointedProcTable) save() error {
+	v := t.ProcTableImpl
+	checkpoint := procTableCheckpoint{
+		Entries:   map[string]checkpointEntry{},
+		Failures:  map[string]checkpointFailure{},
+		Restarted: t.restarted,
+	}
+	v.mutex.RLock()
+	for pvName, entry := range v.procTable {
+		checkpoint.Entries[pvName] = checkpointEntry{StartTime: entry.StartTime, State: checkpointStates[entry.Status]}
+	}
+	for pvName, record := range v.failures {
+		checkpoint.Failures[pvName] = checkpointFailure{Count: record.count, Last: record.last, GaveUp: record.gaveUp}
+	}
+	v.mutex.RUnlock()
+
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to encode proctable checkpoint: %v", err)
+	}
+	tmpPath := t.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write proctable checkpoint %q: %v", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, t.path); err != nil {
+		return fmt.Errorf("failed to replace proctable checkpoint %q: %v", t.path, err)
+	}
+	return nil
+}
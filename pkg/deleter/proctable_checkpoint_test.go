@@ -0,0 +1,157 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deleter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+// newInterruptedProcTable returns the path of the checkpoint of a proctable
+// where the cleanup of pv1 is running, the one of pv2 succeeded, the one of
+// pv3 failed and the one of pv4 is queued, and the start time of pv1.
+func newInterruptedProcTable(t *testing.T) (string, time.Time) {
+	path := filepath.Join(t.TempDir(), "state", "proctable.json")
+	table, err := NewCheckpointedProcTable(path, common.InterruptedCleanupRestart)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, pvName := range []string{"pv1", "pv2", "pv3"} {
+		if err := table.MarkRunning(pvName); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := table.MarkSucceeded("pv2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := table.MarkFailed("pv3"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := table.MarkQueued("pv4"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return path, table.procTable["pv1"].StartTime
+}
+
+func TestCheckpointedProcTable_Restart(t *testing.T) {
+	path, startTime := newInterruptedProcTable(t)
+	table, err := NewCheckpointedProcTable(path, common.InterruptedCleanupRestart)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The interrupted cleanups are restarted.
+	for _, pvName := range []string{"pv1", "pv4"} {
+		if table.IsRunning(pvName) {
+			t.Errorf("Expected interrupted cleanup of %s not to be running", pvName)
+		}
+		if state, _, err := table.RemoveEntry(pvName); err != nil || state != CSNotFound {
+			t.Errorf("Expected no entry for %s, got state %d and error %v", pvName, state, err)
+		}
+	}
+	if state, _, err := table.RemoveEntry("pv2"); err != nil || state != CSSucceeded {
+		t.Errorf("Expected succeeded cleanup of pv2, got state %d and error %v", state, err)
+	}
+	if failures, _ := table.Failures("pv3"); failures != 1 {
+		t.Errorf("Expected 1 failure of pv3, got %d", failures)
+	}
+
+	// The restarted cleanup keeps its start time, also across another restart.
+	table, err = NewCheckpointedProcTable(path, common.InterruptedCleanupRestart)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := table.MarkRunning("pv1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := table.MarkSucceeded("pv1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	state, restartedStartTime, err := table.RemoveEntry("pv1")
+	if err != nil || state != CSSucceeded {
+		t.Fatalf("Expected succeeded cleanup of pv1, got state %d and error %v", state, err)
+	}
+	if !restartedStartTime.Equal(startTime) {
+		t.Errorf("Expected start time %v of the interrupted cleanup, got %v", startTime, restartedStartTime)
+	}
+}
+
+func TestCheckpointedProcTable_Prune(t *testing.T) {
+	path, _ := newInterruptedProcTable(t)
+	table, err := NewCheckpointedProcTable(path, common.InterruptedCleanupRestart)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// pv3 and pv4 were deleted while the provisioner was down.
+	table.Prune(func(pvName string) bool { return pvName == "pv1" || pvName == "pv2" })
+	table, err = NewCheckpointedProcTable(path, common.InterruptedCleanupRestart)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := table.restarted["pv1"]; !ok {
+		t.Errorf("Expected interrupted cleanup of pv1 to be kept")
+	}
+	if _, ok := table.restarted["pv4"]; ok {
+		t.Errorf("Expected interrupted cleanup of pv4 to be forgotten")
+	}
+	if failures, _ := table.Failures("pv3"); failures != 0 {
+		t.Errorf("Expected failures of pv3 to be forgotten, got %d", failures)
+	}
+}
+
+func TestCheckpointedProcTable_Fail(t *testing.T) {
+	path, _ := newInterruptedProcTable(t)
+	table, err := NewCheckpointedProcTable(path, common.InterruptedCleanupFail)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, pvName := range []string{"pv1", "pv4"} {
+		if failures, _ := table.Failures(pvName); failures != 1 {
+			t.Errorf("Expected 1 failure of %s, got %d", pvName, failures)
+		}
+		if state, _, err := table.RemoveEntry(pvName); err != nil || state != CSFailed {
+			t.Errorf("Expected failed cleanup of %s, got state %d and error %v", pvName, state, err)
+		}
+	}
+}
+
+func TestCheckpointedProcTable_InvalidCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proctable.json")
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	table, err := NewCheckpointedProcTable(path, common.InterruptedCleanupRestart)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !table.IsEmpty() {
+		t.Errorf("Expected empty proctable")
+	}
+	// The invalid checkpoint is replaced.
+	if _, err := NewCheckpointedProcTable(path, common.InterruptedCleanupRestart); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != `{"entries":{}}` {
+		t.Errorf("Expected empty checkpoint, got %q and error %v", data, err)
+	}
+}
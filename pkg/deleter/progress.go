@@ -0,0 +1,90 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deleter
+
+import (
+	"fmt"
+	"time"
+
+	batch_v1 "k8s.io/api/batch/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/metrics"
+)
+
+// reportProgress returns a ProgressFunc reporting the progress of the cleanup
+// of pvName in the cleanup progress ratio metric, and in the cleanup progress
+// annotation of the PV at most every progressAnnotationPeriod and once it is
+// complete.
+func reportProgress(config *common.RuntimeConfig, pvName string) ProgressFunc {
+	startTime := time.Now()
+	var lastUpdate time.Time
+	return func(doneBytes, totalBytes int64) {
+		if totalBytes <= 0 {
+			return
+		}
+		metrics.PersistentVolumeCleanupProgressRatio.WithLabelValues(pvName).Set(float64(doneBytes) / float64(totalBytes))
+		if doneBytes < totalBytes && time.Since(lastUpdate) < progressAnnotationPeriod {
+			return
+		}
+		lastUpdate = time.Now()
+		annotateCleanupProgress(config, pvName, formatProgress(doneBytes, totalBytes, time.Since(startTime)))
+	}
+}
+
+// formatProgress describes the progress of a cleanup which processed
+// doneBytes of totalBytes in elapsed, with its estimated time to completion.
+func formatProgress(doneBytes, totalBytes int64, elapsed time.Duration) string {
+	progress := fmt.Sprintf("%d%% (%d of %d bytes)", doneBytes*100/totalBytes, doneBytes, totalBytes)
+	if doneBytes > 0 && doneBytes < totalBytes {
+		eta := time.Duration(float64(elapsed) * float64(totalBytes-doneBytes) / float64(doneBytes))
+		progress += fmt.Sprintf(", ETA %v", eta.Round(time.Second))
+	}
+	return progress
+}
+
+// formatJobStatus describes the status of the cleaning job of a PV. The
+// bytes processed by the job are only logged by its pod.
+func formatJobStatus(job *batch_v1.Job) string {
+	status := fmt.Sprintf("job %s: %d active, %d succeeded, %d failed pods", job.Name, job.Status.Active,
+		job.Status.Succeeded, job.Status.Failed)
+	if job.Status.StartTime != nil {
+		status += fmt.Sprintf(", started at %s", job.Status.StartTime.UTC().Format(time.RFC3339))
+	}
+	return status
+}
+
+// annotateCleanupProgress sets the cleanup progress annotation of the PV
+// pvName to progress. Failing to update the PV does not fail the cleanup, the
+// next report updates it again.
+func annotateCleanupProgress(config *common.RuntimeConfig, pvName string, progress string) {
+	pv, exists := config.Cache.GetPV(pvName)
+	if !exists || pv.Annotations[common.AnnCleanupProgress] == progress {
+		return
+	}
+	newPV := pv.DeepCopy()
+	if newPV.Annotations == nil {
+		newPV.Annotations = map[string]string{}
+	}
+	newPV.Annotations[common.AnnCleanupProgress] = progress
+	updatedPV, err := config.APIUtil.UpdatePV(newPV)
+	if err != nil {
+		klog.Warningf("Error updating annotation %q of PV %q: %v", common.AnnCleanupProgress, pvName, err)
+		return
+	}
+	config.Cache.UpdatePV(updatedPV)
+}
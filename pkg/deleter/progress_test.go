@@ -0,0 +1,155 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deleter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	batch_v1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/cache"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/metrics"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
+)
+
+func TestFormatProgress(t *testing.T) {
+	testcases := map[string]struct {
+		doneBytes int64
+		elapsed   time.Duration
+		expected  string
+	}{
+		"started": {
+			doneBytes: 0,
+			expected:  "0% (0 of 1000 bytes)",
+		},
+		"in progress": {
+			doneBytes: 250,
+			elapsed:   10 * time.Minute,
+			expected:  "25% (250 of 1000 bytes), ETA 30m0s",
+		},
+		"complete": {
+			doneBytes: 1000,
+			elapsed:   time.Hour,
+			expected:  "100% (1000 of 1000 bytes)",
+		},
+	}
+	for name, tc := range testcases {
+		if progress := formatProgress(tc.doneBytes, 1000, tc.elapsed); progress != tc.expected {
+			t.Errorf("%s: expected progress %q, got %q", name, tc.expected, progress)
+		}
+	}
+}
+
+func newProgressRuntimeConfig(t *testing.T, pvName string) *common.RuntimeConfig {
+	clientset := fake.NewSimpleClientset()
+	pv, err := clientset.CoreV1().PersistentVolumes().Create(context.TODO(),
+		&v1.PersistentVolume{ObjectMeta: meta_v1.ObjectMeta{Name: pvName}}, meta_v1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := &common.RuntimeConfig{
+		UserConfig: &common.UserConfig{},
+		Cache:      cache.NewVolumeCache(),
+		APIUtil:    util.NewAPIUtil(clientset),
+	}
+	config.Cache.AddPV(pv)
+	return config
+}
+
+func expectCleanupProgress(t *testing.T, config *common.RuntimeConfig, pvName string, expected string) {
+	pv, exists := config.Cache.GetPV(pvName)
+	if !exists {
+		t.Fatalf("PV %q not found", pvName)
+	}
+	if progress := pv.Annotations[common.AnnCleanupProgress]; progress != expected {
+		t.Errorf("Expected cleanup progress %q, got %q", expected, progress)
+	}
+}
+
+func TestReportProgress(t *testing.T) {
+	pvName := "pv-progress"
+	config := newProgressRuntimeConfig(t, pvName)
+	defer metrics.PersistentVolumeCleanupProgressRatio.DeleteLabelValues(pvName)
+	progress := reportProgress(config, pvName)
+
+	expectRatio := func(expected float64) {
+		if ratio := testutil.ToFloat64(metrics.PersistentVolumeCleanupProgressRatio.WithLabelValues(pvName)); ratio != expected {
+			t.Errorf("Expected cleanup progress ratio %v, got %v", expected, ratio)
+		}
+	}
+
+	progress(0, 1000)
+	expectRatio(0)
+	expectCleanupProgress(t, config, pvName, "0% (0 of 1000 bytes)")
+
+	// The annotation is not updated again within progressAnnotationPeriod.
+	progress(500, 1000)
+	expectRatio(0.5)
+	expectCleanupProgress(t, config, pvName, "0% (0 of 1000 bytes)")
+
+	// The end of the cleanup is always reported.
+	progress(1000, 1000)
+	expectRatio(1)
+	expectCleanupProgress(t, config, pvName, "100% (1000 of 1000 bytes)")
+}
+
+func TestAnnotateJobStatus(t *testing.T) {
+	pvName := "pv-job"
+	config := newProgressRuntimeConfig(t, pvName)
+	job := &batch_v1.Job{
+		ObjectMeta: meta_v1.ObjectMeta{Name: generateCleaningJobName(pvName)},
+		Status:     batch_v1.JobStatus{Active: 1, Failed: 2},
+	}
+	annotateCleanupProgress(config, pvName, formatJobStatus(job))
+	expectCleanupProgress(t, config, pvName, "job cleanup-pv-job: 1 active, 0 succeeded, 2 failed pods")
+
+	// The progress of a PV which is gone is ignored.
+	annotateCleanupProgress(config, "pv-deleted", formatJobStatus(job))
+}
+
+func TestReportJobProgress(t *testing.T) {
+	pvName := "pv-job"
+	config := newProgressRuntimeConfig(t, pvName)
+	c := &jobController{RuntimeConfig: config, progressUpdates: map[string]time.Time{}}
+	startTime := meta_v1.NewTime(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	job := &batch_v1.Job{
+		ObjectMeta: meta_v1.ObjectMeta{Name: generateCleaningJobName(pvName), Labels: map[string]string{PVLabel: pvName}},
+		Status:     batch_v1.JobStatus{Active: 1, StartTime: &startTime},
+	}
+	key := "kube-system/" + job.Name
+	c.reportJobProgress(key, job)
+	expectCleanupProgress(t, config, pvName, "job cleanup-pv-job: 1 active, 0 succeeded, 0 failed pods, started at 2026-01-02T03:04:05Z")
+
+	// The annotation is not updated again within progressAnnotationPeriod
+	// while the job is active.
+	job = job.DeepCopy()
+	job.Status.Failed = 1
+	c.reportJobProgress(key, job)
+	expectCleanupProgress(t, config, pvName, "job cleanup-pv-job: 1 active, 0 succeeded, 0 failed pods, started at 2026-01-02T03:04:05Z")
+
+	// The end of the job is always reported.
+	job.Status.Active = 0
+	job.Status.Succeeded = 1
+	c.reportJobProgress(key, job)
+	expectCleanupProgress(t, config, pvName, "job cleanup-pv-job: 0 active, 1 succeeded, 1 failed pods, started at 2026-01-02T03:04:05Z")
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deleter
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+// CleanupScheduler bounds the number of cleanup processes running at the same
+// time on the node, globally and per storage class. The cleanups over the
+// limits are marked queued in the ProcTable and wait in a queue ordered by the
+// priority of their storage class, then by arrival. The cleanups get the
+// configuration of their storage class when they start, so that the queued
+// ones follow the configuration changes.
+type CleanupScheduler struct {
+	mutex     sync.Mutex
+	procTable ProcTable
+	// classes are the configurations of the storage classes.
+	classes map[string]common.MountConfig
+	// maxConcurrent is the global limit, unlimited if zero.
+	maxConcurrent int
+	// classLimits are the limits of the storage classes, unlimited if zero.
+	classLimits map[string]int
+	queue       []*cleanupTask
+	running     int
+	// classRunning is the number of running cleanups of each storage class.
+	classRunning map[string]int
+}
+
+type cleanupTask struct {
+	pvName   string
+	class    string
+	priority int
+	clean    func(config common.MountConfig)
+}
+
+// CleanupSchedulerStats represents the queued and running cleanups of a
+// storage class.
+type CleanupSchedulerStats struct {
+	Queued  int
+	Running int
+}
+
+// NewCleanupScheduler returns a CleanupScheduler tracking its cleanups in
+// procTable, without limits until SetLimits is called.
+func NewCleanupScheduler(procTable ProcTable) *CleanupScheduler {
+	return &CleanupScheduler{
+		procTable:    procTable,
+		classes:      map[string]common.MountConfig{},
+		classLimits:  map[string]int{},
+		classRunning: map[string]int{},
+	}
+}
+
+// SetLimits sets the global limit and the configurations of the storage
+// classes of config, which hold their limits and priorities, and starts the
+// queued cleanups allowed by them.
+func (s *CleanupScheduler) SetLimits(config *common.UserConfig) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.maxConcurrent = config.MaxConcurrentCleanups
+	s.classes = maps.Clone(config.DiscoveryMap)
+	clear(s.classLimits)
+	for class, mountConfig := range s.classes {
+		if mountConfig.CleanupPolicy != nil {
+			s.classLimits[class] = mountConfig.CleanupPolicy.MaxConcurrent
+		}
+	}
+	for _, task := range s.queue {
+		task.priority = s.priority(task.class)
+	}
+	slices.SortStableFunc(s.queue, func(a, b *cleanupTask) int { return cmp.Compare(b.priority, a.priority) })
+	s.dispatch()
+}
+
+// Schedule starts clean in a goroutine for the PV of a storage class, or
+// queues it if a limit is reached. clean gets the configuration of the
+// storage class when it starts and must mark the end of the cleanup in the
+// ProcTable. It returns the position of the cleanup in the queue, or 0 if it
+// was started.
+func (s *CleanupScheduler) Schedule(pvName string, class string, clean func(config common.MountConfig)) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.classes[class]; !ok {
+		return 0, fmt.Errorf("storage class %q is not configured", class)
+	}
+	task := &cleanupTask{pvName: pvName, class: class, priority: s.priority(class), clean: clean}
+	if len(s.queue) == 0 && s.hasSlot(class) {
+		if err := s.procTable.MarkRunning(pvName); err != nil {
+			return 0, err
+		}
+		s.start(task, s.classes[class])
+		return 0, nil
+	}
+	if err := s.procTable.MarkQueued(pvName); err != nil {
+		return 0, err
+	}
+	// Insert after the cleanups with the same or a higher priority.
+	position := len(s.queue)
+	for i, queued := range s.queue {
+		if queued.priority < task.priority {
+			position = i
+			break
+		}
+	}
+	s.queue = append(s.queue, nil)
+	copy(s.queue[position+1:], s.queue[position:])
+	s.queue[position] = task
+	s.dispatch()
+	for i, queued := range s.queue {
+		if queued == task {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// Stats returns the queued and running cleanups of each storage class.
+func (s *CleanupScheduler) Stats() map[string]CleanupSchedulerStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stats := map[string]CleanupSchedulerStats{}
+	for class, running := range s.classRunning {
+		stats[class] = CleanupSchedulerStats{Running: running}
+	}
+	for _, task := range s.queue {
+		classStats := stats[task.class]
+		classStats.Queued++
+		stats[task.class] = classStats
+	}
+	return stats
+}
+
+func (s *CleanupScheduler) priority(class string) int {
+	if policy := s.classes[class].CleanupPolicy; policy != nil {
+		return policy.Priority
+	}
+	return 0
+}
+
+func (s *CleanupScheduler) hasSlot(class string) bool {
+	if s.maxConcurrent > 0 && s.running >= s.maxConcurrent {
+		return false
+	}
+	limit := s.classLimits[class]
+	return limit <= 0 || s.classRunning[class] < limit
+}
+
+// dispatch starts the queued cleanups in order, skipping the ones of the
+// storage classes at their limit, and drops the ones of the storage classes
+// which were removed from the configuration. Must be called with the mutex
+// held.
+func (s *CleanupScheduler) dispatch() {
+	remaining := s.queue[:0]
+	for _, task := range s.queue {
+		config, ok := s.classes[task.class]
+		if !ok {
+			klog.Warningf("Dropping queued cleanup of pv %s of removed storage class %s", task.pvName, task.class)
+			if err := s.procTable.MarkFailed(task.pvName); err != nil {
+				klog.Error(err)
+			}
+			continue
+		}
+		if !s.hasSlot(task.class) {
+			remaining = append(remaining, task)
+			continue
+		}
+		if err := s.procTable.MarkRunning(task.pvName); err != nil {
+			// Dropping the task leaves the PV queued in the ProcTable,
+			// which is never expected.
+			klog.Error(err)
+			continue
+		}
+		s.start(task, config)
+	}
+	clear(s.queue[len(remaining):])
+	s.queue = remaining
+}
+
+// start runs the cleanup of task with the configuration of its storage class.
+// Must be called with the mutex held.
+func (s *CleanupScheduler) start(task *cleanupTask, config common.MountConfig) {
+	s.running++
+	s.classRunning[task.class]++
+	go func() {
+		task.clean(config)
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.running--
+		s.classRunning[task.class]--
+		if s.classRunning[task.class] == 0 {
+			delete(s.classRunning, task.class)
+		}
+		s.dispatch()
+	}()
+}
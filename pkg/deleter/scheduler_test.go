@@ -0,0 +1,240 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deleter
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+type fakeCleanups struct {
+	t         *testing.T
+	procTable ProcTable
+	started   chan string
+	release   map[string]chan struct{}
+}
+
+func newFakeCleanups(t *testing.T, procTable ProcTable) *fakeCleanups {
+	return &fakeCleanups{t: t, procTable: procTable, started: make(chan string, 10), release: map[string]chan struct{}{}}
+}
+
+// clean returns a cleanup of pvName which runs until it is released.
+func (f *fakeCleanups) clean(pvName string) func(common.MountConfig) {
+	release := make(chan struct{})
+	f.release[pvName] = release
+	return func(common.MountConfig) {
+		f.started <- pvName
+		<-release
+		if err := f.procTable.MarkSucceeded(pvName); err != nil {
+			f.t.Error(err)
+		}
+	}
+}
+
+// expectStarted waits for the cleanups of pvNames to start, in any order.
+func (f *fakeCleanups) expectStarted(pvNames ...string) {
+	expected := sets.NewString(pvNames...)
+	started := sets.NewString()
+	for started.Len() < expected.Len() {
+		select {
+		case pvName := <-f.started:
+			started.Insert(pvName)
+		case <-time.After(5 * time.Second):
+			f.t.Fatalf("Timed out waiting for cleanups of %v to start, started %v", expected.List(), started.List())
+		}
+	}
+	if !started.Equal(expected) {
+		f.t.Fatalf("Expected cleanups of %v to start, got %v", expected.List(), started.List())
+	}
+}
+
+func (f *fakeCleanups) expectNoneStarted() {
+	select {
+	case started := <-f.started:
+		f.t.Fatalf("Unexpected start of cleanup of %q", started)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestCleanupScheduler(t *testing.T) {
+	procTable := NewProcTable()
+	scheduler := NewCleanupScheduler(procTable)
+	scheduler.SetLimits(&common.UserConfig{
+		MaxConcurrentCleanups: 2,
+		DiscoveryMap: map[string]common.MountConfig{
+			"sc1": {CleanupPolicy: &common.CleanupPolicy{MaxConcurrent: 1}},
+			"sc2": {CleanupPolicy: &common.CleanupPolicy{Priority: 1}},
+		},
+	})
+	cleanups := newFakeCleanups(t, procTable)
+
+	schedule := func(pvName, class string, expectedPosition int) {
+		position, err := scheduler.Schedule(pvName, class, cleanups.clean(pvName))
+		if err != nil {
+			t.Fatalf("Unexpected error scheduling cleanup of %q: %v", pvName, err)
+		}
+		if position != expectedPosition {
+			t.Errorf("Expected cleanup of %q at position %d, got %d", pvName, expectedPosition, position)
+		}
+		if !procTable.IsRunning(pvName) {
+			t.Errorf("Expected cleanup of %q to be in progress", pvName)
+		}
+	}
+
+	schedule("pv1", "sc1", 0)
+	cleanups.expectStarted("pv1")
+	// Over the limit of sc1.
+	schedule("pv2", "sc1", 1)
+	schedule("pv3", "sc2", 0)
+	cleanups.expectStarted("pv3")
+	// Over the global limit, the higher priority goes first.
+	schedule("pv4", "sc2", 1)
+	cleanups.expectNoneStarted()
+
+	expectedStats := map[string]CleanupSchedulerStats{
+		"sc1": {Queued: 1, Running: 1},
+		"sc2": {Queued: 1, Running: 1},
+	}
+	if stats := scheduler.Stats(); !reflect.DeepEqual(stats, expectedStats) {
+		t.Errorf("Expected stats %+v, got %+v", expectedStats, stats)
+	}
+	if stats := procTable.Stats(); stats.Queued != 2 || stats.Running != 2 {
+		t.Errorf("Expected 2 queued and 2 running entries in the proctable, got %+v", stats)
+	}
+
+	// pv2 is still over the limit of sc1 when pv3 ends.
+	close(cleanups.release["pv3"])
+	cleanups.expectStarted("pv4")
+	cleanups.expectNoneStarted()
+
+	close(cleanups.release["pv1"])
+	cleanups.expectStarted("pv2")
+
+	close(cleanups.release["pv2"])
+	close(cleanups.release["pv4"])
+	deadline := time.Now().Add(5 * time.Second)
+	for len(scheduler.Stats()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for cleanups to end, stats %+v", scheduler.Stats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, pvName := range []string{"pv1", "pv2", "pv3", "pv4"} {
+		if state, _, err := procTable.RemoveEntry(pvName); err != nil || state != CSSucceeded {
+			t.Errorf("Expected succeeded cleanup of %q, got state %d and error %v", pvName, state, err)
+		}
+	}
+}
+
+func TestCleanupSchedulerSetLimits(t *testing.T) {
+	procTable := NewProcTable()
+	scheduler := NewCleanupScheduler(procTable)
+	classes := map[string]common.MountConfig{"sc1": {}}
+	scheduler.SetLimits(&common.UserConfig{MaxConcurrentCleanups: 1, DiscoveryMap: classes})
+	cleanups := newFakeCleanups(t, procTable)
+
+	for i, pvName := range []string{"pv1", "pv2", "pv3"} {
+		position, err := scheduler.Schedule(pvName, "sc1", cleanups.clean(pvName))
+		if err != nil {
+			t.Fatalf("Unexpected error scheduling cleanup of %q: %v", pvName, err)
+		}
+		if position != i {
+			t.Errorf("Expected cleanup of %q at position %d, got %d", pvName, i, position)
+		}
+	}
+	cleanups.expectStarted("pv1")
+	cleanups.expectNoneStarted()
+
+	// Raising the limit starts the queued cleanups.
+	scheduler.SetLimits(&common.UserConfig{MaxConcurrentCleanups: 3, DiscoveryMap: classes})
+	cleanups.expectStarted("pv2", "pv3")
+
+	if _, err := scheduler.Schedule("pv1", "sc1", func(common.MountConfig) {}); err == nil {
+		t.Errorf("Expected error scheduling the cleanup of %q twice", "pv1")
+	}
+	for _, release := range cleanups.release {
+		close(release)
+	}
+}
+
+func TestCleanupSchedulerConfigChange(t *testing.T) {
+	procTable := NewProcTable()
+	scheduler := NewCleanupScheduler(procTable)
+	scheduler.SetLimits(&common.UserConfig{
+		MaxConcurrentCleanups: 1,
+		DiscoveryMap:          map[string]common.MountConfig{"sc1": {}, "sc2": {}},
+	})
+	cleanups := newFakeCleanups(t, procTable)
+	var configs sync.Map
+	for _, pvName := range []string{"pv1", "pv2", "pv3"} {
+		class := "sc1"
+		if pvName == "pv3" {
+			class = "sc2"
+		}
+		clean := cleanups.clean(pvName)
+		if _, err := scheduler.Schedule(pvName, class, func(config common.MountConfig) {
+			configs.Store(pvName, config)
+			clean(config)
+		}); err != nil {
+			t.Fatalf("Unexpected error scheduling cleanup of %q: %v", pvName, err)
+		}
+	}
+	cleanups.expectStarted("pv1")
+
+	// sc2 is removed and sc1 changed while their cleanups are queued.
+	scheduler.SetLimits(&common.UserConfig{
+		MaxConcurrentCleanups: 1,
+		DiscoveryMap:          map[string]common.MountConfig{"sc1": {HostDir: "/mnt/new"}},
+	})
+	if state, _, err := procTable.RemoveEntry("pv3"); err != nil || state != CSFailed {
+		t.Errorf("Expected dropped cleanup of pv3, got state %d and error %v", state, err)
+	}
+	close(cleanups.release["pv1"])
+	cleanups.expectStarted("pv2")
+	if config, _ := configs.Load("pv2"); config.(common.MountConfig).HostDir != "/mnt/new" {
+		t.Errorf("Expected cleanup of pv2 with the new configuration of sc1, got %+v", config)
+	}
+	close(cleanups.release["pv2"])
+	close(cleanups.release["pv3"])
+}
+
+func TestProcTable_QueuedStartTime(t *testing.T) {
+	procTable := NewProcTable()
+	if err := procTable.MarkQueued("pv1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	queuedTime := procTable.procTable["pv1"].StartTime
+	time.Sleep(10 * time.Millisecond)
+	if err := procTable.MarkRunning("pv1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := procTable.MarkSucceeded("pv1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	state, startTime, err := procTable.RemoveEntry("pv1")
+	if err != nil || state != CSSucceeded {
+		t.Fatalf("Expected succeeded cleanup of pv1, got state %d and error %v", state, err)
+	}
+	if !startTime.Equal(queuedTime) {
+		t.Errorf("Expected start time %v of the queued cleanup, got %v", queuedTime, startTime)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/metrics"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// availableCapacityTolerancePercent is the change, in percent of the capacity
+// of an Available PV, of the available bytes of its filesystem below which
+// the PV is not updated, since they move with the writes to the filesystem.
+const availableCapacityTolerancePercent = 1
+
+// reconcileCapacity compares the capacity of an existing PV with the measured
+// capacity of its volume, which changes when the backing device or filesystem
+// is resized. Available PVs are updated so that the scheduler sees the real
+// capacity, while the drift of bound PVs is only reported since their claims
+// were sized against the old capacity.
+func (d *Discoverer) reconcileCapacity(pv *v1.PersistentVolume, config common.MountConfig, volMode v1.PersistentVolumeMode, capacityByte int64) {
+	measured := resource.NewQuantity(roundCapacity(config.CapacityPolicy, capacityByte), resource.BinarySI)
+	advertised := pv.Spec.Capacity[v1.ResourceStorage]
+	drift := measured.Value() - advertised.Value()
+	if drift == 0 || pv.Status.Phase != v1.VolumeBound {
+		d.clearCapacityDrift(pv.Name)
+	}
+	if pv.Status.Phase == v1.VolumeBound && usesFsAvailable(config.CapacityPolicy, volMode) {
+		// The available bytes of the filesystem of a bound PV shrink as
+		// its claim writes to it, which is not a drift.
+		d.clearCapacityDrift(pv.Name)
+		return
+	}
+	if drift == 0 || pv.DeletionTimestamp != nil {
+		return
+	}
+
+	mode := v1.PersistentVolumeFilesystem
+	if pv.Spec.VolumeMode != nil {
+		mode = *pv.Spec.VolumeMode
+	}
+	switch pv.Status.Phase {
+	case v1.VolumeAvailable:
+		if pv.Spec.ClaimRef != nil {
+			// The PV is being bound, it is updated on the next discovery
+			// if the binding fails.
+			return
+		}
+		if usesFsAvailable(config.CapacityPolicy, volMode) && abs(drift)*100 < advertised.Value()*availableCapacityTolerancePercent {
+			return
+		}
+		newPV := pv.DeepCopy()
+		newPV.Spec.Capacity[v1.ResourceStorage] = *measured
+		updatedPV, err := d.APIUtil.UpdatePV(newPV)
+		if err != nil {
+			klog.Errorf("Error updating capacity of PV %q from %s to %s: %v", pv.Name, advertised.String(), measured.String(), err)
+			return
+		}
+		d.Cache.UpdatePV(updatedPV)
+		klog.Infof("Updated capacity of PV %q from %s to %s", pv.Name, advertised.String(), measured.String())
+		d.Recorder.Eventf(pv, v1.EventTypeNormal, common.EventVolumeCapacityUpdated, "Capacity of volume at %q changed from %s to %s", pv.Spec.Local.Path, advertised.String(), measured.String())
+		metrics.PersistentVolumeCapacityUpdateTotal.WithLabelValues(string(mode)).Inc()
+	case v1.VolumeBound:
+		metrics.PersistentVolumeCapacityDriftBytes.WithLabelValues(pv.Name).Set(float64(drift))
+		if d.capacityDrift[pv.Name] == measured.Value() {
+			return
+		}
+		d.capacityDrift[pv.Name] = measured.Value()
+		klog.Warningf("Capacity of bound PV %q is %s, but %s is advertised", pv.Name, measured.String(), advertised.String())
+		d.Recorder.Eventf(pv, v1.EventTypeWarning, common.EventVolumeCapacityDrift, "Capacity of volume at %q is %s, but %s is advertised", pv.Spec.Local.Path, measured.String(), advertised.String())
+	}
+}
+
+// clearCapacityDrift forgets the reported capacity drift of the PV.
+func (d *Discoverer) clearCapacityDrift(pvName string) {
+	if _, ok := d.capacityDrift[pvName]; !ok {
+		return
+	}
+	delete(d.capacityDrift, pvName)
+	metrics.PersistentVolumeCapacityDriftBytes.DeleteLabelValues(pvName)
+}
+
+// pruneCapacityDrift forgets the reported capacity drift of the PVs which
+// are no longer in the cache.
+func (d *Discoverer) pruneCapacityDrift() {
+	for pvName := range d.capacityDrift {
+		if _, exists := d.Cache.GetPV(pvName); !exists {
+			d.clearCapacityDrift(pvName)
+		}
+	}
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// usesFsAvailable returns true if the capacity of the volumes of volMode is
+// the available bytes of their filesystem.
+func usesFsAvailable(policy *common.CapacityPolicy, volMode v1.PersistentVolumeMode) bool {
+	return policy != nil && policy.FsCapacity == common.FsCapacityAvailable && volMode == v1.PersistentVolumeFilesystem
+}
+
+// reserveCapacity returns the capacity of a volume of capacityByte minus the
+// reserve of policy.
+func reserveCapacity(policy *common.CapacityPolicy, capacityByte int64) (int64, error) {
+	reservedByte, err := policy.ReservedBytes(capacityByte)
+	if err != nil {
+		return 0, err
+	}
+	if reservedByte > 0 && reservedByte >= capacityByte {
+		return 0, fmt.Errorf("reserve %s of capacity policy exceeds the capacity %d of the volume", policy.Reserve, capacityByte)
+	}
+	return capacityByte - reservedByte, nil
+}
+
+// roundCapacity rounds down capacityByte to the capacity advertised by the PV
+// according to the rounding of policy.
+func roundCapacity(policy *common.CapacityPolicy, capacityByte int64) int64 {
+	if policy == nil {
+		return roundDownCapacityPretty(capacityByte)
+	}
+	switch policy.Rounding {
+	case common.CapacityRoundingExact:
+		return capacityByte
+	case common.CapacityRoundingUnit:
+		unitByte := policy.RoundingUnit.Value()
+		if capacityByte < unitByte {
+			// Never advertise an empty volume.
+			return capacityByte
+		}
+		return capacityByte / unitByte * unitByte
+	default:
+		return roundDownCapacityPretty(capacityByte)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
+)
+
+// matchDeviceSelector returns true if the block device attributes satisfy
+// every field set in selector.
+func matchDeviceSelector(selector *common.DeviceSelector, attrs *util.DeviceAttributes) bool {
+	if selector.DevType != "" && selector.DevType != devType(attrs) {
+		return false
+	}
+	if !matchPattern(selector.Model, attrs.Model) ||
+		!matchPattern(selector.Vendor, attrs.Vendor) ||
+		!matchPattern(selector.Serial, attrs.Serial) ||
+		!matchPattern(selector.WWN, attrs.WWN) {
+		return false
+	}
+	if selector.Rotational != nil && *selector.Rotational != attrs.Rotational {
+		return false
+	}
+	if selector.Transport != "" && selector.Transport != attrs.Transport {
+		return false
+	}
+	if selector.MinSize != nil && attrs.SizeBytes < selector.MinSize.Value() {
+		return false
+	}
+	if selector.MaxSize != nil && attrs.SizeBytes > selector.MaxSize.Value() {
+		return false
+	}
+	return matchAnyPattern(selector.ByID, attrs.ByID) && matchAnyPattern(selector.ByPath, attrs.ByPath)
+}
+
+func devType(attrs *util.DeviceAttributes) string {
+	if attrs.PartitionNumber > 0 {
+		return common.DevTypePartition
+	}
+	return common.DevTypeDisk
+}
+
+// matchPattern returns true if pattern is empty or value matches it.
+func matchPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, _ := filepath.Match(pattern, value)
+	return matched
+}
+
+// matchAnyPattern returns true if pattern is empty or any of values matches it.
+func matchAnyPattern(pattern string, values []string) bool {
+	if pattern == "" {
+		return true
+	}
+	for _, value := range values {
+		if matched, _ := filepath.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
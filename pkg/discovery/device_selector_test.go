@@ -0,0 +1,77 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
+)
+
+func TestMatchDeviceSelector(t *testing.T) {
+	nvme := &util.DeviceAttributes{
+		Name:      "nvme0n1",
+		Model:     "Example NVMe SSD 2TB",
+		Serial:    "S1234567",
+		WWN:       "eui.0025388b91b2c1a4",
+		Transport: util.TransportNVMe,
+		SizeBytes: 2000398934016,
+		ByID:      []string{"nvme-eui.0025388b91b2c1a4", "nvme-Example_NVMe_SSD_2TB_S1234567"},
+		ByPath:    []string{"pci-0000:00:04.0-nvme-1"},
+	}
+	hdd := &util.DeviceAttributes{
+		Name:            "sdb1",
+		PartitionNumber: 1,
+		Vendor:          "ATA",
+		Model:           "HDD 1TB",
+		Rotational:      true,
+		Transport:       util.TransportSATA,
+		SizeBytes:       1000204886016,
+	}
+	rotational := true
+	solidState := false
+	minSize := resource.MustParse("1Ti")
+	maxSize := resource.MustParse("1Ti")
+
+	tests := []struct {
+		name     string
+		selector common.DeviceSelector
+		attrs    *util.DeviceAttributes
+		expected bool
+	}{
+		{"empty selector", common.DeviceSelector{}, nvme, true},
+		{"non-rotational nvme over 1Ti", common.DeviceSelector{Transport: util.TransportNVMe, Rotational: &solidState, MinSize: &minSize}, nvme, true},
+		{"non-rotational nvme over 1Ti with hdd", common.DeviceSelector{Transport: util.TransportNVMe, Rotational: &solidState, MinSize: &minSize}, hdd, false},
+		{"rotational", common.DeviceSelector{Rotational: &rotational}, hdd, true},
+		{"max size", common.DeviceSelector{MaxSize: &maxSize}, nvme, false},
+		{"model pattern", common.DeviceSelector{Model: "Example*"}, nvme, true},
+		{"vendor pattern", common.DeviceSelector{Vendor: "ATA"}, nvme, false},
+		{"serial and wwn", common.DeviceSelector{Serial: "S1234567", WWN: "eui.*"}, nvme, true},
+		{"disk", common.DeviceSelector{DevType: common.DevTypeDisk}, hdd, false},
+		{"partition", common.DeviceSelector{DevType: common.DevTypePartition}, hdd, true},
+		{"by-id", common.DeviceSelector{ByID: "nvme-Example_*"}, nvme, true},
+		{"by-id without links", common.DeviceSelector{ByID: "*"}, hdd, false},
+		{"by-path", common.DeviceSelector{ByPath: "pci-0000:00:05.0-*"}, nvme, false},
+	}
+	for _, test := range tests {
+		if matched := matchDeviceSelector(&test.selector, test.attrs); matched != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, matched)
+		}
+	}
+}
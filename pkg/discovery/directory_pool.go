@@ -0,0 +1,80 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+// ensureDirectoryPool creates the directories of the directory pool of the
+// storage class which are missing under MountDir, each limited to the size of
+// the pool by a project quota, or records them in the plan in dry run. It
+// refuses a pool whose total size exceeds the capacity of the filesystem, and
+// holds the volume lock while allocating their projects.
+func (d *Discoverer) ensureDirectoryPool(class string, config common.MountConfig) error {
+	capacityByte, err := d.VolUtil.GetFsCapacityByte(config.HostDir, config.MountDir)
+	if err != nil {
+		return fmt.Errorf("failed to get capacity of %q: %v", config.MountDir, err)
+	}
+	poolSize := config.DirectoryPool.Size.Value()
+	if count := int64(config.DirectoryPool.Count); poolSize > 0 && count > capacityByte/poolSize {
+		return fmt.Errorf("directory pool of %d directories of %s exceeds the capacity %d of the filesystem of %q",
+			count, config.DirectoryPool.Size.String(), capacityByte, config.MountDir)
+	}
+	d.VolumeLock.Lock()
+	defer d.VolumeLock.Unlock()
+	files, err := d.VolUtil.ReadDir(config.MountDir)
+	if err != nil {
+		return fmt.Errorf("error reading directory: %v", err)
+	}
+	var projectIDs map[string]uint32
+	for i := 1; i <= config.DirectoryPool.Count; i++ {
+		name := fmt.Sprintf("%s%d", common.NamePrefix, i)
+		if slices.Contains(files, name) {
+			continue
+		}
+		if d.DryRun {
+			d.Plan.RecordVolume(class, filepath.Join(config.HostDir, name), config.DirectoryPool.Size.Value(), "directory missing from directory pool")
+			continue
+		}
+		if projectIDs == nil {
+			projectIDs, err = common.GetProjectIDs(d.VolUtil, d.QuotaUtil, config.MountDir)
+			if err != nil {
+				return fmt.Errorf("failed to get project IDs under %q: %v", config.MountDir, err)
+			}
+		}
+		mountPath := filepath.Join(config.MountDir, name)
+		projectID, err := common.NewProjectID(d.QuotaUtil, mountPath, projectIDs)
+		if err != nil {
+			return err
+		}
+		klog.Infof("Creating directory %q with project %d limited to %s", mountPath, projectID, config.DirectoryPool.Size.String())
+		if err := d.VolUtil.MakeDir(mountPath); err != nil {
+			return fmt.Errorf("failed to create directory %q: %v", mountPath, err)
+		}
+		if err := d.QuotaUtil.SetProjectQuota(mountPath, projectID, config.DirectoryPool.Size.Value()); err != nil {
+			return fmt.Errorf("failed to set quota of directory %q: %v", mountPath, err)
+		}
+		projectIDs[name] = projectID
+	}
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"hash/fnv"
+	"maps"
 	"net/http"
 	"path/filepath"
 	"slices"
@@ -30,9 +31,13 @@ import (
 
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/dryrun"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/inventory"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/metrics"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	storagev1listers "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/tools/cache"
@@ -51,6 +56,22 @@ type Discoverer struct {
 	nodeSelector   *v1.NodeSelector
 	classLister    storagev1listers.StorageClassLister
 	ownerReference *metav1.OwnerReference
+	// capacityDrift is the measured capacity of the bound PVs whose capacity
+	// drift was last reported, by PV name
+	capacityDrift map[string]int64
+	// inventoryVolumes are the volumes seen by discovery, by storage class
+	// and path
+	inventoryVolumes map[string]map[string]*inventory.Volume
+	// inventoryWriter writes inventoryVolumes to the LocalVolumeInventory of
+	// the node, nil if it is not published
+	inventoryWriter *inventory.Writer
+	// reservations are the claims the volumes of the node are reserved for
+	reservations map[reservationKey]common.ClaimReference
+	// reservationConflicts are the PVs claimed by another claim than the one
+	// their volume is reserved for, which were reported
+	reservationConflicts map[string]bool
+	// Plan records the volumes which would be created in dry run
+	Plan *dryrun.Plan
 
 	Readyz *readyzCheck
 }
@@ -106,19 +127,29 @@ func NewDiscoverer(config *common.RuntimeConfig, cleanupTracker *deleter.Cleanup
 		return nil, fmt.Errorf("Failed to generate owner reference: %v", err)
 	}
 
-	nodeSelector, err := generateNodeSelector(config.Node)
+	nodeSelector, err := common.GenerateNodeSelector(config.Node, config.NodeLabelsForPVAffinity)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to generate node selector: %v", err)
 	}
 
+	var inventoryWriter *inventory.Writer
+	if config.PublishInventory && !config.DryRun {
+		inventoryWriter = inventory.NewWriter(config.DynamicClient, config.Node)
+	}
+
 	return &Discoverer{
-		RuntimeConfig:  config,
-		Labels:         labelMap,
-		CleanupTracker: cleanupTracker,
-		classLister:    sharedInformer.Lister(),
-		nodeSelector:   nodeSelector,
-		ownerReference: ownerRef,
-		Readyz:         &readyzCheck{},
+		RuntimeConfig:        config,
+		Labels:               labelMap,
+		CleanupTracker:       cleanupTracker,
+		classLister:          sharedInformer.Lister(),
+		nodeSelector:         nodeSelector,
+		ownerReference:       ownerRef,
+		capacityDrift:        map[string]int64{},
+		inventoryVolumes:     map[string]map[string]*inventory.Volume{},
+		inventoryWriter:      inventoryWriter,
+		reservations:         nodeReservations(config.Node.Name, config.Reservations),
+		reservationConflicts: map[string]bool{},
+		Readyz:               &readyzCheck{},
 	}, nil
 }
 
@@ -139,40 +170,23 @@ func generateOwnerReference(node *v1.Node) (*metav1.OwnerReference, error) {
 	}, nil
 }
 
-func generateNodeSelector(node *v1.Node) (*v1.NodeSelector, error) {
-	if node.Labels == nil {
-		return nil, fmt.Errorf("Node does not have labels")
-	}
-	nodeValue, found := node.Labels[common.NodeLabelKey]
-	if !found {
-		return nil, fmt.Errorf("Node does not have expected label %s", common.NodeLabelKey)
-	}
-
-	return &v1.NodeSelector{
-		NodeSelectorTerms: []v1.NodeSelectorTerm{
-			{
-				MatchExpressions: []v1.NodeSelectorRequirement{
-					{
-						Key:      common.NodeLabelKey,
-						Operator: v1.NodeSelectorOpIn,
-						Values:   []string{nodeValue},
-					},
-				},
-			},
-		},
-	}, nil
-}
-
 // DiscoverLocalVolumes reads the configured discovery paths, and creates PVs for the new volumes
 func (d *Discoverer) DiscoverLocalVolumes() {
 	readyz := true
 	for class, config := range d.DiscoveryMap {
+		if config.DynamicProvisioning {
+			// The volumes of the class are created by the dynamic provisioner.
+			continue
+		}
 		err := d.discoverVolumesAtPath(class, config)
 		if err != nil {
 			klog.Errorf("Failed to discover local volumes: %v", err)
 			readyz = false
 		}
 	}
+	d.checkMissingVolumes()
+	d.pruneCapacityDrift()
+	d.publishInventory()
 	d.Readyz.readySync.Lock()
 	d.Readyz.ready = readyz
 	d.Readyz.readySync.Unlock()
@@ -198,27 +212,109 @@ func (d *Discoverer) getMountOptionsFromStorageClass(name string) ([]string, err
 	return class.MountOptions, nil
 }
 
+// DiscoverLocalVolumesAtPaths creates PVs for the volumes at the given paths only.
+// It is used in watch mode to react to individual changes in the discovery
+// directories without rescanning all of them.
+func (d *Discoverer) DiscoverLocalVolumesAtPaths(events []PathEvent) {
+	filesByClass := map[string][]string{}
+	for _, event := range events {
+		if event.Removed {
+			d.reportRemovedDevice(event)
+			d.removeInventoryVolume(event.Class, event.File)
+			continue
+		}
+		if !slices.Contains(filesByClass[event.Class], event.File) {
+			filesByClass[event.Class] = append(filesByClass[event.Class], event.File)
+		}
+	}
+
+	readyz := true
+	for class, files := range filesByClass {
+		config, ok := d.DiscoveryMap[class]
+		if !ok || config.DynamicProvisioning {
+			continue
+		}
+		klog.V(5).Infof("Discovering volumes %v under mount path %q for storage class %q", files, config.MountDir, class)
+		if _, _, err := d.discoverVolumesAtFiles(class, config, files); err != nil {
+			klog.Errorf("Failed to discover local volumes: %v", err)
+			readyz = false
+		}
+	}
+	d.publishInventory()
+	// A successful partial discovery says nothing about the other paths,
+	// so only a failure changes the readiness state here.
+	if !readyz {
+		d.Readyz.readySync.Lock()
+		d.Readyz.ready = false
+		d.Readyz.readySync.Unlock()
+	}
+}
+
+// reportRemovedDevice records a warning event on the PV of a volume whose
+// block device was removed from the node.
+func (d *Discoverer) reportRemovedDevice(event PathEvent) {
+	config, ok := d.DiscoveryMap[event.Class]
+	if !ok {
+		return
+	}
+	outsidePath := filepath.Join(config.HostDir, event.File)
+	for _, pvName := range d.Cache.LookupPVsByPath(outsidePath) {
+		pv, exists := d.Cache.GetPV(pvName)
+		if !exists {
+			continue
+		}
+		klog.Warningf("Block device %q backing PV %q at %q was removed", event.Device, pvName, outsidePath)
+		d.Recorder.Eventf(pv, v1.EventTypeWarning, common.EventVolumeDeviceRemoved, "Block device %q backing volume at %q was removed from node %q", event.Device, outsidePath, d.Node.Name)
+		mode := v1.PersistentVolumeFilesystem
+		if pv.Spec.VolumeMode != nil {
+			mode = *pv.Spec.VolumeMode
+		}
+		metrics.PersistentVolumeDeviceRemovedTotal.WithLabelValues(string(mode)).Inc()
+	}
+}
+
 func (d *Discoverer) discoverVolumesAtPath(class string, config common.MountConfig) error {
 	klog.V(7).Infof("Discovering volumes at hostpath %q, mount path %q for storage class %q", config.HostDir, config.MountDir, class)
 
-	reclaimPolicy, err := d.getReclaimPolicyFromStorageClass(class)
-	if err != nil {
-		return fmt.Errorf("failed to get ReclaimPolicy from storage class %q: %v", class, err)
+	if config.LVM != nil {
+		if err := d.ensureLogicalVolumes(class, config); err != nil {
+			return err
+		}
 	}
-
-	if reclaimPolicy != v1.PersistentVolumeReclaimRetain && reclaimPolicy != v1.PersistentVolumeReclaimDelete {
-		return fmt.Errorf("unsupported ReclaimPolicy %q from storage class %q, supported policy are Retain and Delete", reclaimPolicy, class)
+	if config.DirectoryPool != nil {
+		if err := d.ensureDirectoryPool(class, config); err != nil {
+			return err
+		}
 	}
 
 	files, err := d.VolUtil.ReadDir(config.MountDir)
 	if err != nil {
 		return fmt.Errorf("error reading directory: %v", err)
 	}
+	d.resetInventory(class)
+
+	totalCapacityBlockBytes, totalCapacityFSBytes, err := d.discoverVolumesAtFiles(class, config, files)
+	metrics.PersistentVolumeCapacityBytes.WithLabelValues(string(v1.PersistentVolumeBlock)).Set(float64(totalCapacityBlockBytes))
+	metrics.PersistentVolumeCapacityBytes.WithLabelValues(string(v1.PersistentVolumeFilesystem)).Set(float64(totalCapacityFSBytes))
+	return err
+}
+
+// discoverVolumesAtFiles creates PVs for the given files under config.MountDir
+// and returns the capacity of the newly discovered block and filesystem volumes.
+func (d *Discoverer) discoverVolumesAtFiles(class string, config common.MountConfig, files []string) (int64, int64, error) {
+	reclaimPolicy, err := d.getReclaimPolicyFromStorageClass(class)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get ReclaimPolicy from storage class %q: %v", class, err)
+	}
+
+	if reclaimPolicy != v1.PersistentVolumeReclaimRetain && reclaimPolicy != v1.PersistentVolumeReclaimDelete {
+		return 0, 0, fmt.Errorf("unsupported ReclaimPolicy %q from storage class %q, supported policy are Retain and Delete", reclaimPolicy, class)
+	}
 
 	// Retrieve list of mount points to iterate through discovered paths (aka files) below
 	mountPoints, err := d.RuntimeConfig.Mounter.List()
 	if err != nil {
-		return fmt.Errorf("error retrieving mountpoints: %v", err)
+		return 0, 0, fmt.Errorf("error retrieving mountpoints: %v", err)
 	}
 	// Put mount points into set for faster checks below
 	type empty struct{}
@@ -227,57 +323,180 @@ func (d *Discoverer) discoverVolumesAtPath(class string, config common.MountConf
 		mountPointMap[mp.Path] = empty{}
 	}
 
-	var namePatterns []string
-	if config.NamePattern != "" {
-		namePatterns = strings.Split(config.NamePattern, ",")
+	filter, err := newEntryFilter(config)
+	if err != nil {
+		return 0, 0, err
 	}
+	files = d.expandDirectories(config, filter, files, mountPointMap)
 
 	var discoErrors []error
+	if config.Partitioning != nil {
+		var partitionErrors []error
+		files, partitionErrors = d.partitionDisks(class, config, files)
+		discoErrors = append(discoErrors, partitionErrors...)
+	}
+
 	var totalCapacityBlockBytes, totalCapacityFSBytes int64
+	var mountedFiles []string
 	for _, file := range files {
-		var matched bool
-		for _, pattern := range namePatterns {
-			if pattern != "" {
-				matched, err = filepath.Match(pattern, file)
-				if err != nil {
-					return err
-				}
-				if matched {
-					break
-				}
-			}
+		if config.VolumeMetadata && isVolumeMetadataFile(filepath.Base(file)) {
+			continue
+		}
+		matched, err := matchNamePattern(config.NamePattern, filepath.Base(file))
+		if err != nil {
+			return 0, 0, err
 		}
-		if config.NamePattern != "" && !matched {
+		outsidePath := filepath.Join(config.HostDir, file)
+		volume := d.inventoryVolume(class, outsidePath)
+		if !matched {
 			klog.V(5).Infof("file(%s) under(%s) does not match pattern(%s)", file, config.MountDir, config.NamePattern)
+			volume.Reason = "name pattern mismatch"
+			continue
+		}
+		if !filter.included(file) {
+			klog.V(5).Infof("file(%s) under(%s) does not match include regex(%v)", file, config.MountDir, config.IncludeRegex)
+			volume.Reason = "include regex mismatch"
+			continue
+		}
+		if pattern := filter.excluded(file); pattern != "" {
+			klog.V(5).Infof("file(%s) under(%s) is excluded by pattern(%s)", file, config.MountDir, pattern)
+			volume.Reason = fmt.Sprintf("excluded by pattern %q", pattern)
+			continue
+		}
+		if config.LVM != nil && !strings.HasPrefix(file, common.NamePrefix) {
+			klog.V(5).Infof("file(%s) under(%s) is not a logical volume created by the provisioner", file, config.MountDir)
+			volume.Reason = "not a logical volume created by the provisioner"
 			continue
 		}
+		if config.DirectoryPool != nil && !strings.HasPrefix(file, common.NamePrefix) {
+			klog.V(5).Infof("file(%s) under(%s) is not a directory created by the provisioner", file, config.MountDir)
+			volume.Reason = "not a directory created by the provisioner"
+			continue
+		}
+		metadata := &volumeMetadata{}
+		if config.VolumeMetadata {
+			if metadata, err = d.readVolumeMetadata(config, file); err != nil {
+				discoErrors = append(discoErrors, err)
+				volume.Reason = err.Error()
+				continue
+			}
+			if metadata.Exclude {
+				klog.V(5).Infof("file(%s) under(%s) is excluded by its metadata file", file, config.MountDir)
+				volume.Reason = "excluded by metadata file"
+				continue
+			}
+		}
 
 		startTime := time.Now()
 		filePath := filepath.Join(config.MountDir, file)
+		// The device selector of a class with a partitioning policy applies
+		// to the disks, only the partitions created on them are discovered.
+		if config.Partitioning != nil {
+			matched, err := d.matchPartition(filePath)
+			if err != nil {
+				discoErrors = append(discoErrors, err)
+				volume.Reason = err.Error()
+				continue
+			}
+			if !matched {
+				klog.V(5).Infof("file(%s) under(%s) is not a partition created by the provisioner", file, config.MountDir)
+				volume.Reason = "not a partition created by the provisioner"
+				continue
+			}
+		} else if config.DeviceSelector != nil {
+			matched, err := d.matchDevice(config.DeviceSelector, filePath)
+			if err != nil {
+				discoErrors = append(discoErrors, err)
+				volume.Reason = err.Error()
+				continue
+			}
+			if !matched {
+				klog.V(5).Infof("file(%s) under(%s) does not match device selector", file, config.MountDir)
+				volume.Reason = "device selector mismatch"
+				continue
+			}
+		}
+		// Raw block devices of a class with a format and mount policy are
+		// only discovered once mounted in the format and mount directory.
+		if config.FormatAndMount != nil {
+			isBlock, err := d.VolUtil.IsBlock(filePath)
+			if err != nil {
+				err = fmt.Errorf("Block device check for %q failed: %s", filePath, err)
+				discoErrors = append(discoErrors, err)
+				volume.Reason = err.Error()
+				continue
+			}
+			if isBlock {
+				volume.VolumeMode = string(v1.PersistentVolumeBlock)
+				mounted, err := d.formatAndMount(config, file, mountPointMap)
+				if err != nil {
+					discoErrors = append(discoErrors, err)
+					volume.Reason = err.Error()
+				} else if mounted {
+					mountedFiles = append(mountedFiles, file)
+					volume.Reason = fmt.Sprintf("formatted and mounted under %q", config.FormatAndMount.HostDir)
+				} else {
+					volume.Reason = "not formatted, the device is in use or has foreign signatures"
+				}
+				continue
+			}
+		}
 		volMode, err := common.GetVolumeMode(d.VolUtil, filePath)
 		if err != nil {
 			discoErrors = append(discoErrors, err)
+			volume.Reason = err.Error()
 			continue
 		}
+		volume.VolumeMode = string(volMode)
 		// Check if PV already exists for it
 		pvName := generatePVName(file, d.Node.Name, class)
+		var fingerprint string
+		if config.IdentityMode == common.IdentityModeDevice {
+			fingerprint, err = common.GetVolumeFingerprint(d.VolUtil, filePath, volMode)
+			if err != nil {
+				err = fmt.Errorf("path %q device identity error: %v", filePath, err)
+				discoErrors = append(discoErrors, err)
+				volume.Reason = err.Error()
+				continue
+			}
+			pvName = generatePVName(fingerprint, d.Node.Name, class)
+			volume.DeviceIdentity = fingerprint
+		}
 		pv, exists := d.Cache.GetPV(pvName)
 		if exists {
+			volume.PersistentVolume = pvName
 			if pv.Spec.VolumeMode != nil && *pv.Spec.VolumeMode == v1.PersistentVolumeBlock &&
 				volMode == v1.PersistentVolumeFilesystem {
 				err := fmt.Errorf("incorrect Volume Mode: PV %q requires block mode but path %q was in fs mode", pvName, filePath)
 				discoErrors = append(discoErrors, err)
 				d.Recorder.Eventf(pv, v1.EventTypeWarning, common.EventVolumeFailedDelete, err.Error())
+				volume.Reason = err.Error()
+			} else if capacityByte, err := d.volumeCapacity(config, metadata, volMode, filePath, outsidePath, mountPointMap); err != nil {
+				klog.Warningf("Failed to measure capacity of PV %q: %v", pvName, err)
+				volume.Reason = err.Error()
+			} else {
+				volume.Capacity = resource.NewQuantity(capacityByte, resource.BinarySI)
+				d.reconcileCapacity(pv, config, volMode, capacityByte)
+			}
+			if claim := d.reservedClaim(class, outsidePath); claim != nil {
+				d.enforceReservation(pv, claim)
+			}
+			if fingerprint != "" && pv.Spec.Local != nil && pv.Spec.Local.Path != outsidePath {
+				klog.Warningf("Device %q of PV %q at %q was found at %q", fingerprint, pvName, pv.Spec.Local.Path, outsidePath)
+				d.Recorder.Eventf(pv, v1.EventTypeWarning, common.EventVolumeDeviceMoved, "Device %q of volume at %q was found at %q", fingerprint, pv.Spec.Local.Path, outsidePath)
 			}
 			continue
 		}
 
 		// Check that the local filePath is not already in use in any other local volume
 		// note: this check relies on the cache only containing PVs from this node and no others
-		outsidePath := filepath.Join(config.HostDir, file)
 		existingPVNames := d.Cache.LookupPVsByPath(outsidePath)
 		if len(existingPVNames) > 0 {
 			klog.Errorf("Volume path already in use: PV %q wants path %q which was already found in %q.", pvName, outsidePath, strings.Join(existingPVNames, ","))
+			if fingerprint != "" {
+				d.reportDeviceMismatch(existingPVNames, outsidePath, fingerprint)
+			}
+			volume.Reason = fmt.Sprintf("path already in use by PV %s", strings.Join(existingPVNames, ","))
 			continue
 		}
 
@@ -287,6 +506,7 @@ func (d *Discoverer) discoverVolumesAtPath(class string, config common.MountConf
 		}
 		if d.CleanupTracker.InProgress(pvName, usejob) {
 			klog.Infof("PV %s is still being cleaned, not going to recreate it", pvName)
+			volume.Reason = "cleanup in progress"
 			continue
 		}
 
@@ -294,12 +514,15 @@ func (d *Discoverer) discoverVolumesAtPath(class string, config common.MountConf
 		_, _, err = d.CleanupTracker.RemoveStatus(pvName, usejob)
 		if err != nil {
 			klog.Errorf("expected status exists and fail to remove cleanup status for pv %s", pvName)
+			volume.Reason = "failed to remove cleanup status"
 			continue
 		}
 
 		mountOptions, err := d.getMountOptionsFromStorageClass(class)
 		if err != nil {
-			discoErrors = append(discoErrors, fmt.Errorf("failed to get mount options from storage class %s: %v", class, err))
+			err = fmt.Errorf("failed to get mount options from storage class %s: %v", class, err)
+			discoErrors = append(discoErrors, err)
+			volume.Reason = err.Error()
 			continue
 		}
 
@@ -311,9 +534,10 @@ func (d *Discoverer) discoverVolumesAtPath(class string, config common.MountConf
 		desireVolumeMode := v1.PersistentVolumeMode(config.VolumeMode)
 		switch volMode {
 		case v1.PersistentVolumeBlock:
-			capacityByte, err = d.VolUtil.GetBlockCapacityByte(filePath)
+			capacityByte, err = d.volumeCapacity(config, metadata, volMode, filePath, outsidePath, mountPointMap)
 			if err != nil {
-				discoErrors = append(discoErrors, fmt.Errorf("path %q block stats error: %v", filePath, err))
+				discoErrors = append(discoErrors, err)
+				volume.Reason = err.Error()
 				continue
 			}
 			totalCapacityBlockBytes += capacityByte
@@ -323,42 +547,162 @@ func (d *Discoverer) discoverVolumesAtPath(class string, config common.MountConf
 			}
 		case v1.PersistentVolumeFilesystem:
 			if desireVolumeMode == v1.PersistentVolumeBlock {
-				discoErrors = append(discoErrors, fmt.Errorf("path %q of filesystem mode cannot be used to create block volume", filePath))
-				continue
-			}
-
-			// check if the file in the discovery directory is a mount point:
-			// - Windows: it should be a symlink pointing to a path that exists
-			// - Linux: it should exist in the /proc/mounts file
-			isLikelyMountPoint, err := d.VolUtil.IsLikelyMountPoint(outsidePath, filePath, mountPointMap)
-			if !isLikelyMountPoint || err != nil {
-				discoErrors = append(discoErrors, fmt.Errorf("path %q is not a valid mount point: %v", filePath, err))
+				err = fmt.Errorf("path %q of filesystem mode cannot be used to create block volume", filePath)
+				discoErrors = append(discoErrors, err)
+				volume.Reason = err.Error()
 				continue
 			}
 
-			capacityByte, err = d.VolUtil.GetFsCapacityByte(outsidePath, filePath)
+			capacityByte, err = d.volumeCapacity(config, metadata, volMode, filePath, outsidePath, mountPointMap)
 			if err != nil {
-				discoErrors = append(discoErrors, fmt.Errorf("path %q fs stats error: %v", filePath, err))
+				discoErrors = append(discoErrors, err)
+				volume.Reason = err.Error()
 				continue
 			}
 
 			totalCapacityFSBytes += capacityByte
 		default:
-			discoErrors = append(discoErrors, fmt.Errorf("path %q has unexpected volume type %q", filePath, volMode))
+			err = fmt.Errorf("path %q has unexpected volume type %q", filePath, volMode)
+			discoErrors = append(discoErrors, err)
+			volume.Reason = err.Error()
 			continue
 		}
 
-		err = d.createPV(file, class, reclaimPolicy, mountOptions, config, capacityByte, desireVolumeMode, desiredAccessMode, startTime)
+		volume.Capacity = resource.NewQuantity(capacityByte, resource.BinarySI)
+		err = d.createPV(file, pvName, fingerprint, class, reclaimPolicy, mountOptions, config, metadata, capacityByte, desireVolumeMode, desiredAccessMode, startTime)
+		if err != nil {
+			discoErrors = append(discoErrors, err)
+			volume.Reason = err.Error()
+		} else {
+			volume.PersistentVolume = pvName
+		}
+	}
+	if len(mountedFiles) > 0 {
+		_, capacityFSBytes, err := d.discoverVolumesAtFiles(class, formattedConfig(config), mountedFiles)
+		totalCapacityFSBytes += capacityFSBytes
 		if err != nil {
 			discoErrors = append(discoErrors, err)
 		}
 	}
-	metrics.PersistentVolumeCapacityBytes.WithLabelValues(string(v1.PersistentVolumeBlock)).Set(float64(totalCapacityBlockBytes))
-	metrics.PersistentVolumeCapacityBytes.WithLabelValues(string(v1.PersistentVolumeFilesystem)).Set(float64(totalCapacityFSBytes))
 	if discoErrors == nil {
-		return nil
+		return totalCapacityBlockBytes, totalCapacityFSBytes, nil
+	}
+	return totalCapacityBlockBytes, totalCapacityFSBytes, fmt.Errorf("%d error(s) while discovering volumes: %v", len(discoErrors), discoErrors)
+}
+
+// volumeCapacity returns the capacity in bytes of the PV of the volume at
+// filePath before rounding, which is its measured capacity minus the reserve
+// of the capacity policy unless overridden by its metadata.
+func (d *Discoverer) volumeCapacity(config common.MountConfig, metadata *volumeMetadata, volMode v1.PersistentVolumeMode, filePath, outsidePath string, mountPointMap map[string]interface{}) (int64, error) {
+	capacityByte, err := d.measureCapacity(config, volMode, filePath, outsidePath, mountPointMap)
+	if err != nil {
+		return 0, err
+	}
+	capacityByte, err = reserveCapacity(config.CapacityPolicy, capacityByte)
+	if err != nil {
+		return 0, fmt.Errorf("path %q %v", filePath, err)
+	}
+	capacityByte, err = metadata.capacityByte(capacityByte)
+	if err != nil {
+		return 0, fmt.Errorf("path %q %v", filePath, err)
+	}
+	return capacityByte, nil
+}
+
+// measureCapacity measures the capacity in bytes of the volume at filePath.
+func (d *Discoverer) measureCapacity(config common.MountConfig, volMode v1.PersistentVolumeMode, filePath, outsidePath string, mountPointMap map[string]interface{}) (int64, error) {
+	if volMode == v1.PersistentVolumeBlock {
+		capacityByte, err := d.VolUtil.GetBlockCapacityByte(filePath)
+		if err != nil {
+			return 0, fmt.Errorf("path %q block stats error: %v", filePath, err)
+		}
+		return capacityByte, nil
+	}
+
+	if config.DirectoryPool != nil {
+		// The directories of a pool share the filesystem, each one is
+		// limited to the size of the pool by its project quota.
+		return config.DirectoryPool.Size.Value(), nil
+	}
+
+	// check if the file in the discovery directory is a mount point:
+	// - Windows: it should be a symlink pointing to a path that exists
+	// - Linux: it should exist in the /proc/mounts file
+	isLikelyMountPoint, err := d.VolUtil.IsLikelyMountPoint(outsidePath, filePath, mountPointMap)
+	if !isLikelyMountPoint || err != nil {
+		return 0, fmt.Errorf("path %q is not a valid mount point: %v", filePath, err)
+	}
+
+	getCapacityByte := d.VolUtil.GetFsCapacityByte
+	if config.CapacityPolicy != nil && config.CapacityPolicy.FsCapacity == common.FsCapacityAvailable {
+		getCapacityByte = d.VolUtil.GetFsAvailableByte
+	}
+	capacityByte, err := getCapacityByte(outsidePath, filePath)
+	if err != nil {
+		return 0, fmt.Errorf("path %q fs stats error: %v", filePath, err)
+	}
+	return capacityByte, nil
+}
+
+// matchNamePattern returns true if file matches one of the comma separated
+// patterns of namePattern, or namePattern is empty.
+func matchNamePattern(namePattern, file string) (bool, error) {
+	if namePattern == "" {
+		return true, nil
+	}
+	for _, pattern := range strings.Split(namePattern, ",") {
+		if pattern == "" {
+			continue
+		}
+		matched, err := filepath.Match(pattern, file)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
+
+// matchDevice returns true if filePath is a block device matching selector.
+func (d *Discoverer) matchDevice(selector *common.DeviceSelector, filePath string) (bool, error) {
+	attrs, err := d.deviceAttributes(filePath)
+	if err != nil || attrs == nil {
+		return false, err
+	}
+	return matchDeviceSelector(selector, attrs), nil
+}
+
+// deviceAttributes returns the attributes of the block device at filePath,
+// or nil if it is not a block device.
+func (d *Discoverer) deviceAttributes(filePath string) (*util.DeviceAttributes, error) {
+	isBlock, err := d.VolUtil.IsBlock(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Block device check for %q failed: %s", filePath, err)
+	}
+	if !isBlock {
+		return nil, nil
+	}
+	attrs, err := d.VolUtil.GetDeviceAttributes(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("path %q device attributes error: %v", filePath, err)
+	}
+	return attrs, nil
+}
+
+// reportDeviceMismatch records a warning event on the PVs at outsidePath which
+// were created for a different device than the one now found there.
+func (d *Discoverer) reportDeviceMismatch(pvNames []string, outsidePath, fingerprint string) {
+	for _, name := range pvNames {
+		pv, exists := d.Cache.GetPV(name)
+		if !exists {
+			continue
+		}
+		expected, ok := pv.Annotations[common.AnnDeviceFingerprint]
+		if !ok || expected == fingerprint {
+			continue
+		}
+		klog.Warningf("PV %q expects device %q at %q, found %q", name, expected, outsidePath, fingerprint)
+		d.Recorder.Eventf(pv, v1.EventTypeWarning, common.EventVolumeDeviceMismatch, "Expected device %q at %q, found %q", expected, outsidePath, fingerprint)
 	}
-	return fmt.Errorf("%d error(s) while discovering volumes: %v", len(discoErrors), discoErrors)
 }
 
 func generatePVName(file, node, class string) string {
@@ -370,8 +714,7 @@ func generatePVName(file, node, class string) string {
 	return fmt.Sprintf("local-pv-%x", h.Sum32())
 }
 
-func (d *Discoverer) createPV(file, class string, reclaimPolicy v1.PersistentVolumeReclaimPolicy, mountOptions []string, config common.MountConfig, capacityByte int64, volMode v1.PersistentVolumeMode, accessMode v1.PersistentVolumeAccessMode, startTime time.Time) error {
-	pvName := generatePVName(file, d.Node.Name, class)
+func (d *Discoverer) createPV(file, pvName, fingerprint, class string, reclaimPolicy v1.PersistentVolumeReclaimPolicy, mountOptions []string, config common.MountConfig, metadata *volumeMetadata, capacityByte int64, volMode v1.PersistentVolumeMode, accessMode v1.PersistentVolumeAccessMode, startTime time.Time) error {
 	outsidePath := filepath.Join(config.HostDir, file)
 
 	klog.Infof("Found new volume at host path %q with capacity %d, creating Local PV %q, required volumeMode %q",
@@ -380,21 +723,33 @@ func (d *Discoverer) createPV(file, class string, reclaimPolicy v1.PersistentVol
 	localPVConfig := &common.LocalPVConfig{
 		Name:            pvName,
 		HostPath:        outsidePath,
-		Capacity:        roundDownCapacityPretty(capacityByte),
+		Capacity:        roundCapacity(config.CapacityPolicy, capacityByte),
 		StorageClass:    class,
 		ReclaimPolicy:   reclaimPolicy,
 		ProvisionerName: d.Name,
 		VolumeMode:      volMode,
 		AccessMode:      accessMode,
 		Labels:          d.Labels,
-		MountOptions:    mountOptions,
+		MountOptions:    slices.Concat(mountOptions, metadata.MountOptions),
 		SetPVOwnerRef:   d.SetPVOwnerRef,
 		OwnerReference:  d.ownerReference,
+		Annotations:     maps.Clone(metadata.Annotations),
 	}
-
-	volumeNodeSelector := &v1.NodeSelector{
-		NodeSelectorTerms: slices.Concat(d.nodeSelector.NodeSelectorTerms, config.Selector),
+	if claim := d.reservedClaim(class, outsidePath); claim != nil {
+		if metadata.ClaimRef != nil && *metadata.ClaimRef != *claim {
+			klog.Warningf("Volume at %q is reserved for claim %s/%s, ignoring claim %s/%s of its metadata file", outsidePath, claim.Namespace, claim.Name, metadata.ClaimRef.Namespace, metadata.ClaimRef.Name)
+		}
+		localPVConfig.ClaimRef = claim.ObjectReference()
+	} else if metadata.ClaimRef != nil {
+		localPVConfig.ClaimRef = metadata.ClaimRef.ObjectReference()
 	}
+	if len(metadata.Labels) > 0 {
+		localPVConfig.Labels = map[string]string{}
+		maps.Copy(localPVConfig.Labels, d.Labels)
+		maps.Copy(localPVConfig.Labels, metadata.Labels)
+	}
+
+	volumeNodeSelector := common.VolumeNodeSelector(d.nodeSelector, config)
 
 	if d.UseAlphaAPI {
 		nodeAffinity := &v1.NodeAffinity{
@@ -417,6 +772,13 @@ func (d *Discoverer) createPV(file, class string, reclaimPolicy v1.PersistentVol
 		localPVConfig.FsType = &config.FsType
 	}
 
+	if fingerprint != "" {
+		if localPVConfig.Annotations == nil {
+			localPVConfig.Annotations = map[string]string{}
+		}
+		localPVConfig.Annotations[common.AnnDeviceFingerprint] = fingerprint
+	}
+
 	pvSpec := common.CreateLocalPVSpec(localPVConfig)
 
 	_, err := d.APIUtil.CreatePV(pvSpec)
@@ -17,27 +17,37 @@ limitations under the License.
 package discovery
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"k8s.io/klog/v2"
 	esUtil "sigs.k8s.io/sig-storage-lib-external-provisioner/v6/util"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/cache"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/deleter"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/dryrun"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/inventory"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/metrics"
 	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
 
 	v1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/mount"
 )
 
@@ -177,6 +187,1089 @@ func TestDiscoverVolumes_BasicTwice(t *testing.T) {
 	verifyCreatedPVs(t, test)
 }
 
+func TestDiscoverVolumes_AtPaths(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir1": {
+			{Name: "mount1", Hash: 0xaaaafef5, VolumeType: util.FakeEntryFile},
+			{Name: "mount2", Hash: 0x79412c38, VolumeType: util.FakeEntryFile},
+		},
+		"dir2": {
+			{Name: "symlink1", Hash: 0x55d5adba, VolumeType: util.FakeEntryBlock},
+			{Name: "symlink2", Hash: 0x226458a3, VolumeType: util.FakeEntryBlock},
+		},
+	}
+	test := &testConfig{
+		dirLayout: vols,
+		// Only the paths reported by the watcher are discovered
+		expectedVolumes: map[string][]*util.FakeDirEntry{
+			"dir1": {vols["dir1"][1]},
+			"dir2": {vols["dir2"][0]},
+		},
+	}
+	d := testSetup(t, test, false, false)
+
+	d.DiscoverLocalVolumesAtPaths([]PathEvent{
+		{Class: "sc1", File: "mount2"},
+		{Class: "sc2", File: "symlink1"},
+		{Class: "sc2", File: "symlink1"},
+		{Class: "unknown-class", File: "mount1"},
+	})
+	verifyCreatedPVs(t, test)
+
+	// The periodic resync still picks up the remaining volumes
+	test.expectedVolumes = map[string][]*util.FakeDirEntry{
+		"dir1": {vols["dir1"][0]},
+		"dir2": {vols["dir2"][1]},
+	}
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+}
+
+func TestDiscoverVolumes_RemovedDevice(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir2": {
+			{Name: "symlink1", Hash: 0x55d5adba, VolumeType: util.FakeEntryBlock},
+		},
+	}
+	test := &testConfig{
+		dirLayout:       vols,
+		expectedVolumes: vols,
+	}
+	d := testSetup(t, test, false, false)
+	recorder := record.NewFakeRecorder(10)
+	d.Recorder = recorder
+
+	d.DiscoverLocalVolumesAtPaths([]PathEvent{{Class: "sc2", File: "symlink1", Device: "nvme0n1"}})
+	verifyCreatedPVs(t, test)
+
+	// Removal of a device without a PV is ignored
+	d.DiscoverLocalVolumesAtPaths([]PathEvent{
+		{Class: "sc2", File: "symlink2", Device: "nvme1n1", Removed: true},
+		{Class: "sc2", File: "symlink1", Device: "nvme0n1", Removed: true},
+	})
+	select {
+	case event := <-recorder.Events:
+		expected := fmt.Sprintf("%s %s Block device %q backing volume at %q was removed from node %q",
+			v1.EventTypeWarning, common.EventVolumeDeviceRemoved, "nvme0n1", filepath.Join(testHostDir, "dir2", "symlink1"), testNodeName)
+		if event != expected {
+			t.Errorf("Expected event %q, got %q", expected, event)
+		}
+	default:
+		t.Fatalf("Expected a %s event", common.EventVolumeDeviceRemoved)
+	}
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("Unexpected event %q", event)
+	default:
+	}
+	// The PV is kept
+	if _, exists := test.cache.GetPV(generatePVName("symlink1", testNodeName, "sc2")); !exists {
+		t.Errorf("Expected PV to be kept after device removal")
+	}
+}
+
+func TestDiscoverVolumes_CapacityChanged(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir1": {
+			{Name: "mount1", Hash: 0xaaaafef5, VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB},
+		},
+		"dir2": {
+			{Name: "symlink1", Hash: 0x55d5adba, VolumeType: util.FakeEntryBlock, Capacity: 100 * esUtil.GiB},
+		},
+	}
+	test := &testConfig{
+		dirLayout:       vols,
+		expectedVolumes: vols,
+	}
+	d := testSetup(t, test, false, false)
+	recorder := record.NewFakeRecorder(10)
+	d.Recorder = recorder
+
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	availablePVName := getPVName(vols["dir1"][0])
+	boundPVName := getPVName(vols["dir2"][0])
+	for name, phase := range map[string]v1.PersistentVolumePhase{availablePVName: v1.VolumeAvailable, boundPVName: v1.VolumeBound} {
+		pv, _ := test.cache.GetPV(name)
+		pv.Status.Phase = phase
+	}
+
+	// Both volumes were resized
+	vols["dir1"][0].Capacity = 200 * esUtil.GiB
+	vols["dir2"][0].Capacity = 200 * esUtil.GiB
+	test.expectedVolumes = map[string][]*util.FakeDirEntry{}
+	d.DiscoverLocalVolumesAtPaths([]PathEvent{{Class: "sc1", File: "mount1"}})
+	d.DiscoverLocalVolumesAtPaths([]PathEvent{{Class: "sc2", File: "symlink1"}})
+	verifyCreatedPVs(t, test)
+
+	// The available PV is updated
+	verifyEvent(t, recorder, fmt.Sprintf("%s %s Capacity of volume at %q changed from 100Gi to 200Gi",
+		v1.EventTypeNormal, common.EventVolumeCapacityUpdated, filepath.Join(testHostDir, "dir1", "mount1")))
+	pv, _ := test.cache.GetPV(availablePVName)
+	if capacity := pv.Spec.Capacity[v1.ResourceStorage]; capacity.Value() != 200*esUtil.GiB {
+		t.Errorf("Expected capacity %d of PV %q, got %d", 200*esUtil.GiB, availablePVName, capacity.Value())
+	}
+	// The drift of the bound PV is only reported
+	verifyEvent(t, recorder, fmt.Sprintf("%s %s Capacity of volume at %q is 200Gi, but 100Gi is advertised",
+		v1.EventTypeWarning, common.EventVolumeCapacityDrift, filepath.Join(testHostDir, "dir2", "symlink1")))
+	pv, _ = test.cache.GetPV(boundPVName)
+	if capacity := pv.Spec.Capacity[v1.ResourceStorage]; capacity.Value() != 100*esUtil.GiB {
+		t.Errorf("Expected capacity %d of PV %q, got %d", 100*esUtil.GiB, boundPVName, capacity.Value())
+	}
+
+	// The drift is reported once
+	d.DiscoverLocalVolumes()
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("Unexpected event %q", event)
+	default:
+	}
+
+	// The drift of a deleted PV is forgotten
+	test.cache.DeletePV(boundPVName)
+	d.DiscoveryMap = map[string]common.MountConfig{"sc1": scMapping["sc1"]}
+	d.DiscoverLocalVolumes()
+	if _, ok := d.capacityDrift[boundPVName]; ok {
+		t.Errorf("Expected capacity drift of deleted PV %q to be forgotten", boundPVName)
+	}
+	if count := testutil.CollectAndCount(metrics.PersistentVolumeCapacityDriftBytes); count != 0 {
+		t.Errorf("Expected no capacity drift series, got %d", count)
+	}
+}
+
+func TestDiscoverVolumes_AvailableCapacityChanged(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir1": {
+			{Name: "mount1", Hash: 0xaaaafef5, VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB, Available: 90 * esUtil.GiB},
+		},
+	}
+	test := &testConfig{
+		dirLayout: vols,
+	}
+	d := testSetup(t, test, false, false)
+	recorder := record.NewFakeRecorder(10)
+	d.Recorder = recorder
+	config := scMapping["sc1"]
+	config.CapacityPolicy = &common.CapacityPolicy{Rounding: common.CapacityRoundingExact, FsCapacity: common.FsCapacityAvailable}
+	d.DiscoveryMap = map[string]common.MountConfig{"sc1": config}
+
+	d.DiscoverLocalVolumes()
+	pvName := getPVName(vols["dir1"][0])
+	pv, ok := getAndResetCreatedPVs(test.client, test.cache)[pvName]
+	if !ok {
+		t.Fatalf("Expected PV %q to be created", pvName)
+	}
+	pv.Status.Phase = v1.VolumeAvailable
+	test.cache.UpdatePV(pv)
+
+	// Small changes of the available bytes are ignored
+	vols["dir1"][0].Available = 90*esUtil.GiB - 100*esUtil.MiB
+	d.DiscoverLocalVolumes()
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("Unexpected event %q", event)
+	default:
+	}
+
+	vols["dir1"][0].Available = 80 * esUtil.GiB
+	d.DiscoverLocalVolumes()
+	verifyEvent(t, recorder, fmt.Sprintf("%s %s Capacity of volume at %q changed from 90Gi to 80Gi",
+		v1.EventTypeNormal, common.EventVolumeCapacityUpdated, filepath.Join(testHostDir, "dir1", "mount1")))
+}
+
+func TestDiscoverVolumes_MissingVolumes(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir1": {
+			{Name: "mount1", Hash: 0xaaaafef5, VolumeType: util.FakeEntryFile},
+			{Name: "mount2", Hash: 0x79412c38, VolumeType: util.FakeEntryFile},
+		},
+		"dir2": {
+			{Name: "symlink1", Hash: 0x55d5adba, VolumeType: util.FakeEntryBlock},
+		},
+	}
+	test := &testConfig{
+		dirLayout:       vols,
+		expectedVolumes: vols,
+	}
+	d := testSetup(t, test, false, false)
+	recorder := record.NewFakeRecorder(10)
+	d.Recorder = recorder
+
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	availablePVName := getPVName(vols["dir1"][0])
+	presentPVName := getPVName(vols["dir1"][1])
+	boundPVName := getPVName(vols["dir2"][0])
+	for name, phase := range map[string]v1.PersistentVolumePhase{availablePVName: v1.VolumeAvailable, presentPVName: v1.VolumeAvailable, boundPVName: v1.VolumeBound} {
+		pv, _ := test.cache.GetPV(name)
+		pv.Status.Phase = phase
+	}
+
+	// The volumes of the available and the bound PVs vanish
+	for _, path := range []string{"dir1/mount1", "dir2/symlink1"} {
+		if err := test.volUtil.RemoveDir(filepath.Join(testMountDir, path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	test.expectedVolumes = map[string][]*util.FakeDirEntry{}
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	expectedAnnotations := map[string]string{
+		availablePVName: fmt.Sprintf("path %q does not exist", filepath.Join(testHostDir, "dir1", "mount1")),
+		presentPVName:   "",
+		boundPVName:     fmt.Sprintf("path %q does not exist", filepath.Join(testHostDir, "dir2", "symlink1")),
+	}
+	var expectedEvents []string
+	for name, reason := range expectedAnnotations {
+		pv, _ := test.cache.GetPV(name)
+		if pv.Annotations[common.AnnVolumeMissing] != reason {
+			t.Errorf("Expected annotation %q of PV %q, got %q", reason, name, pv.Annotations[common.AnnVolumeMissing])
+		}
+		if reason != "" {
+			expectedEvents = append(expectedEvents, fmt.Sprintf("%s %s Volume is missing: %s", v1.EventTypeWarning, common.EventVolumeMissing, reason))
+		}
+	}
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	slices.Sort(expectedEvents)
+	slices.Sort(events)
+	if !reflect.DeepEqual(events, expectedEvents) {
+		t.Errorf("Expected events %q, got %q", expectedEvents, events)
+	}
+
+	// The available PV is deleted with the delete policy, the bound PV is
+	// reported once
+	d.MissingVolumePolicy = common.MissingVolumePolicyDelete
+	d.DiscoverLocalVolumes()
+	if _, exists := test.cache.GetPV(availablePVName); exists {
+		t.Errorf("Expected PV %q to be deleted", availablePVName)
+	}
+	if _, exists := test.cache.GetPV(presentPVName); !exists {
+		t.Errorf("Expected PV %q to be kept", presentPVName)
+	}
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("Unexpected event %q", event)
+	default:
+	}
+
+	// The annotation is removed once the volume is back
+	test.volUtil.AddNewDirEntries(testMountDir, map[string][]*util.FakeDirEntry{"dir2": vols["dir2"]})
+	d.DiscoverLocalVolumes()
+	pv, _ := test.cache.GetPV(boundPVName)
+	if reason, ok := pv.Annotations[common.AnnVolumeMissing]; ok {
+		t.Errorf("Expected annotation of PV %q to be removed, got %q", boundPVName, reason)
+	}
+}
+
+func TestDiscoverVolumes_DeviceSelector(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir2": {
+			{Name: "symlink1", Hash: 0x55d5adba, VolumeType: util.FakeEntryBlock, Capacity: 2 * 1024 * esUtil.GiB,
+				DeviceAttributes: &util.DeviceAttributes{Name: "nvme0n1", Transport: util.TransportNVMe, SizeBytes: 2 * 1024 * esUtil.GiB}},
+			{Name: "symlink2", VolumeType: util.FakeEntryBlock, Capacity: 2 * 1024 * esUtil.GiB,
+				DeviceAttributes: &util.DeviceAttributes{Name: "sda", Transport: util.TransportSATA, Rotational: true, SizeBytes: 2 * 1024 * esUtil.GiB}},
+			{Name: "symlink3", VolumeType: util.FakeEntryBlock, Capacity: 512 * esUtil.GiB,
+				DeviceAttributes: &util.DeviceAttributes{Name: "nvme1n1", Transport: util.TransportNVMe, SizeBytes: 512 * esUtil.GiB}},
+			// Entries which are not block devices are skipped without error
+			{Name: "mount1", VolumeType: util.FakeEntryFile},
+		},
+	}
+	test := &testConfig{
+		dirLayout: vols,
+		expectedVolumes: map[string][]*util.FakeDirEntry{
+			"dir2": {vols["dir2"][0]},
+		},
+	}
+	d := testSetup(t, test, false, false)
+	rotational := false
+	minSize := resource.MustParse("1Ti")
+	config := scMapping["sc2"]
+	config.DeviceSelector = &common.DeviceSelector{
+		Transport:  util.TransportNVMe,
+		Rotational: &rotational,
+		MinSize:    &minSize,
+	}
+	d.DiscoveryMap = map[string]common.MountConfig{"sc2": config}
+
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	if err := d.Readyz.Check(nil); err != nil {
+		t.Errorf("Expected discoverer to be ready, got %v", err)
+	}
+}
+
+func TestDiscoverVolumes_SelectorAndAffinityLabels(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir2": {
+			{Name: "symlink1", Hash: 0x55d5adba, VolumeType: util.FakeEntryBlock, Capacity: 2 * esUtil.GiB},
+		},
+	}
+	test := &testConfig{dirLayout: vols, expectedVolumes: vols}
+	d := testSetup(t, test, false, false)
+	nodeSelector, err := common.GenerateNodeSelector(testNode, []string{"failure-domain.beta.kubernetes.io/zone"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	d.nodeSelector = nodeSelector
+	config := scMapping["sc2"]
+	config.Selector = []v1.NodeSelectorTerm{
+		{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "rack", Operator: v1.NodeSelectorOpIn, Values: []string{"r1"}}}},
+		{MatchFields: []v1.NodeSelectorRequirement{{Key: "metadata.name", Operator: v1.NodeSelectorOpIn, Values: []string{"other-node"}}}},
+	}
+	d.DiscoveryMap = map[string]common.MountConfig{"sc2": config}
+
+	d.DiscoverLocalVolumes()
+	pvs := test.cache.ListPVs()
+	if len(pvs) != 1 {
+		t.Fatalf("Expected 1 PV, got %d", len(pvs))
+	}
+	// The selector terms are alternatives to the node and its zone.
+	nodeRequirements := []v1.NodeSelectorRequirement{
+		{Key: common.NodeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{testNodeName}},
+		{Key: "failure-domain.beta.kubernetes.io/zone", Operator: v1.NodeSelectorOpIn, Values: []string{"west-1"}},
+	}
+	expectedTerms := []v1.NodeSelectorTerm{
+		{MatchExpressions: nodeRequirements},
+		config.Selector[0],
+		config.Selector[1],
+	}
+	if terms := pvs[0].Spec.NodeAffinity.Required.NodeSelectorTerms; !reflect.DeepEqual(terms, expectedTerms) {
+		t.Errorf("Expected node selector terms %+v, got %+v", expectedTerms, terms)
+	}
+
+	// With selectorRequiresNode, the node and its zone are required by every selector term.
+	config.SelectorRequiresNode = true
+	expectedTerms = []v1.NodeSelectorTerm{
+		{MatchExpressions: append(slices.Clone(nodeRequirements), config.Selector[0].MatchExpressions...)},
+		{MatchExpressions: nodeRequirements, MatchFields: config.Selector[1].MatchFields},
+	}
+	if terms := common.VolumeNodeSelector(d.nodeSelector, config).NodeSelectorTerms; !reflect.DeepEqual(terms, expectedTerms) {
+		t.Errorf("Expected node selector terms %+v, got %+v", expectedTerms, terms)
+	}
+}
+
+func TestDiscoverVolumes_Partitioning(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir2": {
+			{Name: "disk1", VolumeType: util.FakeEntryBlock,
+				DeviceAttributes: &util.DeviceAttributes{Name: "nvme0n1", SizeBytes: 2 * esUtil.GiB}},
+			// Disks with signatures are not partitioned
+			{Name: "disk2", VolumeType: util.FakeEntryBlock,
+				DeviceAttributes: &util.DeviceAttributes{Name: "nvme1n1", SizeBytes: 2 * esUtil.GiB}},
+		},
+	}
+	test := &testConfig{
+		dirLayout: vols,
+		expectedVolumes: map[string][]*util.FakeDirEntry{
+			"dir2": {
+				{Name: "disk1-part1", Hash: 0x1b14f6eb, VolumeType: util.FakeEntryBlock, Capacity: 1023 * esUtil.MiB},
+				{Name: "disk1-part2", Hash: 0xae7f2d82, VolumeType: util.FakeEntryBlock, Capacity: 1023 * esUtil.MiB},
+			},
+		},
+	}
+	d := testSetup(t, test, false, false)
+	test.volUtil.AddNewDirEntries("/", map[string][]*util.FakeDirEntry{
+		"dev": {
+			{Name: "nvme0n1p1", VolumeType: util.FakeEntryBlock, Capacity: 1023 * esUtil.MiB,
+				DeviceAttributes: &util.DeviceAttributes{Name: "nvme0n1p1", PartitionNumber: 1, PartitionName: common.NamePrefix + "1"}},
+			{Name: "nvme0n1p2", VolumeType: util.FakeEntryBlock, Capacity: 1023 * esUtil.MiB,
+				DeviceAttributes: &util.DeviceAttributes{Name: "nvme0n1p2", PartitionNumber: 2, PartitionName: common.NamePrefix + "2"}},
+		},
+	})
+	partitionUtil := util.NewFakePartitionUtil(map[string]string{
+		filepath.Join(testMountDir, "dir2", "disk1"): "/dev/nvme0n1",
+		filepath.Join(testMountDir, "dir2", "disk2"): "/dev/nvme1n1",
+	})
+	partitionUtil.Signatures[filepath.Join(testMountDir, "dir2", "disk2")] = []util.Signature{{Type: "ext4"}}
+	d.PartitionUtil = partitionUtil
+	config := scMapping["sc2"]
+	config.Partitioning = &common.Partitioning{Count: 2}
+	d.DiscoveryMap = map[string]common.MountConfig{"sc2": config}
+
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	if err := d.Readyz.Check(nil); err != nil {
+		t.Errorf("Expected discoverer to be ready, got %v", err)
+	}
+	if len(partitionUtil.Created) != 1 {
+		t.Errorf("Expected 1 partitioned disk, got %v", partitionUtil.Created)
+	}
+
+	// Partitioning is idempotent
+	test.expectedVolumes = map[string][]*util.FakeDirEntry{}
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	if len(partitionUtil.Created) != 1 {
+		t.Errorf("Expected 1 partitioned disk, got %v", partitionUtil.Created)
+	}
+}
+
+func TestDiscoverVolumes_FormatAndMount(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"raw1": {
+			{Name: "disk1", VolumeType: util.FakeEntryBlock},
+			// Devices with other signatures are not formatted
+			{Name: "disk2", VolumeType: util.FakeEntryBlock},
+			// Filesystems created by the provisioner are mounted again
+			{Name: "disk3", VolumeType: util.FakeEntryBlock},
+		},
+		"dir1": {
+			{Name: "disk1", Hash: 0x8cb690d7, VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB},
+			{Name: "disk2", Hash: 0xdbd40926, VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB},
+			{Name: "disk3", Hash: 0xec2fa19, VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB},
+		},
+	}
+	test := &testConfig{
+		dirLayout: vols,
+		expectedVolumes: map[string][]*util.FakeDirEntry{
+			"dir1": {vols["dir1"][0], vols["dir1"][2]},
+		},
+	}
+	d := testSetup(t, test, false, false)
+	rawDir := filepath.Join(testMountDir, "raw1")
+	partitionUtil := util.NewFakePartitionUtil(map[string]string{
+		filepath.Join(rawDir, "disk1"): "/dev/nvme0n1",
+		filepath.Join(rawDir, "disk2"): "/dev/nvme1n1",
+		filepath.Join(rawDir, "disk3"): "/dev/nvme2n1",
+	})
+	partitionUtil.Signatures[filepath.Join(rawDir, "disk2")] = []util.Signature{{Type: "xfs", Label: "data"}}
+	partitionUtil.Signatures[filepath.Join(rawDir, "disk3")] = []util.Signature{{Type: "ext4", Label: common.FilesystemLabel}}
+	formatUtil := util.NewFakeFormatUtil()
+	d.PartitionUtil = partitionUtil
+	d.FormatUtil = formatUtil
+	config := scMapping["sc1"]
+	config.HostDir = filepath.Join(testHostDir, "raw1")
+	config.MountDir = rawDir
+	config.FsType = "ext4"
+	config.FormatAndMount = &common.FormatAndMount{
+		HostDir:  scMapping["sc1"].HostDir,
+		MountDir: scMapping["sc1"].MountDir,
+	}
+	d.DiscoveryMap = map[string]common.MountConfig{"sc1": config}
+
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	if err := d.Readyz.Check(nil); err != nil {
+		t.Errorf("Expected discoverer to be ready, got %v", err)
+	}
+	expectedFormatted := []string{filepath.Join(rawDir, "disk1")}
+	if !reflect.DeepEqual(formatUtil.Formatted, expectedFormatted) {
+		t.Errorf("Expected formatted devices %v, got %v", expectedFormatted, formatUtil.Formatted)
+	}
+
+	// Mounted devices are not formatted again
+	test.expectedVolumes = map[string][]*util.FakeDirEntry{}
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	if !reflect.DeepEqual(formatUtil.Formatted, expectedFormatted) {
+		t.Errorf("Expected formatted devices %v, got %v", expectedFormatted, formatUtil.Formatted)
+	}
+
+	// Devices with other signatures are only formatted if allowed
+	config.FormatAndMount.AllowOverwrite = true
+	test.expectedVolumes = map[string][]*util.FakeDirEntry{
+		"dir1": {vols["dir1"][1]},
+	}
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	expectedForced := []string{filepath.Join(rawDir, "disk2")}
+	if !reflect.DeepEqual(formatUtil.Forced, expectedForced) {
+		t.Errorf("Expected forcibly formatted devices %v, got %v", expectedForced, formatUtil.Forced)
+	}
+}
+
+func TestDiscoverVolumes_LVM(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir2": {
+			{Name: "local-static-provisioner-1", Hash: 0x454f4447, VolumeType: util.FakeEntryBlock},
+			{Name: "local-static-provisioner-2", Hash: 0xc2b611ae, VolumeType: util.FakeEntryBlock},
+			// Logical volumes not created by the provisioner are not discovered
+			{Name: "root", VolumeType: util.FakeEntryBlock},
+		},
+	}
+	test := &testConfig{
+		dirLayout:       vols,
+		expectedVolumes: map[string][]*util.FakeDirEntry{"dir2": vols["dir2"][:2]},
+	}
+	d := testSetup(t, test, false, false)
+	lvmUtil := util.NewFakeLVMUtil(&util.VolumeGroup{
+		Name:            "vg0",
+		SizeBytes:       120 * esUtil.GiB,
+		FreeBytes:       100 * esUtil.GiB,
+		ExtentSizeBytes: 4 * esUtil.MiB,
+	})
+	lvmUtil.LogicalVolumes["vg0"] = []util.LogicalVolume{{Name: "root", SizeBytes: 20 * esUtil.GiB}}
+	d.LVMUtil = lvmUtil
+	config := scMapping["sc2"]
+	config.LVM = &common.LVMConfig{VolumeGroup: "vg0", Count: 2}
+	d.DiscoveryMap = map[string]common.MountConfig{"sc2": config}
+
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	expectedCreated := []string{"local-static-provisioner-1", "local-static-provisioner-2"}
+	if !reflect.DeepEqual(lvmUtil.Created, expectedCreated) {
+		t.Errorf("Expected created logical volumes %v, got %v", expectedCreated, lvmUtil.Created)
+	}
+	for _, lv := range lvmUtil.LogicalVolumes["vg0"][1:] {
+		if lv.SizeBytes != 50*esUtil.GiB {
+			t.Errorf("Expected logical volume %q of %d bytes, got %d", lv.Name, 50*esUtil.GiB, lv.SizeBytes)
+		}
+	}
+
+	// A removed logical volume is created again with the same size
+	if err := lvmUtil.RemoveLogicalVolume("vg0", "local-static-provisioner-2"); err != nil {
+		t.Fatal(err)
+	}
+	lvmUtil.VolumeGroups["vg0"].FreeBytes = 60 * esUtil.GiB
+	test.expectedVolumes = map[string][]*util.FakeDirEntry{}
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	expectedCreated = append(expectedCreated, "local-static-provisioner-2")
+	if !reflect.DeepEqual(lvmUtil.Created, expectedCreated) {
+		t.Errorf("Expected created logical volumes %v, got %v", expectedCreated, lvmUtil.Created)
+	}
+	if size := lvmUtil.LogicalVolumes["vg0"][2].SizeBytes; size != 50*esUtil.GiB {
+		t.Errorf("Expected recreated logical volume of %d bytes, got %d", 50*esUtil.GiB, size)
+	}
+}
+
+func TestDiscoverVolumes_DirectoryPool(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir1": {
+			{Name: "local-static-provisioner-1", Hash: 0x464f45da, Capacity: 10 * esUtil.GiB, VolumeType: util.FakeEntryFile},
+			// Directories not created by the provisioner are not discovered
+			{Name: "data", Capacity: 100 * esUtil.GiB, VolumeType: util.FakeEntryFile},
+		},
+	}
+	test := &testConfig{
+		dirLayout: vols,
+		expectedVolumes: map[string][]*util.FakeDirEntry{"dir1": {
+			vols["dir1"][0],
+			{Name: "local-static-provisioner-2", Hash: 0xc1b6101b, Capacity: 10 * esUtil.GiB},
+		}},
+	}
+	d := testSetup(t, test, false, false)
+	addPoolCapacity(test, 100*esUtil.GiB)
+	quotaUtil := util.NewFakeQuotaUtil()
+	// A project of another class on the same filesystem
+	quotaUtil.Limits[0xc1b6101b] = 10 * esUtil.GiB
+	pool1Path := filepath.Join(testMountDir, "dir1", "local-static-provisioner-1")
+	quotaUtil.ProjectIDs[pool1Path] = 7
+	quotaUtil.Limits[7] = 10 * esUtil.GiB
+	d.QuotaUtil = quotaUtil
+	size := resource.MustParse("10Gi")
+	config := scMapping["sc1"]
+	config.DirectoryPool = &common.DirectoryPool{Count: 2, Size: &size}
+	d.DiscoveryMap = map[string]common.MountConfig{"sc1": config}
+
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	pool2Path := filepath.Join(testMountDir, "dir1", "local-static-provisioner-2")
+	projectID := quotaUtil.ProjectIDs[pool2Path]
+	if projectID == 0 || projectID == 7 || projectID == 0xc1b6101b {
+		t.Errorf("Expected a new project for %q, got %d", pool2Path, projectID)
+	}
+	if quotaUtil.Limits[projectID] != 10*esUtil.GiB {
+		t.Errorf("Expected limit %d of project %d, got %d", 10*esUtil.GiB, projectID, quotaUtil.Limits[projectID])
+	}
+}
+
+func TestDiscoverVolumes_DirectoryPoolExceedsCapacity(t *testing.T) {
+	test := &testConfig{
+		dirLayout: map[string][]*util.FakeDirEntry{"dir1": {}},
+	}
+	d := testSetup(t, test, false, false)
+	addPoolCapacity(test, 15*esUtil.GiB)
+	quotaUtil := util.NewFakeQuotaUtil()
+	d.QuotaUtil = quotaUtil
+	size := resource.MustParse("10Gi")
+	config := scMapping["sc1"]
+	config.DirectoryPool = &common.DirectoryPool{Count: 2, Size: &size}
+	d.DiscoveryMap = map[string]common.MountConfig{"sc1": config}
+
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	if len(quotaUtil.ProjectIDs) != 0 {
+		t.Errorf("Expected no directory of a pool exceeding the filesystem, got %v", quotaUtil.ProjectIDs)
+	}
+}
+
+// addPoolCapacity sets the capacity of the filesystem of the directory "dir1"
+// of the directory pool tests
+func addPoolCapacity(test *testConfig, capacity int64) {
+	test.volUtil.AddNewDirEntries(testMountDir, map[string][]*util.FakeDirEntry{
+		"": {{Name: "dir1", Capacity: capacity, VolumeType: util.FakeEntryFile}},
+	})
+}
+
+func TestDiscoverVolumes_DryRun(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir1": {
+			{Name: "local-static-provisioner-1", Hash: 0x464f45da, Capacity: 10 * esUtil.GiB, VolumeType: util.FakeEntryFile},
+		},
+	}
+	test := &testConfig{
+		dirLayout: vols,
+	}
+	d := testSetup(t, test, false, false)
+	addPoolCapacity(test, 100*esUtil.GiB)
+	quotaUtil := util.NewFakeQuotaUtil()
+	d.QuotaUtil = quotaUtil
+	plan := &dryrun.Plan{}
+	d.APIUtil = dryrun.NewAPIUtil(test.cache, plan)
+	d.Plan = plan
+	d.DryRun = true
+	size := resource.MustParse("10Gi")
+	config := scMapping["sc1"]
+	config.DirectoryPool = &common.DirectoryPool{Count: 2, Size: &size}
+	d.DiscoveryMap = map[string]common.MountConfig{"sc1": config}
+
+	d.DiscoverLocalVolumes()
+	if createdPVs := getAndResetCreatedPVs(test.client, test.cache); len(createdPVs) != 0 {
+		t.Errorf("Expected no created PVs in dry run, got %v", createdPVs)
+	}
+	pool2Path := filepath.Join(testMountDir, "dir1", "local-static-provisioner-2")
+	if _, ok := quotaUtil.ProjectIDs[pool2Path]; ok {
+		t.Errorf("Expected directory %q not to be created in dry run", pool2Path)
+	}
+	changes := plan.Changes()
+	if len(changes) != 2 || changes[0].Action != dryrun.ActionCreateVolume || changes[1].Action != dryrun.ActionCreate || changes[1].PersistentVolume != getPVName(vols["dir1"][0]) {
+		t.Fatalf("Expected creation of directory %q and of the PV of %q in plan, got %+v", pool2Path, vols["dir1"][0].Name, changes)
+	}
+	expected := dryrun.Change{
+		Action:       dryrun.ActionCreateVolume,
+		StorageClass: "sc1",
+		Path:         filepath.Join(testHostDir, "dir1", "local-static-provisioner-2"),
+		Capacity:     "10Gi",
+		Reason:       "directory missing from directory pool",
+	}
+	if changes[0] != expected {
+		t.Errorf("Expected change %+v, got %+v", expected, changes[0])
+	}
+}
+
+func TestDiscoverVolumes_VolumeMetadata(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir1": {
+			{Name: "mount1", Hash: 0xaaaafef5, VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.MiB},
+			{Name: ".mount1.pv.yaml", VolumeType: util.FakeEntryFile, Content: []byte(`
+labels:
+  example.com/tier: gold
+annotations:
+  example.com/owner: team-a
+capacity: 60Mi
+mountOptions:
+  - noatime
+claimRef:
+  namespace: ns1
+  name: claim1
+`)},
+			{Name: "mount2", Hash: 0x79412c38, VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.MiB},
+			{Name: ".mount2.pv.yaml", VolumeType: util.FakeEntryFile, Content: []byte("exclude: true\n")},
+			{Name: "mount3", VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.MiB},
+			{Name: ".mount3.pv.yaml", VolumeType: util.FakeEntryFile, Content: []byte("annotations:\n  pv.kubernetes.io/provisioned-by: other\n")},
+			{Name: "mount4", VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.MiB},
+			{Name: ".mount4.pv.yaml", VolumeType: util.FakeEntryFile, Content: []byte("capacity: 1Gi\n")},
+		},
+	}
+	test := &testConfig{
+		dirLayout: vols,
+	}
+	d := testSetup(t, test, false, false)
+	config := scMapping["sc1"]
+	config.VolumeMetadata = true
+	d.DiscoveryMap = map[string]common.MountConfig{"sc1": config}
+
+	d.DiscoverLocalVolumes()
+	if d.Readyz.Check(nil) == nil {
+		t.Errorf("Expected discoverer not to be ready with invalid metadata files")
+	}
+	createdPVs := getAndResetCreatedPVs(test.client, test.cache)
+	if len(createdPVs) != 1 {
+		t.Fatalf("Expected 1 created PV, got %v", len(createdPVs))
+	}
+	pv, ok := createdPVs[getPVName(vols["dir1"][0])]
+	if !ok {
+		t.Fatalf("Expected PV of %q to be created, got %v", vols["dir1"][0].Name, createdPVs)
+	}
+	if pv.Labels["example.com/tier"] != "gold" || pv.Labels["local-storage-cr-name"] != "foobar" {
+		t.Errorf("Expected labels of the metadata file and the class, got %v", pv.Labels)
+	}
+	if pv.Annotations["example.com/owner"] != "team-a" || pv.Annotations[common.AnnProvisionedBy] != testProvisionerName {
+		t.Errorf("Expected annotations of the metadata file and the provisioner, got %v", pv.Annotations)
+	}
+	capacity := pv.Spec.Capacity[v1.ResourceStorage]
+	if capacity.Value() != 60*esUtil.MiB {
+		t.Errorf("Expected capacity %d, got %d", 60*esUtil.MiB, capacity.Value())
+	}
+	if expected := []string{"ro", "noatime"}; !reflect.DeepEqual(pv.Spec.MountOptions, expected) {
+		t.Errorf("Expected mount options %v, got %v", expected, pv.Spec.MountOptions)
+	}
+	if ref := pv.Spec.ClaimRef; ref == nil || ref.Kind != "PersistentVolumeClaim" || ref.Namespace != "ns1" || ref.Name != "claim1" {
+		t.Errorf("Expected PV to be pre-bound to claim ns1/claim1, got %+v", ref)
+	}
+	if !reflect.DeepEqual(expectedPVLabels, d.Labels) {
+		t.Errorf("Expected labels of the discoverer to be unchanged, got %v", d.Labels)
+	}
+}
+
+func TestDiscoverVolumes_Reservations(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir1": {
+			{Name: "mount1", Hash: 0xaaaafef5, VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB},
+			{Name: "mount2", Hash: 0x79412c38, VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB},
+		},
+	}
+	test := &testConfig{
+		dirLayout:       vols,
+		expectedVolumes: vols,
+	}
+	d := testSetup(t, test, false, false)
+	recorder := record.NewFakeRecorder(10)
+	d.Recorder = recorder
+	mount1Path := filepath.Join(testHostDir, "dir1", "mount1")
+	mount2Path := filepath.Join(testHostDir, "dir1", "mount2")
+	d.reservations = nodeReservations(testNodeName, []common.Reservation{
+		{Node: testNodeName, StorageClass: "sc1", Path: mount1Path, Claim: common.ClaimReference{Namespace: "ns1", Name: "claim1"}},
+		// Reservations of other nodes and classes are ignored
+		{Node: "other-node", StorageClass: "sc1", Path: mount2Path, Claim: common.ClaimReference{Namespace: "ns1", Name: "claim2"}},
+		{Node: testNodeName, StorageClass: "sc2", Path: mount2Path, Claim: common.ClaimReference{Namespace: "ns1", Name: "claim2"}},
+	})
+
+	d.DiscoverLocalVolumes()
+	createdPVs := getAndResetCreatedPVs(test.client, test.cache)
+	reservedPV := createdPVs[getPVName(vols["dir1"][0])]
+	if reservedPV == nil || reservedPV.Spec.ClaimRef == nil || reservedPV.Spec.ClaimRef.Namespace != "ns1" || reservedPV.Spec.ClaimRef.Name != "claim1" {
+		t.Fatalf("Expected PV of reserved volume to be pre-bound to claim ns1/claim1, got %+v", reservedPV)
+	}
+	if pv := createdPVs[getPVName(vols["dir1"][1])]; pv == nil || pv.Spec.ClaimRef != nil {
+		t.Fatalf("Expected PV of volume without reservation not to be pre-bound, got %+v", pv)
+	}
+
+	// The available PV of a volume reserved later is pre-bound
+	for _, file := range vols["dir1"] {
+		pv, _ := test.cache.GetPV(getPVName(file))
+		pv.Status.Phase = v1.VolumeAvailable
+	}
+	d.reservations[reservationKey{class: "sc1", path: mount2Path}] = common.ClaimReference{Namespace: "ns1", Name: "claim2"}
+	d.DiscoverLocalVolumes()
+	verifyEvent(t, recorder, fmt.Sprintf("%s %s Volume at %q is reserved for claim ns1/claim2",
+		v1.EventTypeNormal, common.EventVolumeReserved, mount2Path))
+	pv, _ := test.cache.GetPV(getPVName(vols["dir1"][1]))
+	if pv.Spec.ClaimRef == nil || pv.Spec.ClaimRef.Namespace != "ns1" || pv.Spec.ClaimRef.Name != "claim2" {
+		t.Errorf("Expected PV %q to be pre-bound to claim ns1/claim2, got %+v", pv.Name, pv.Spec.ClaimRef)
+	}
+
+	// A PV claimed by another claim is left alone, and the conflict is reported once
+	d.reservations[reservationKey{class: "sc1", path: mount1Path}] = common.ClaimReference{Namespace: "ns1", Name: "claim3"}
+	d.DiscoverLocalVolumes()
+	d.DiscoverLocalVolumes()
+	verifyEvent(t, recorder, fmt.Sprintf("%s %s Volume at %q is reserved for claim ns1/claim3, but claimed by ns1/claim1",
+		v1.EventTypeWarning, common.EventVolumeReservationConflict, mount1Path))
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("Unexpected event %q", event)
+	default:
+	}
+	pv, _ = test.cache.GetPV(getPVName(vols["dir1"][0]))
+	if pv.Spec.ClaimRef.Name != "claim1" {
+		t.Errorf("Expected PV %q to stay pre-bound to claim ns1/claim1, got %+v", pv.Name, pv.Spec.ClaimRef)
+	}
+}
+
+func TestValidateVolumeMetadata(t *testing.T) {
+	capacity := resource.MustParse("1Gi")
+	zero := resource.MustParse("0")
+	testcases := map[string]struct {
+		metadata  *volumeMetadata
+		expectErr bool
+	}{
+		"empty": {
+			metadata: &volumeMetadata{},
+		},
+		"valid": {
+			metadata: &volumeMetadata{
+				Labels:      map[string]string{"example.com/tier": "gold"},
+				Annotations: map[string]string{"example.com/owner": "team-a"},
+				Capacity:    &capacity,
+				ClaimRef:    &common.ClaimReference{Namespace: "ns1", Name: "claim1"},
+			},
+		},
+		"invalid label": {
+			metadata:  &volumeMetadata{Labels: map[string]string{"tier": "not a label value"}},
+			expectErr: true,
+		},
+		"reserved annotation": {
+			metadata:  &volumeMetadata{Annotations: map[string]string{common.AnnDeviceFingerprint: "abc"}},
+			expectErr: true,
+		},
+		"zero capacity": {
+			metadata:  &volumeMetadata{Capacity: &zero},
+			expectErr: true,
+		},
+		"claim without name": {
+			metadata:  &volumeMetadata{ClaimRef: &common.ClaimReference{Namespace: "ns1"}},
+			expectErr: true,
+		},
+	}
+	for name, test := range testcases {
+		err := validateVolumeMetadata(test.metadata)
+		if test.expectErr && err == nil {
+			t.Errorf("%s: expected error, got none", name)
+		}
+		if !test.expectErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+	}
+}
+
+func TestDiscoverVolumes_MaxDepth(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir1": {
+			{Name: "mount1", VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB},
+			{Name: "rack1", VolumeType: util.FakeEntryFile},
+			{Name: "rack2", VolumeType: util.FakeEntryFile},
+			{Name: "rack3", VolumeType: util.FakeEntryFile},
+		},
+		"dir1/rack1": {
+			{Name: "slot1", VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB},
+			{Name: "slot2", VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB},
+			{Name: "spare", VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB},
+		},
+		"dir1/rack2": {
+			{Name: "slot1", VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB},
+		},
+		"dir1/rack3": {
+			{Name: "slot1", VolumeType: util.FakeEntryFile, Capacity: 100 * esUtil.GiB},
+		},
+	}
+	test := &testConfig{
+		dirLayout: vols,
+	}
+	d := testSetup(t, test, false, false)
+	fm := &mount.FakeMounter{}
+	for _, file := range []string{"mount1", "rack1/slot1", "rack1/slot2", "rack1/spare", "rack2/slot1", "rack3/slot1"} {
+		fm.MountPoints = append(fm.MountPoints, mount.MountPoint{Path: filepath.Join(testMountDir, "dir1", file)})
+	}
+	d.Mounter = fm
+	config := scMapping["sc1"]
+	config.MaxDepth = 2
+	config.IncludeRegex = []string{"^mount[0-9]+$", "^rack[0-9]+/slot[0-9]+$"}
+	config.ExcludePatterns = []string{"rack2/*", "rack3"}
+	d.DiscoveryMap = map[string]common.MountConfig{"sc1": config}
+
+	d.DiscoverLocalVolumes()
+	createdPVs := getAndResetCreatedPVs(test.client, test.cache)
+	expected := []string{"mount1", "rack1/slot1", "rack1/slot2"}
+	if len(createdPVs) != len(expected) {
+		t.Errorf("Expected %d created PVs, got %d", len(expected), len(createdPVs))
+	}
+	for _, file := range expected {
+		pvName := generatePVName(file, testNodeName, "sc1")
+		pv, ok := createdPVs[pvName]
+		if !ok {
+			t.Errorf("Expected PV %q of %q to be created", pvName, file)
+			continue
+		}
+		if path := filepath.Join(testHostDir, "dir1", file); pv.Spec.Local.Path != path {
+			t.Errorf("Expected path %q of PV %q, got %q", path, pvName, pv.Spec.Local.Path)
+		}
+	}
+	// The names of the PVs of top level entries do not depend on maxDepth
+	if _, ok := createdPVs["local-pv-aaaafef5"]; !ok {
+		t.Errorf("Expected PV local-pv-aaaafef5 of mount1 to be created")
+	}
+}
+
+func TestDiscoverVolumes_CapacityPolicy(t *testing.T) {
+	gib := resource.MustParse("1Gi")
+	testcases := map[string]struct {
+		policy   *common.CapacityPolicy
+		capacity int64
+		expected int64
+		created  bool
+	}{
+		"pretty by default": {
+			capacity: 10*esUtil.GiB + 5*esUtil.MiB,
+			expected: 10 * esUtil.GiB,
+			created:  true,
+		},
+		"exact": {
+			policy:   &common.CapacityPolicy{Rounding: common.CapacityRoundingExact},
+			capacity: 10*esUtil.GiB + 5,
+			expected: 10*esUtil.GiB + 5,
+			created:  true,
+		},
+		"unit": {
+			policy:   &common.CapacityPolicy{Rounding: common.CapacityRoundingUnit, RoundingUnit: &gib},
+			capacity: 2*esUtil.GiB - 1,
+			expected: 1 * esUtil.GiB,
+			created:  true,
+		},
+		"fixed reserve": {
+			policy:   &common.CapacityPolicy{Rounding: common.CapacityRoundingExact, Reserve: "100Mi"},
+			capacity: 10 * esUtil.GiB,
+			expected: 10*esUtil.GiB - 100*esUtil.MiB,
+			created:  true,
+		},
+		"percentage reserve": {
+			policy:   &common.CapacityPolicy{Reserve: "10%"},
+			capacity: 20 * esUtil.GiB,
+			expected: 18 * esUtil.GiB,
+			created:  true,
+		},
+		"available": {
+			policy:   &common.CapacityPolicy{Rounding: common.CapacityRoundingExact, FsCapacity: common.FsCapacityAvailable},
+			capacity: 10 * esUtil.GiB,
+			expected: 9 * esUtil.GiB,
+			created:  true,
+		},
+		"reserve exceeds capacity": {
+			policy:   &common.CapacityPolicy{Reserve: "1Gi"},
+			capacity: 100 * esUtil.MiB,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			vols := map[string][]*util.FakeDirEntry{
+				"dir1": {
+					{Name: "mount1", Hash: 0xaaaafef5, VolumeType: util.FakeEntryFile, Capacity: tc.capacity, Available: 9 * esUtil.GiB},
+				},
+			}
+			test := &testConfig{
+				dirLayout: vols,
+			}
+			d := testSetup(t, test, false, false)
+			config := scMapping["sc1"]
+			config.CapacityPolicy = tc.policy
+			d.DiscoveryMap = map[string]common.MountConfig{"sc1": config}
+
+			d.DiscoverLocalVolumes()
+			createdPVs := getAndResetCreatedPVs(test.client, test.cache)
+			pv, ok := createdPVs[getPVName(vols["dir1"][0])]
+			if ok != tc.created {
+				t.Fatalf("Expected PV to be created %v, got %v", tc.created, createdPVs)
+			}
+			if !tc.created {
+				return
+			}
+			capacity := pv.Spec.Capacity[v1.ResourceStorage]
+			if capacity.Value() != tc.expected {
+				t.Errorf("Expected capacity %d, got %d", tc.expected, capacity.Value())
+			}
+		})
+	}
+}
+
+func TestDiscoverVolumes_DeviceIdentity(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir2": {
+			// Hash of the device fingerprint instead of the file name
+			{Name: "symlink1", Hash: 0x8f45f7e4, VolumeType: util.FakeEntryBlock,
+				DeviceAttributes: &util.DeviceAttributes{Name: "nvme0n1", WWN: "eui.0025388b91b2c1a4"}},
+		},
+	}
+	test := &testConfig{
+		dirLayout:       vols,
+		expectedVolumes: vols,
+	}
+	d := testSetup(t, test, false, false)
+	recorder := record.NewFakeRecorder(10)
+	d.Recorder = recorder
+	config := scMapping["sc2"]
+	config.IdentityMode = common.IdentityModeDevice
+	d.DiscoveryMap = map[string]common.MountConfig{"sc2": config}
+
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	pv, exists := test.cache.GetPV(getPVName(vols["dir2"][0]))
+	if !exists {
+		t.Fatalf("PV %q not in cache", getPVName(vols["dir2"][0]))
+	}
+	if fingerprint := pv.Annotations[common.AnnDeviceFingerprint]; fingerprint != "wwn:eui.0025388b91b2c1a4" {
+		t.Errorf("Expected fingerprint annotation %q, got %q", "wwn:eui.0025388b91b2c1a4", fingerprint)
+	}
+
+	// A different device at the path of the PV is not provisioned
+	vols["dir2"][0].DeviceAttributes = &util.DeviceAttributes{Name: "nvme0n1", Model: "Example", Serial: "S1234567"}
+	test.expectedVolumes = map[string][]*util.FakeDirEntry{}
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	expectedEvent := fmt.Sprintf("%s %s Expected device %q at %q, found %q", v1.EventTypeWarning, common.EventVolumeDeviceMismatch,
+		"wwn:eui.0025388b91b2c1a4", filepath.Join(testHostDir, "dir2", "symlink1"), "serial:Example:S1234567")
+	verifyEvent(t, recorder, expectedEvent)
+
+	// The device of the PV found at a new path is not provisioned again
+	vols["dir2"][0].Name = "symlink2"
+	vols["dir2"][0].DeviceAttributes = &util.DeviceAttributes{Name: "nvme0n1", WWN: "eui.0025388b91b2c1a4"}
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+	expectedEvent = fmt.Sprintf("%s %s Device %q of volume at %q was found at %q", v1.EventTypeWarning, common.EventVolumeDeviceMoved,
+		"wwn:eui.0025388b91b2c1a4", filepath.Join(testHostDir, "dir2", "symlink1"), filepath.Join(testHostDir, "dir2", "symlink2"))
+	verifyEvent(t, recorder, expectedEvent)
+}
+
+func TestDiscoverVolumes_Inventory(t *testing.T) {
+	vols := map[string][]*util.FakeDirEntry{
+		"dir1": {
+			{Name: "mount1", Hash: 0xaaaafef5, VolumeType: util.FakeEntryFile, Capacity: 100 * 1024},
+			{Name: "mount2", Hash: 0x79412c38, VolumeType: util.FakeEntryFile, Capacity: 100 * 1024},
+			// mount5 is not listed in the FakeMounter MountPoints setup for testing
+			{Name: "mount5", VolumeType: util.FakeEntryFile, Capacity: 100 * 1024},
+		},
+		"dir2": {
+			{Name: "symlink1", Hash: 0x55d5adba, VolumeType: util.FakeEntryBlock, Capacity: 100 * 1024 * 1024},
+		},
+	}
+	expectedVols := map[string][]*util.FakeDirEntry{
+		"dir1": {vols["dir1"][0]},
+		"dir2": {vols["dir2"][0]},
+	}
+	test := &testConfig{
+		dirLayout:       vols,
+		expectedVolumes: expectedVols,
+	}
+	d := testSetup(t, test, false, false)
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	d.inventoryWriter = inventory.NewWriter(dynamicClient, testNode)
+	test.cleanupTracker.ProcTable.MarkRunning(getPVName(vols["dir1"][1]))
+
+	d.DiscoverLocalVolumes()
+	verifyCreatedPVs(t, test)
+
+	obj, err := dynamicClient.Resource(inventory.Resource).Get(context.TODO(), testNodeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Error getting local volume inventory: %v", err)
+	}
+	inv := &inventory.LocalVolumeInventory{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, inv); err != nil {
+		t.Fatalf("Error converting local volume inventory: %v", err)
+	}
+	expected := []inventory.Volume{
+		{Path: "/mnt/disks/dir1/mount1", StorageClass: "sc1", VolumeMode: "Filesystem", Capacity: resource.NewQuantity(100*1024, resource.BinarySI), PersistentVolume: getPVName(vols["dir1"][0])},
+		{Path: "/mnt/disks/dir1/mount2", StorageClass: "sc1", VolumeMode: "Filesystem", Reason: "cleanup in progress"},
+		{Path: "/mnt/disks/dir1/mount5", StorageClass: "sc1", VolumeMode: "Filesystem", Reason: `path "/discoveryPath/dir1/mount5" is not a valid mount point: hostPath="/mnt/disks/dir1/mount5" wasn't found in the /proc/mounts file`},
+		{Path: "/mnt/disks/dir2/symlink1", StorageClass: "sc2", VolumeMode: "Block", Capacity: resource.NewQuantity(100*1024*1024, resource.BinarySI), PersistentVolume: getPVName(vols["dir2"][0])},
+	}
+	if !apiequality.Semantic.DeepEqual(inv.Status.Volumes, expected) {
+		t.Errorf("Expected inventory volumes %+v, got %+v", expected, inv.Status.Volumes)
+	}
+	if len(inv.OwnerReferences) != 1 || inv.OwnerReferences[0].UID != testNode.UID {
+		t.Errorf("Expected inventory to be owned by node %q, got %+v", testNodeName, inv.OwnerReferences)
+	}
+
+	// The inventory is not written again if nothing changed.
+	dynamicClient.ClearActions()
+	test.cleanupTracker.ProcTable.MarkRunning(getPVName(vols["dir1"][1]))
+	d.DiscoverLocalVolumes()
+	if actions := dynamicClient.Actions(); len(actions) != 0 {
+		t.Errorf("Expected no inventory update, got %v", actions)
+	}
+}
+
 func TestDiscoverVolumes_NoDir(t *testing.T) {
 	vols := map[string][]*util.FakeDirEntry{}
 	test := &testConfig{
@@ -622,6 +1715,17 @@ type testPVInfo struct {
 	volumeMode   v1.PersistentVolumeMode
 }
 
+func verifyEvent(t *testing.T, recorder *record.FakeRecorder, expected string) {
+	select {
+	case event := <-recorder.Events:
+		if event != expected {
+			t.Errorf("Expected event %q, got %q", expected, event)
+		}
+	default:
+		t.Errorf("Expected event %q, got none", expected)
+	}
+}
+
 func getPVName(entry *util.FakeDirEntry) string {
 	return fmt.Sprintf("local-pv-%x", entry.Hash)
 }
@@ -780,3 +1884,14 @@ func getAndResetCreatedPVs(cli *fake.Clientset, cache *cache.VolumeCache) map[st
 	cli.ClearActions()
 	return pvs
 }
+
+func TestDrainPathEvents(t *testing.T) {
+	events := make(chan PathEvent, 3)
+	events <- PathEvent{Class: "sc1", File: "b"}
+	events <- PathEvent{Class: "sc2", File: "c"}
+
+	batch := DrainPathEvents(PathEvent{Class: "sc1", File: "a"}, events)
+	if len(batch) != 3 {
+		t.Errorf("Expected 3 events, got %d: %+v", len(batch), batch)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+// entryFilter selects the entries of a discovery directory by their path
+// relative to it.
+type entryFilter struct {
+	includeRegex    []*regexp.Regexp
+	excludePatterns []string
+}
+
+func newEntryFilter(config common.MountConfig) (*entryFilter, error) {
+	filter := &entryFilter{excludePatterns: config.ExcludePatterns}
+	for _, expr := range config.IncludeRegex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid includeRegex %q: %v", expr, err)
+		}
+		filter.includeRegex = append(filter.includeRegex, re)
+	}
+	return filter, nil
+}
+
+// included returns true if file matches one of the include regular
+// expressions, or there are none.
+func (f *entryFilter) included(file string) bool {
+	if len(f.includeRegex) == 0 {
+		return true
+	}
+	file = filepath.ToSlash(file)
+	for _, re := range f.includeRegex {
+		if re.MatchString(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded returns the exclude pattern matching file, empty if none.
+func (f *entryFilter) excluded(file string) string {
+	file = filepath.ToSlash(file)
+	for _, pattern := range f.excludePatterns {
+		if matched, _ := filepath.Match(pattern, file); matched {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// entryDepth returns the number of directory levels of file below MountDir.
+func entryDepth(file string) int {
+	return strings.Count(filepath.ToSlash(file), "/") + 1
+}
+
+// expandDirectories replaces the entries of files which are directories above
+// config.MaxDepth by the entries under them, so that nested layouts such as
+// <rack>/<slot> are discovered by their path relative to config.MountDir.
+// Directories which are mount points are volumes and are not descended into,
+// and excluded directories are skipped.
+func (d *Discoverer) expandDirectories(config common.MountConfig, filter *entryFilter, files []string, mountPointMap map[string]interface{}) []string {
+	if config.MaxDepth <= 1 {
+		return files
+	}
+	var expanded []string
+	for _, file := range files {
+		if entryDepth(file) >= config.MaxDepth || isVolumeMetadataFile(filepath.Base(file)) {
+			expanded = append(expanded, file)
+			continue
+		}
+		filePath := filepath.Join(config.MountDir, file)
+		if _, isMountPoint := mountPointMap[filePath]; isMountPoint {
+			expanded = append(expanded, file)
+			continue
+		}
+		// Errors are reported by the discovery of the entry itself.
+		if isBlock, err := d.VolUtil.IsBlock(filePath); err != nil || isBlock {
+			expanded = append(expanded, file)
+			continue
+		}
+		if isDir, err := d.VolUtil.IsDir(filePath); err != nil || !isDir {
+			expanded = append(expanded, file)
+			continue
+		}
+		if pattern := filter.excluded(file); pattern != "" {
+			klog.V(5).Infof("directory(%s) under(%s) is excluded by pattern(%s)", file, config.MountDir, pattern)
+			continue
+		}
+		entries, err := d.VolUtil.ReadDir(filePath)
+		if err != nil {
+			klog.Errorf("Error reading directory %q: %v", filePath, err)
+			continue
+		}
+		children := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			children = append(children, filepath.Join(file, entry))
+		}
+		expanded = append(expanded, d.expandDirectories(config, filter, children, mountPointMap)...)
+	}
+	return expanded
+}
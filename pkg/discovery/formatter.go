@@ -0,0 +1,89 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+// formatAndMount formats the block device at file in config.MountDir with
+// config.FsType if needed, and mounts it at file in the format and mount
+// directory. It returns false if the device is left alone, because it is
+// used by a PV or carries signatures of another filesystem or partitioning.
+func (d *Discoverer) formatAndMount(config common.MountConfig, file string, mountPointMap map[string]interface{}) (bool, error) {
+	devPath := filepath.Join(config.MountDir, file)
+	target := filepath.Join(config.FormatAndMount.MountDir, file)
+	if _, mounted := mountPointMap[target]; mounted {
+		return true, nil
+	}
+	// A device which is already used as a raw volume is left alone.
+	if len(d.Cache.LookupPVsByPath(filepath.Join(config.HostDir, file))) > 0 {
+		return false, nil
+	}
+	if d.DryRun {
+		klog.Infof("Dry run, not formatting and mounting device %q", devPath)
+		return false, nil
+	}
+
+	signatures, err := d.PartitionUtil.GetSignatures(devPath)
+	if err != nil {
+		return false, fmt.Errorf("path %q signatures error: %v", devPath, err)
+	}
+	switch {
+	case len(signatures) == 0:
+		klog.Infof("Formatting device %q with %s", devPath, config.FsType)
+		err = d.FormatUtil.Format(devPath, config.FsType, common.FilesystemLabel, config.FormatAndMount.MkfsOptions, false)
+	case len(signatures) == 1 && signatures[0].Type == config.FsType && signatures[0].Label == common.FilesystemLabel:
+		// Formatted by the provisioner before, e.g. prior to a reboot.
+	case config.FormatAndMount.AllowOverwrite:
+		klog.Warningf("Formatting device %q with %s, overwriting signatures %v", devPath, config.FsType, signatures)
+		err = d.FormatUtil.Format(devPath, config.FsType, common.FilesystemLabel, config.FormatAndMount.MkfsOptions, true)
+	default:
+		klog.V(4).Infof("Not formatting device %q with signatures %v", devPath, signatures)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("path %q format error: %v", devPath, err)
+	}
+
+	if err := d.VolUtil.MakeDir(target); err != nil {
+		return false, fmt.Errorf("failed to create mount point %q: %v", target, err)
+	}
+	if err := d.Mounter.Mount(devPath, target, config.FsType, nil); err != nil {
+		return false, fmt.Errorf("failed to mount %q at %q: %v", devPath, target, err)
+	}
+	klog.Infof("Mounted device %q at %q", devPath, target)
+	return true, nil
+}
+
+// formattedConfig returns the configuration used to discover the volumes
+// formatted and mounted for a storage class with config.
+func formattedConfig(config common.MountConfig) common.MountConfig {
+	formatted := config
+	formatted.HostDir = config.FormatAndMount.HostDir
+	formatted.MountDir = config.FormatAndMount.MountDir
+	formatted.NamePattern = ""
+	formatted.DeviceSelector = nil
+	formatted.Partitioning = nil
+	formatted.FormatAndMount = nil
+	formatted.LVM = nil
+	return formatted
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+const (
+	ueventActionAdd    = "add"
+	ueventActionChange = "change"
+	ueventActionRemove = "remove"
+
+	ueventSubsystemBlock = "block"
+
+	// udevMonitorPrefix starts the messages that udevd forwards to the udev
+	// monitor netlink group, as opposed to the plain kernel messages.
+	udevMonitorPrefix = "libudev\x00"
+	// udevMonitorHeaderSize is the size of the udev monitor header fields up
+	// to and including properties_len.
+	udevMonitorHeaderSize = 24
+)
+
+// uevent is a block device event sent by the kernel or forwarded by udevd.
+type uevent struct {
+	Action    string
+	Subsystem string
+	// DevName is the kernel name of the device, e.g. "nvme0n1".
+	DevName string
+	DevType string
+}
+
+// HotplugRulesConfigured returns true if any storage class in discoveryMap
+// wants to discover hot-plugged block devices.
+func HotplugRulesConfigured(discoveryMap map[string]common.MountConfig) bool {
+	for _, config := range discoveryMap {
+		if len(config.HotplugRules) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// parseUevent parses a message received on a NETLINK_KOBJECT_UEVENT socket.
+// Both the kernel format ("action@devpath" followed by KEY=VALUE pairs) and the
+// udev monitor format are supported. It returns false if msg is malformed.
+func parseUevent(msg []byte) (*uevent, bool) {
+	var properties []byte
+	if bytes.HasPrefix(msg, []byte(udevMonitorPrefix)) {
+		if len(msg) < udevMonitorHeaderSize {
+			return nil, false
+		}
+		offset := binary.NativeEndian.Uint32(msg[16:20])
+		length := binary.NativeEndian.Uint32(msg[20:24])
+		if uint64(offset)+uint64(length) > uint64(len(msg)) {
+			return nil, false
+		}
+		properties = msg[offset : offset+length]
+	} else {
+		header, rest, found := bytes.Cut(msg, []byte{0})
+		if !found || !bytes.Contains(header, []byte("@")) {
+			return nil, false
+		}
+		properties = rest
+	}
+
+	event := &uevent{}
+	for _, field := range bytes.Split(properties, []byte{0}) {
+		key, value, found := strings.Cut(string(field), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "ACTION":
+			event.Action = value
+		case "SUBSYSTEM":
+			event.Subsystem = value
+		case "DEVNAME":
+			// The kernel reports the name relative to /dev, udevd the absolute path.
+			event.DevName = filepath.Base(value)
+		case "DEVTYPE":
+			event.DevType = value
+		}
+	}
+	if event.Action == "" || event.DevName == "" {
+		return nil, false
+	}
+	return event, true
+}
+
+// matchHotplugRules returns true if the block device event matches any of rules.
+func matchHotplugRules(rules []common.HotplugRule, event *uevent) bool {
+	if event.Subsystem != ueventSubsystemBlock {
+		return false
+	}
+	for _, rule := range rules {
+		if rule.DevType != "" && rule.DevType != event.DevType {
+			continue
+		}
+		if matched, _ := filepath.Match(rule.DevNamePattern, event.DevName); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+const (
+	// ueventGroups subscribes to the events forwarded by udevd once it has
+	// processed the device, e.g. created the symlinks pointing to it. The
+	// kernel group (1) is left out as it would deliver every event twice.
+	ueventGroups = 2
+
+	// ueventReceiveBufferSize is the socket receive buffer size requested so
+	// that a burst of uevents, e.g. at boot, is not dropped.
+	ueventReceiveBufferSize = 1 << 20
+
+	// ueventMessageSize is large enough for any kernel or udev monitor message.
+	ueventMessageSize = 16 * 1024
+)
+
+var _ PathWatcher = &hotplugWatcher{}
+
+// hotplugWatcher is a PathWatcher based on block device uevents received on a
+// NETLINK_KOBJECT_UEVENT socket.
+type hotplugWatcher struct {
+	discoveryMap map[string]common.MountConfig
+	devDir       string
+
+	sockFd int
+	stopFd int
+
+	// entries maps a storage class to the entries of its MountDir which point
+	// to a block device and the kernel name of that device. It is needed to
+	// map a remove event to the entries once the device node is gone.
+	entries map[string]map[string]string
+
+	events   chan PathEvent
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewHotplugWatcher returns a PathWatcher which reports the entries in the
+// MountDir of the storage classes with hotplug rules that point to a block
+// device matching the rules whenever such a device is added, changed or removed.
+// The provisioner must run in the host network namespace to receive uevents.
+func NewHotplugWatcher(discoveryMap map[string]common.MountConfig) (PathWatcher, error) {
+	w := newHotplugWatcher(discoveryMap, "/dev")
+	if err := w.init(); err != nil {
+		w.close()
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+func newHotplugWatcher(discoveryMap map[string]common.MountConfig, devDir string) *hotplugWatcher {
+	w := &hotplugWatcher{
+		discoveryMap: discoveryMap,
+		devDir:       devDir,
+		sockFd:       -1,
+		stopFd:       -1,
+		entries:      map[string]map[string]string{},
+		events:       make(chan PathEvent, pathEventBufferSize),
+		done:         make(chan struct{}),
+	}
+	for class, config := range discoveryMap {
+		if len(config.HotplugRules) > 0 {
+			w.scan(class, config)
+		}
+	}
+	return w
+}
+
+func (w *hotplugWatcher) init() error {
+	var err error
+	w.sockFd, err = unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return fmt.Errorf("failed to create uevent netlink socket: %v", err)
+	}
+	if err := unix.SetsockoptInt(w.sockFd, unix.SOL_SOCKET, unix.SO_RCVBUFFORCE, ueventReceiveBufferSize); err != nil {
+		klog.Warningf("Failed to increase uevent socket receive buffer: %v", err)
+	}
+	// Receive the credentials of the senders, so that the messages sent to
+	// the udev group by unprivileged processes can be dropped.
+	if err := unix.SetsockoptInt(w.sockFd, unix.SOL_SOCKET, unix.SO_PASSCRED, 1); err != nil {
+		return fmt.Errorf("failed to enable credentials on uevent netlink socket: %v", err)
+	}
+	if err := unix.Bind(w.sockFd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: ueventGroups}); err != nil {
+		return fmt.Errorf("failed to bind uevent netlink socket: %v", err)
+	}
+	if w.stopFd, err = unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK); err != nil {
+		return fmt.Errorf("failed to create eventfd: %v", err)
+	}
+	return nil
+}
+
+// Events returns the channel on which path events are delivered.
+func (w *hotplugWatcher) Events() <-chan PathEvent {
+	return w.events
+}
+
+// Stop stops watching and releases all resources held by the watcher.
+func (w *hotplugWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		var one uint64 = 1
+		if _, err := unix.Write(w.stopFd, (*[8]byte)(unsafe.Pointer(&one))[:]); err != nil {
+			klog.Errorf("Failed to signal hotplug watcher to stop: %v", err)
+		}
+	})
+}
+
+func (w *hotplugWatcher) close() {
+	for _, fd := range []int{w.sockFd, w.stopFd} {
+		if fd >= 0 {
+			unix.Close(fd)
+		}
+	}
+}
+
+func (w *hotplugWatcher) run() {
+	defer w.close()
+	fds := []unix.PollFd{
+		{Fd: int32(w.stopFd), Events: unix.POLLIN},
+		{Fd: int32(w.sockFd), Events: unix.POLLIN},
+	}
+	buf := make([]byte, ueventMessageSize)
+	oob := make([]byte, unix.CmsgSpace(unix.SizeofUcred))
+	for {
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			klog.Errorf("Hotplug watcher stopped, poll failed: %v", err)
+			return
+		}
+		if fds[0].Revents != 0 {
+			return
+		}
+		for {
+			n, oobn, _, _, err := unix.Recvmsg(w.sockFd, buf, oob, 0)
+			if err == unix.EINTR {
+				continue
+			}
+			if err == unix.ENOBUFS {
+				klog.Warningf("Hotplug watcher dropped uevents, changes will be picked up by the next discovery")
+				continue
+			}
+			if err != nil || n <= 0 {
+				// EAGAIN: the socket has been drained.
+				break
+			}
+			if !isTrustedUeventSender(oob[:oobn]) {
+				klog.V(4).Infof("Hotplug watcher dropped uevent of an unprivileged sender")
+				continue
+			}
+			if event, ok := parseUevent(buf[:n]); ok {
+				w.handleUevent(event)
+			}
+		}
+	}
+}
+
+// isTrustedUeventSender returns true if the control messages of a uevent hold
+// the credentials of root, the ones of the kernel and of udevd, like libudev
+// checks them. Any process may send to the udev group.
+func isTrustedUeventSender(oob []byte) bool {
+	messages, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return false
+	}
+	for i := range messages {
+		if cred, err := unix.ParseUnixCredentials(&messages[i]); err == nil {
+			return cred.Uid == 0
+		}
+	}
+	return false
+}
+
+func (w *hotplugWatcher) handleUevent(event *uevent) {
+	for class, config := range w.discoveryMap {
+		if !matchHotplugRules(config.HotplugRules, event) {
+			continue
+		}
+		klog.V(4).Infof("Hotplug watcher observed %s of block device %q for storage class %q", event.Action, event.DevName, class)
+		removed := false
+		switch event.Action {
+		case ueventActionAdd, ueventActionChange:
+			w.scan(class, config)
+		case ueventActionRemove:
+			removed = true
+		default:
+			continue
+		}
+		for file, devName := range w.entries[class] {
+			if devName == event.DevName {
+				w.send(PathEvent{Class: class, File: file, Device: event.DevName, Removed: removed})
+			}
+		}
+	}
+}
+
+// scan refreshes the entries of class pointing to a block device.
+func (w *hotplugWatcher) scan(class string, config common.MountConfig) {
+	files, err := os.ReadDir(config.MountDir)
+	if err != nil {
+		klog.Errorf("Hotplug watcher failed to read %q for storage class %q: %v", config.MountDir, class, err)
+		return
+	}
+	entries := map[string]string{}
+	for _, file := range files {
+		target, err := filepath.EvalSymlinks(filepath.Join(config.MountDir, file.Name()))
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(w.devDir, target); err == nil && !strings.HasPrefix(rel, "..") && !strings.Contains(rel, "/") {
+			entries[file.Name()] = rel
+		}
+	}
+	w.entries[class] = entries
+}
+
+func (w *hotplugWatcher) send(event PathEvent) {
+	select {
+	case w.events <- event:
+	case <-w.done:
+	}
+}
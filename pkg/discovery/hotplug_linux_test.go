@@ -0,0 +1,104 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+func TestHotplugWatcher_HandleUevent(t *testing.T) {
+	devDir := t.TempDir()
+	mountDir := t.TempDir()
+	for _, dev := range []string{"nvme0n1", "sda"} {
+		if err := os.WriteFile(filepath.Join(devDir, dev), nil, 0600); err != nil {
+			t.Fatalf("Error creating fake device: %v", err)
+		}
+	}
+	discoveryMap := map[string]common.MountConfig{
+		"sc1": {
+			HostDir:      "/mnt/disks",
+			MountDir:     mountDir,
+			HotplugRules: []common.HotplugRule{{DevNamePattern: "nvme*"}},
+		},
+		"sc2": {HostDir: "/mnt/other", MountDir: mountDir},
+	}
+	w := newHotplugWatcher(discoveryMap, devDir)
+
+	// The device is linked after the watcher started
+	if err := os.Symlink(filepath.Join(devDir, "nvme0n1"), filepath.Join(mountDir, "disk1")); err != nil {
+		t.Fatalf("Error creating symlink: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(devDir, "sda"), filepath.Join(mountDir, "disk2")); err != nil {
+		t.Fatalf("Error creating symlink: %v", err)
+	}
+
+	w.handleUevent(&uevent{Action: ueventActionAdd, Subsystem: ueventSubsystemBlock, DevName: "sda", DevType: "disk"})
+	w.handleUevent(&uevent{Action: ueventActionAdd, Subsystem: ueventSubsystemBlock, DevName: "nvme0n1", DevType: "disk"})
+	// The device node is gone by the time the remove event is handled
+	if err := os.Remove(filepath.Join(devDir, "nvme0n1")); err != nil {
+		t.Fatalf("Error removing fake device: %v", err)
+	}
+	w.handleUevent(&uevent{Action: ueventActionRemove, Subsystem: ueventSubsystemBlock, DevName: "nvme0n1", DevType: "disk"})
+
+	expected := []PathEvent{
+		{Class: "sc1", File: "disk1", Device: "nvme0n1"},
+		{Class: "sc1", File: "disk1", Device: "nvme0n1", Removed: true},
+	}
+	var events []PathEvent
+	for len(w.events) > 0 {
+		events = append(events, <-w.events)
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected events %+v, got %+v", expected, events)
+	}
+}
+
+func TestIsTrustedUeventSender(t *testing.T) {
+	testcases := map[string]struct {
+		oob      []byte
+		expected bool
+	}{
+		"kernel": {
+			oob:      unix.UnixCredentials(&unix.Ucred{Pid: 0, Uid: 0, Gid: 0}),
+			expected: true,
+		},
+		"udevd": {
+			oob:      unix.UnixCredentials(&unix.Ucred{Pid: 412, Uid: 0, Gid: 0}),
+			expected: true,
+		},
+		"unprivileged process": {
+			oob: unix.UnixCredentials(&unix.Ucred{Pid: 1234, Uid: 1000, Gid: 1000}),
+		},
+		"no credentials": {},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			if trusted := isTrustedUeventSender(tc.oob); trusted != tc.expected {
+				t.Errorf("Expected trusted %v, got %v", tc.expected, trusted)
+			}
+		})
+	}
+}
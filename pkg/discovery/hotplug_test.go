@@ -0,0 +1,114 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+func udevMonitorMessage(properties string) []byte {
+	const headerSize = 40
+	msg := make([]byte, headerSize)
+	copy(msg, udevMonitorPrefix)
+	binary.BigEndian.PutUint32(msg[8:12], 0xfeedcafe)
+	binary.NativeEndian.PutUint32(msg[12:16], headerSize)
+	binary.NativeEndian.PutUint32(msg[16:20], headerSize)
+	binary.NativeEndian.PutUint32(msg[20:24], uint32(len(properties)))
+	return append(msg, properties...)
+}
+
+func TestParseUevent(t *testing.T) {
+	kernelProperties := strings.Join([]string{
+		"ACTION=add",
+		"DEVPATH=/devices/pci0000:00/0000:00:04.0/nvme/nvme0/nvme0n1",
+		"SUBSYSTEM=block",
+		"MAJOR=259",
+		"MINOR=0",
+		"DEVNAME=nvme0n1",
+		"DEVTYPE=disk",
+		"SEQNUM=2048",
+	}, "\x00") + "\x00"
+	udevProperties := strings.Join([]string{
+		"ACTION=remove",
+		"DEVPATH=/devices/pci0000:00/0000:00:04.0/nvme/nvme0/nvme0n1/nvme0n1p1",
+		"SUBSYSTEM=block",
+		"DEVNAME=/dev/nvme0n1p1",
+		"DEVTYPE=partition",
+		"DEVLINKS=/dev/disk/by-id/nvme-disk-part1",
+	}, "\x00") + "\x00"
+
+	tests := map[string]struct {
+		msg      []byte
+		expected *uevent
+	}{
+		"kernel": {
+			msg:      []byte("add@/devices/pci0000:00/0000:00:04.0/nvme/nvme0/nvme0n1\x00" + kernelProperties),
+			expected: &uevent{Action: "add", Subsystem: "block", DevName: "nvme0n1", DevType: "disk"},
+		},
+		"udev": {
+			msg:      udevMonitorMessage(udevProperties),
+			expected: &uevent{Action: "remove", Subsystem: "block", DevName: "nvme0n1p1", DevType: "partition"},
+		},
+		"kernel-without-header": {
+			msg: []byte(kernelProperties),
+		},
+		"udev-truncated": {
+			msg: udevMonitorMessage(udevProperties)[:32],
+		},
+		"no-devname": {
+			msg: []byte("add@/module/loop\x00ACTION=add\x00SUBSYSTEM=module\x00"),
+		},
+	}
+	for name, test := range tests {
+		event, ok := parseUevent(test.msg)
+		if ok != (test.expected != nil) {
+			t.Errorf("%s: expected ok %v, got %v", name, test.expected != nil, ok)
+			continue
+		}
+		if ok && !reflect.DeepEqual(event, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", name, test.expected, event)
+		}
+	}
+}
+
+func TestMatchHotplugRules(t *testing.T) {
+	rules := []common.HotplugRule{
+		{DevNamePattern: "nvme*n1", DevType: common.DevTypeDisk},
+		{DevNamePattern: "sd[b-d]1"},
+	}
+	tests := []struct {
+		event    uevent
+		expected bool
+	}{
+		{uevent{Subsystem: "block", DevName: "nvme3n1", DevType: "disk"}, true},
+		{uevent{Subsystem: "block", DevName: "nvme3n1", DevType: "partition"}, false},
+		{uevent{Subsystem: "block", DevName: "nvme3n1p1", DevType: "partition"}, false},
+		{uevent{Subsystem: "block", DevName: "sdc1", DevType: "partition"}, true},
+		{uevent{Subsystem: "block", DevName: "sda1", DevType: "partition"}, false},
+		{uevent{Subsystem: "nvme", DevName: "nvme3n1", DevType: "disk"}, false},
+	}
+	for _, test := range tests {
+		if matched := matchHotplugRules(rules, &test.event); matched != test.expected {
+			t.Errorf("Expected match %v for %+v, got %v", test.expected, test.event, matched)
+		}
+	}
+}
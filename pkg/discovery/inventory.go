@@ -0,0 +1,76 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"cmp"
+	"path/filepath"
+	"slices"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/inventory"
+)
+
+// inventoryVolume adds the volume at the host path of the storage class to
+// the inventory, replacing the one seen before, and returns it so that the
+// outcome of its discovery can be filled in.
+func (d *Discoverer) inventoryVolume(class, path string) *inventory.Volume {
+	volumes, ok := d.inventoryVolumes[class]
+	if !ok {
+		volumes = map[string]*inventory.Volume{}
+		d.inventoryVolumes[class] = volumes
+	}
+	volume := &inventory.Volume{Path: path, StorageClass: class}
+	volumes[path] = volume
+	return volume
+}
+
+// resetInventory removes the volumes of the storage class from the inventory
+// before all of them are discovered again.
+func (d *Discoverer) resetInventory(class string) {
+	delete(d.inventoryVolumes, class)
+}
+
+// removeInventoryVolume removes the volume at file in the discovery directory
+// of the storage class from the inventory.
+func (d *Discoverer) removeInventoryVolume(class, file string) {
+	config, ok := d.DiscoveryMap[class]
+	if !ok {
+		return
+	}
+	delete(d.inventoryVolumes[class], filepath.Join(config.HostDir, file))
+}
+
+// publishInventory writes the volumes seen by discovery to the
+// LocalVolumeInventory of the node, if enabled.
+func (d *Discoverer) publishInventory() {
+	if d.inventoryWriter == nil {
+		return
+	}
+	volumes := []inventory.Volume{}
+	for _, classVolumes := range d.inventoryVolumes {
+		for _, volume := range classVolumes {
+			volumes = append(volumes, *volume)
+		}
+	}
+	slices.SortFunc(volumes, func(a, b inventory.Volume) int {
+		return cmp.Or(cmp.Compare(a.StorageClass, b.StorageClass), cmp.Compare(a.Path, b.Path))
+	})
+	if err := d.inventoryWriter.Update(volumes); err != nil {
+		klog.Errorf("Failed to update local volume inventory: %v", err)
+	}
+}
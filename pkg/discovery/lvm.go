@@ -0,0 +1,101 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+// ensureLogicalVolumes creates the logical volumes of the LVM configuration
+// of the storage class which are missing from its volume group, or records
+// them in the plan in dry run. It holds the volume lock so that the logical
+// volumes recreated by the deleter are not seen missing.
+func (d *Discoverer) ensureLogicalVolumes(class string, config common.MountConfig) error {
+	lvm := config.LVM
+	d.VolumeLock.Lock()
+	defer d.VolumeLock.Unlock()
+	vg, err := d.LVMUtil.GetVolumeGroup(lvm.VolumeGroup)
+	if err != nil {
+		return fmt.Errorf("volume group %q error: %v", lvm.VolumeGroup, err)
+	}
+	volumes, err := d.LVMUtil.ListLogicalVolumes(lvm.VolumeGroup)
+	if err != nil {
+		return fmt.Errorf("volume group %q logical volumes error: %v", lvm.VolumeGroup, err)
+	}
+	existing := map[string]int64{}
+	var existingSize int64
+	for _, lv := range volumes {
+		if strings.HasPrefix(lv.Name, common.NamePrefix) {
+			existing[lv.Name] = lv.SizeBytes
+			existingSize = max(existingSize, lv.SizeBytes)
+		}
+	}
+
+	size := logicalVolumeSize(lvm, vg.FreeBytes, vg.ExtentSizeBytes)
+	if lvm.Size == nil && existingSize > 0 {
+		// Recreate missing volumes with the size the free space was split into.
+		size = existingSize
+	}
+	if size <= 0 {
+		klog.Warningf("Not enough free space in volume group %q for %d logical volumes", lvm.VolumeGroup, lvm.Count)
+		return nil
+	}
+
+	free := vg.FreeBytes
+	for i := 1; lvm.Count == 0 || i <= lvm.Count; i++ {
+		name := fmt.Sprintf("%s%d", common.NamePrefix, i)
+		if _, ok := existing[name]; ok {
+			continue
+		}
+		if lvm.ThinPool == "" {
+			if free < size {
+				if lvm.Count > 0 {
+					klog.Warningf("Not enough free space in volume group %q for logical volume %q of %d bytes", lvm.VolumeGroup, name, size)
+				}
+				break
+			}
+			free -= size
+		}
+		if d.DryRun {
+			d.Plan.RecordVolume(class, filepath.Join(config.HostDir, name), size, fmt.Sprintf("logical volume missing from volume group %s", lvm.VolumeGroup))
+			continue
+		}
+		klog.Infof("Creating logical volume %q of %d bytes in volume group %q", name, size, lvm.VolumeGroup)
+		if err := d.LVMUtil.CreateLogicalVolume(lvm.VolumeGroup, name, size, lvm.ThinPool); err != nil {
+			return fmt.Errorf("failed to create logical volume %q in volume group %q: %v", name, lvm.VolumeGroup, err)
+		}
+	}
+	return nil
+}
+
+// logicalVolumeSize returns the size of the logical volumes to create,
+// either the configured size rounded up to the extent size, or the free
+// space of the volume group split into Count volumes rounded down to it.
+func logicalVolumeSize(lvm *common.LVMConfig, freeBytes, extentSizeBytes int64) int64 {
+	if extentSizeBytes <= 0 {
+		extentSizeBytes = 1
+	}
+	if lvm.Size != nil {
+		return (lvm.Size.Value() + extentSizeBytes - 1) / extentSizeBytes * extentSizeBytes
+	}
+	return freeBytes / int64(lvm.Count) / extentSizeBytes * extentSizeBytes
+}
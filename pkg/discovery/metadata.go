@@ -0,0 +1,126 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// volumeMetadataSuffix is the suffix of the metadata file of a volume, which
+// is named .<name>.pv.yaml after the entry of the volume.
+const volumeMetadataSuffix = ".pv.yaml"
+
+// reservedAnnotationPrefixes are the prefixes of the PV annotations managed by
+// Kubernetes or the provisioner, which cannot be set by a metadata file.
+var reservedAnnotationPrefixes = []string{
+	"pv.kubernetes.io/",
+	"volume.alpha.kubernetes.io/",
+	"local-static-provisioner.sigs.k8s.io/",
+}
+
+// volumeMetadata is the content of the metadata file of a volume, which
+// overrides the attributes of the PV created for it.
+type volumeMetadata struct {
+	// Labels are added to the labels of the PV.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are added to the annotations of the PV.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Capacity of the PV, at most the capacity of the volume.
+	Capacity *resource.Quantity `json:"capacity,omitempty"`
+	// MountOptions are added to the mount options of the storage class.
+	MountOptions []string `json:"mountOptions,omitempty"`
+	// ClaimRef pre-binds the PV to the claim.
+	ClaimRef *common.ClaimReference `json:"claimRef,omitempty"`
+	// Exclude skips the volume.
+	Exclude bool `json:"exclude,omitempty"`
+}
+
+// isVolumeMetadataFile returns true if file is the metadata file of a volume.
+func isVolumeMetadataFile(file string) bool {
+	return strings.HasPrefix(file, ".") && strings.HasSuffix(file, volumeMetadataSuffix)
+}
+
+// readVolumeMetadata reads and validates the metadata file of the volume at
+// file under config.MountDir, which is in the directory of the volume. Empty
+// metadata is returned if there is none.
+func (d *Discoverer) readVolumeMetadata(config common.MountConfig, file string) (*volumeMetadata, error) {
+	dir, name := filepath.Split(file)
+	metadataPath := filepath.Join(config.MountDir, dir, "."+name+volumeMetadataSuffix)
+	data, err := d.VolUtil.ReadFile(metadataPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &volumeMetadata{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading metadata file %q: %v", metadataPath, err)
+	}
+	metadata := &volumeMetadata{}
+	if err := yaml.UnmarshalStrict(data, metadata); err != nil {
+		return nil, fmt.Errorf("error parsing metadata file %q: %v", metadataPath, err)
+	}
+	if err := validateVolumeMetadata(metadata); err != nil {
+		return nil, fmt.Errorf("invalid metadata file %q: %v", metadataPath, err)
+	}
+	return metadata, nil
+}
+
+func validateVolumeMetadata(metadata *volumeMetadata) error {
+	allErrs := metav1validation.ValidateLabels(metadata.Labels, field.NewPath("labels"))
+	allErrs = append(allErrs, apivalidation.ValidateAnnotations(metadata.Annotations, field.NewPath("annotations"))...)
+	for key := range metadata.Annotations {
+		for _, prefix := range reservedAnnotationPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				allErrs = append(allErrs, field.Forbidden(field.NewPath("annotations").Key(key), "reserved annotation"))
+			}
+		}
+	}
+	if metadata.Capacity != nil && metadata.Capacity.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("capacity"), metadata.Capacity.String(), "must be greater than zero"))
+	}
+	if ref := metadata.ClaimRef; ref != nil {
+		for _, msg := range validation.IsDNS1123Label(ref.Namespace) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("claimRef", "namespace"), ref.Namespace, msg))
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(ref.Name) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("claimRef", "name"), ref.Name, msg))
+		}
+	}
+	return allErrs.ToAggregate()
+}
+
+// capacityByte returns the capacity of the PV of a volume of capacityByte.
+func (m *volumeMetadata) capacityByte(capacityByte int64) (int64, error) {
+	if m.Capacity == nil {
+		return capacityByte, nil
+	}
+	if m.Capacity.Value() > capacityByte {
+		return 0, fmt.Errorf("capacity %s of metadata file exceeds the capacity %d of the volume", m.Capacity.String(), capacityByte)
+	}
+	return m.Capacity.Value(), nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/metrics"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// checkMissingVolumes verifies that the volumes of the available and bound
+// PVs created by the provisioner still exist. The missing volumes of bound PVs
+// are reported with an annotation and an event on the PV, the available PVs
+// are either annotated the same way or deleted depending on the policy.
+func (d *Discoverer) checkMissingVolumes() {
+	mountPoints, err := d.RuntimeConfig.Mounter.List()
+	if err != nil {
+		klog.Errorf("Failed to check missing volumes, error retrieving mountpoints: %v", err)
+		return
+	}
+	mountPointMap := make(map[string]interface{})
+	for _, mp := range mountPoints {
+		mountPointMap[mp.Path] = struct{}{}
+	}
+
+	missingVolumes := map[v1.PersistentVolumeMode]int{}
+	for _, pv := range d.Cache.ListPVs() {
+		if pv.Spec.Local == nil || pv.DeletionTimestamp != nil {
+			continue
+		}
+		// Released PVs are cleaned up by the deleter.
+		if pv.Status.Phase != v1.VolumeAvailable && pv.Status.Phase != v1.VolumeBound {
+			continue
+		}
+		config, ok := d.DiscoveryMap[pv.Spec.StorageClassName]
+		if !ok || config.DynamicProvisioning {
+			continue
+		}
+		mountPath, err := common.GetContainerPath(pv, config)
+		if err != nil {
+			klog.Errorf("Failed to check volume of PV %q: %v", pv.Name, err)
+			continue
+		}
+		reason, err := d.missingVolumeReason(pv, config, mountPath, mountPointMap)
+		if err != nil {
+			klog.Warningf("Failed to check volume of PV %q at %q: %v", pv.Name, mountPath, err)
+			continue
+		}
+		if reason == "" {
+			if _, ok := pv.Annotations[common.AnnVolumeMissing]; ok {
+				d.annotateMissingVolume(pv, "")
+			}
+			continue
+		}
+
+		if pv.Status.Phase == v1.VolumeAvailable && pv.Spec.ClaimRef == nil && d.MissingVolumePolicy == common.MissingVolumePolicyDelete {
+			klog.Warningf("Deleting available PV %q, its volume is missing: %s", pv.Name, reason)
+			if err := d.APIUtil.DeletePV(pv.Name); err == nil || apierrors.IsNotFound(err) {
+				continue
+			}
+			klog.Errorf("Error deleting PV %q: %v", pv.Name, err)
+		}
+		mode := v1.PersistentVolumeFilesystem
+		if pv.Spec.VolumeMode != nil {
+			mode = *pv.Spec.VolumeMode
+		}
+		missingVolumes[mode]++
+		if pv.Annotations[common.AnnVolumeMissing] != reason {
+			d.annotateMissingVolume(pv, reason)
+		}
+	}
+
+	metrics.MissingVolumes.Reset()
+	for mode, count := range missingVolumes {
+		metrics.MissingVolumes.WithLabelValues(string(mode)).Set(float64(count))
+	}
+}
+
+// missingVolumeReason returns why the volume of the PV at mountPath is
+// missing, or an empty string if it is present. The volume must exist with the
+// volume mode of the PV, and directories must be mount points unless they
+// belong to a directory pool.
+func (d *Discoverer) missingVolumeReason(pv *v1.PersistentVolume, config common.MountConfig, mountPath string, mountPointMap map[string]interface{}) (string, error) {
+	// The volumes can't be checked if their discovery directory itself is
+	// not available.
+	files, err := d.VolUtil.ReadDir(filepath.Dir(mountPath))
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(files, filepath.Base(mountPath)) {
+		return fmt.Sprintf("path %q does not exist", pv.Spec.Local.Path), nil
+	}
+
+	isBlock, blockErr := d.VolUtil.IsBlock(mountPath)
+	if errors.Is(blockErr, fs.ErrNotExist) {
+		return fmt.Sprintf("target of path %q does not exist", pv.Spec.Local.Path), nil
+	}
+	if pv.Spec.VolumeMode != nil && *pv.Spec.VolumeMode == v1.PersistentVolumeBlock {
+		if blockErr != nil {
+			return "", blockErr
+		}
+		if !isBlock {
+			return fmt.Sprintf("path %q is not a block device", pv.Spec.Local.Path), nil
+		}
+		return "", nil
+	}
+	// Filesystem PVs may be backed by block devices formatted by kubelet.
+	if isBlock {
+		return "", nil
+	}
+	isDir, err := d.VolUtil.IsDir(mountPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Sprintf("target of path %q does not exist", pv.Spec.Local.Path), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !isDir {
+		return fmt.Sprintf("path %q is not a directory nor a block device", pv.Spec.Local.Path), nil
+	}
+	if config.DirectoryPool != nil {
+		return "", nil
+	}
+	if isMountPoint, _ := d.VolUtil.IsLikelyMountPoint(pv.Spec.Local.Path, mountPath, mountPointMap); !isMountPoint {
+		return fmt.Sprintf("path %q is not a mount point", pv.Spec.Local.Path), nil
+	}
+	return "", nil
+}
+
+// annotateMissingVolume sets the missing volume annotation of the PV to
+// reason, or removes it if reason is empty.
+func (d *Discoverer) annotateMissingVolume(pv *v1.PersistentVolume, reason string) {
+	newPV := pv.DeepCopy()
+	if reason == "" {
+		delete(newPV.Annotations, common.AnnVolumeMissing)
+	} else {
+		if newPV.Annotations == nil {
+			newPV.Annotations = map[string]string{}
+		}
+		newPV.Annotations[common.AnnVolumeMissing] = reason
+	}
+	updatedPV, err := d.APIUtil.UpdatePV(newPV)
+	if err != nil {
+		klog.Errorf("Error updating annotation %q of PV %q: %v", common.AnnVolumeMissing, pv.Name, err)
+		return
+	}
+	d.Cache.UpdatePV(updatedPV)
+	if reason == "" {
+		klog.Infof("Volume of PV %q at %q is present again", pv.Name, pv.Spec.Local.Path)
+		return
+	}
+	klog.Warningf("Volume of PV %q is missing: %s", pv.Name, reason)
+	d.Recorder.Eventf(pv, v1.EventTypeWarning, common.EventVolumeMissing, "Volume is missing: %s", reason)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
+)
+
+// partitionAlignment is the alignment of the partitions, which also leaves
+// room for the primary and backup GPT at the start and the end of the disk.
+const partitionAlignment = 1024 * 1024
+
+// partitionDisks partitions the unused whole disks among files as defined by
+// the partitioning policy of config, and returns files with the entries of
+// their partitions in config.MountDir added.
+func (d *Discoverer) partitionDisks(class string, config common.MountConfig, files []string) ([]string, []error) {
+	var errs []error
+	result := slices.Clone(files)
+	for _, file := range files {
+		if matched, err := matchNamePattern(config.NamePattern, file); err != nil || !matched {
+			continue
+		}
+		entries, err := d.partitionDisk(class, config, file)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, entry := range entries {
+			if !slices.Contains(result, entry) {
+				result = append(result, entry)
+			}
+		}
+	}
+	return result, errs
+}
+
+// partitionDisk partitions the disk at file if needed, and returns the names
+// of the entries of its partitions in config.MountDir. Partitions whose node
+// is not already in config.MountDir, e.g. when it is /dev, are linked there
+// as "<file>-part<number>".
+func (d *Discoverer) partitionDisk(class string, config common.MountConfig, file string) ([]string, error) {
+	filePath := filepath.Join(config.MountDir, file)
+	attrs, err := d.deviceAttributes(filePath)
+	if err != nil || attrs == nil || attrs.PartitionNumber > 0 {
+		return nil, err
+	}
+	if config.DeviceSelector != nil && !matchDeviceSelector(config.DeviceSelector, attrs) {
+		return nil, nil
+	}
+	// A disk which is already used as a whole, e.g. since before the
+	// partitioning policy was configured, is left alone.
+	if len(d.Cache.LookupPVsByPath(filepath.Join(config.HostDir, file))) > 0 {
+		return nil, nil
+	}
+	partitions, err := d.ensurePartitions(class, filepath.Join(config.HostDir, file), filePath, attrs.SizeBytes, config.Partitioning)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []string
+	for _, partition := range partitions {
+		entry := filepath.Base(partition.Node)
+		if filepath.Join(config.MountDir, entry) != partition.Node {
+			entry = fmt.Sprintf("%s-part%d", file, partition.Number)
+			if d.DryRun {
+				klog.Infof("Dry run, not linking partition %q of %q", partition.Path, filePath)
+				continue
+			}
+			if err := d.VolUtil.EnsureSymlink(filepath.Join(config.MountDir, entry), partition.Path); err != nil {
+				return entries, fmt.Errorf("failed to link partition %q of %q: %v", partition.Path, filePath, err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ensurePartitions returns the partitions created by the provisioner on the
+// disk at filePath, partitioning it first if it has no signatures at all.
+// Disks with any other partitions or signatures are never touched. In dry
+// run, the partitions of a disk which would be partitioned are recorded in
+// the plan of the storage class at the host path of the disk instead.
+func (d *Discoverer) ensurePartitions(class, hostPath, filePath string, sizeBytes int64, partitioning *common.Partitioning) ([]util.Partition, error) {
+	signatures, err := d.PartitionUtil.GetSignatures(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("path %q signatures error: %v", filePath, err)
+	}
+	if len(signatures) == 0 {
+		layout := partitionLayout(sizeBytes, partitioning)
+		if len(layout) == 0 {
+			klog.Warningf("Disk %q of %d bytes is too small to be partitioned", filePath, sizeBytes)
+			return nil, nil
+		}
+		if d.DryRun {
+			for _, partition := range layout {
+				d.Plan.RecordVolume(class, hostPath, partition.SizeBytes, fmt.Sprintf("partition %d of unused disk", partition.Number))
+			}
+			return nil, nil
+		}
+		klog.Infof("Partitioning disk %q into %d partitions of %d bytes", filePath, len(layout), layout[0].SizeBytes)
+		if err := d.PartitionUtil.CreatePartitions(filePath, layout); err != nil {
+			return nil, fmt.Errorf("path %q partitioning error: %v", filePath, err)
+		}
+	} else if !util.HasSignature(signatures, "gpt") {
+		klog.V(4).Infof("Not partitioning disk %q with signatures %v", filePath, signatures)
+		return nil, nil
+	}
+
+	partitions, err := d.PartitionUtil.ListPartitions(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("path %q partition table error: %v", filePath, err)
+	}
+	for _, partition := range partitions {
+		if !strings.HasPrefix(partition.Name, common.NamePrefix) {
+			klog.V(4).Infof("Not using disk %q with partition %d %q not created by the provisioner", filePath, partition.Number, partition.Name)
+			return nil, nil
+		}
+	}
+	return partitions, nil
+}
+
+// partitionLayout returns the partitions to create on a disk of sizeBytes,
+// either partitioning.Count partitions of equal size or as many partitions of
+// partitioning.Size as fit on the disk. Sizes are rounded down to the
+// partition alignment.
+func partitionLayout(sizeBytes int64, partitioning *common.Partitioning) []util.Partition {
+	usableBytes := sizeBytes - 2*partitionAlignment
+	var count int64
+	var size int64
+	if partitioning.Size != nil {
+		size = partitioning.Size.Value() / partitionAlignment * partitionAlignment
+		if size > 0 {
+			count = min(usableBytes/size, common.MaxPartitions)
+		}
+	} else {
+		count = int64(partitioning.Count)
+		if count > 0 {
+			size = usableBytes / count / partitionAlignment * partitionAlignment
+		}
+	}
+	if count <= 0 || size <= 0 {
+		return nil
+	}
+
+	layout := make([]util.Partition, count)
+	for i := range layout {
+		layout[i] = util.Partition{
+			Number:    i + 1,
+			Name:      fmt.Sprintf("%s%d", common.NamePrefix, i+1),
+			SizeBytes: size,
+		}
+	}
+	return layout
+}
+
+// matchPartition returns true if filePath is a partition created by the
+// provisioner.
+func (d *Discoverer) matchPartition(filePath string) (bool, error) {
+	attrs, err := d.deviceAttributes(filePath)
+	if err != nil || attrs == nil {
+		return false, err
+	}
+	return attrs.PartitionNumber > 0 && strings.HasPrefix(attrs.PartitionName, common.NamePrefix), nil
+}
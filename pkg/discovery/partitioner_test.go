@@ -0,0 +1,98 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+func TestPartitionLayout(t *testing.T) {
+	const mib = 1024 * 1024
+	size := func(s string) *resource.Quantity {
+		q := resource.MustParse(s)
+		return &q
+	}
+	tests := []struct {
+		name          string
+		sizeBytes     int64
+		partitioning  *common.Partitioning
+		expectedCount int
+		expectedSize  int64
+	}{
+		{
+			name:          "equal partitions",
+			sizeBytes:     2048 * mib,
+			partitioning:  &common.Partitioning{Count: 2},
+			expectedCount: 2,
+			expectedSize:  1023 * mib,
+		},
+		{
+			name:          "equal partitions rounded down to alignment",
+			sizeBytes:     1000*mib + 12345,
+			partitioning:  &common.Partitioning{Count: 3},
+			expectedCount: 3,
+			expectedSize:  332 * mib,
+		},
+		{
+			name:          "fixed size partitions",
+			sizeBytes:     1024 * mib,
+			partitioning:  &common.Partitioning{Size: size("300Mi")},
+			expectedCount: 3,
+			expectedSize:  300 * mib,
+		},
+		{
+			name:          "fixed size partitions limited to the partition table",
+			sizeBytes:     1024 * 1024 * mib,
+			partitioning:  &common.Partitioning{Size: size("1Mi")},
+			expectedCount: common.MaxPartitions,
+			expectedSize:  mib,
+		},
+		{
+			name:         "disk smaller than partition size",
+			sizeBytes:    100 * mib,
+			partitioning: &common.Partitioning{Size: size("100Mi")},
+		},
+		{
+			name:         "disk too small for count",
+			sizeBytes:    4 * mib,
+			partitioning: &common.Partitioning{Count: 4},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			layout := partitionLayout(test.sizeBytes, test.partitioning)
+			if len(layout) != test.expectedCount {
+				t.Fatalf("Expected %d partitions, got %d", test.expectedCount, len(layout))
+			}
+			for i, partition := range layout {
+				if partition.Number != i+1 {
+					t.Errorf("Expected partition number %d, got %d", i+1, partition.Number)
+				}
+				if partition.SizeBytes != test.expectedSize {
+					t.Errorf("Expected partition %d of %d bytes, got %d", partition.Number, test.expectedSize, partition.SizeBytes)
+				}
+				if expected := common.NamePrefix + fmt.Sprint(i+1); partition.Name != expected {
+					t.Errorf("Expected partition name %q, got %q", expected, partition.Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// reservationKey identifies a volume of a storage class by its host path.
+type reservationKey struct {
+	class string
+	path  string
+}
+
+// nodeReservations returns the claims the volumes of node are reserved for.
+func nodeReservations(node string, reservations []common.Reservation) map[reservationKey]common.ClaimReference {
+	claims := map[reservationKey]common.ClaimReference{}
+	for _, r := range reservations {
+		if r.Node == node {
+			claims[reservationKey{class: r.StorageClass, path: r.Path}] = r.Claim
+		}
+	}
+	return claims
+}
+
+// reservedClaim returns the claim the volume at outsidePath of class is
+// reserved for, nil if it is not reserved.
+func (d *Discoverer) reservedClaim(class, outsidePath string) *common.ClaimReference {
+	claim, ok := d.reservations[reservationKey{class: class, path: outsidePath}]
+	if !ok {
+		return nil
+	}
+	return &claim
+}
+
+// enforceReservation pre-binds the available PV of a reserved volume to the
+// claim it is reserved for, so that the volume is not handed to another
+// claim. A PV which is already claimed by another claim is left alone and the
+// conflict is reported once.
+func (d *Discoverer) enforceReservation(pv *v1.PersistentVolume, claim *common.ClaimReference) {
+	if ref := pv.Spec.ClaimRef; ref != nil {
+		if ref.Namespace == claim.Namespace && ref.Name == claim.Name {
+			delete(d.reservationConflicts, pv.Name)
+			return
+		}
+		if d.reservationConflicts[pv.Name] {
+			return
+		}
+		d.reservationConflicts[pv.Name] = true
+		klog.Warningf("PV %q is reserved for claim %s/%s, but claimed by %s/%s", pv.Name, claim.Namespace, claim.Name, ref.Namespace, ref.Name)
+		d.Recorder.Eventf(pv, v1.EventTypeWarning, common.EventVolumeReservationConflict, "Volume at %q is reserved for claim %s/%s, but claimed by %s/%s", pv.Spec.Local.Path, claim.Namespace, claim.Name, ref.Namespace, ref.Name)
+		return
+	}
+	if pv.Status.Phase != v1.VolumeAvailable || pv.DeletionTimestamp != nil {
+		return
+	}
+
+	newPV := pv.DeepCopy()
+	newPV.Spec.ClaimRef = claim.ObjectReference()
+	updatedPV, err := d.APIUtil.UpdatePV(newPV)
+	if err != nil {
+		klog.Errorf("Error pre-binding PV %q to claim %s/%s: %v", pv.Name, claim.Namespace, claim.Name, err)
+		return
+	}
+	d.Cache.UpdatePV(updatedPV)
+	klog.Infof("Pre-bound PV %q to claim %s/%s", pv.Name, claim.Namespace, claim.Name)
+	d.Recorder.Eventf(pv, v1.EventTypeNormal, common.EventVolumeReserved, "Volume at %q is reserved for claim %s/%s", pv.Spec.Local.Path, claim.Namespace, claim.Name)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+// PathEvent notifies the discoverer that the entry File, relative to the
+// MountDir of storage class Class, may have become a new local volume, or that
+// the block device backing it was removed.
+type PathEvent struct {
+	Class string
+	File  string
+	// Device is the block device which triggered the event, if any.
+	Device string
+	// Removed is true if Device was removed from the node.
+	Removed bool
+}
+
+// PathWatcher watches the discovery directories of the configured storage
+// classes and the mount table of the node, and reports entries that may need
+// to be discovered.
+type PathWatcher interface {
+	// Events returns the channel on which path events are delivered.
+	Events() <-chan PathEvent
+	// Stop stops watching and releases all resources held by the watcher.
+	Stop()
+}
+
+// pathEventBufferSize is the number of path events that can be queued before
+// the watcher blocks waiting for the discoverer.
+const pathEventBufferSize = 1024
+
+// DrainPathEvents returns first together with any events that are already
+// queued on events, so that a burst of changes is discovered in one pass.
+func DrainPathEvents(first PathEvent, events <-chan PathEvent) []PathEvent {
+	batch := []PathEvent{first}
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, event)
+		default:
+			return batch
+		}
+	}
+}
@@ -0,0 +1,366 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/mount"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+const (
+	// procMountInfo is polled for changes of the mount table. The kernel
+	// reports POLLPRI on it whenever a mount is added or removed in the
+	// mount namespace of the provisioner.
+	procMountInfo = "/proc/self/mountinfo"
+
+	// inotifyMask selects the events that may create a new volume entry, and
+	// the directories moved away whose watches must be dropped.
+	inotifyMask = unix.IN_CREATE | unix.IN_MOVED_TO | unix.IN_ATTRIB | unix.IN_MOVED_FROM
+)
+
+// watchTarget is a directory watched for a storage class, with its path
+// relative to the MountDir of the class, empty for MountDir itself.
+type watchTarget struct {
+	class string
+	dir   string
+}
+
+var _ PathWatcher = &inotifyWatcher{}
+
+// inotifyWatcher is a PathWatcher based on inotify for the discovery directories
+// and on mountinfo change notifications for the mount table.
+type inotifyWatcher struct {
+	discoveryMap map[string]common.MountConfig
+	mounter      mount.Interface
+
+	epollFd   int
+	inotifyFd int
+	stopFd    int
+	mountInfo *os.File
+
+	// watches maps an inotify watch descriptor to the storage classes and
+	// directories watched with it. The directories which are not mount points
+	// are watched down to the MaxDepth of their storage class.
+	watches     map[int32][]watchTarget
+	filters     map[string]*entryFilter
+	mountPoints sets.Set[string]
+
+	events   chan PathEvent
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewPathWatcher returns a PathWatcher for the MountDir of every storage class in
+// discoveryMap.
+func NewPathWatcher(discoveryMap map[string]common.MountConfig, mounter mount.Interface) (PathWatcher, error) {
+	w := &inotifyWatcher{
+		discoveryMap: discoveryMap,
+		mounter:      mounter,
+		epollFd:      -1,
+		inotifyFd:    -1,
+		stopFd:       -1,
+		watches:      map[int32][]watchTarget{},
+		filters:      map[string]*entryFilter{},
+		events:       make(chan PathEvent, pathEventBufferSize),
+		done:         make(chan struct{}),
+	}
+	if err := w.init(); err != nil {
+		w.close()
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *inotifyWatcher) init() error {
+	var err error
+	if w.epollFd, err = unix.EpollCreate1(unix.EPOLL_CLOEXEC); err != nil {
+		return fmt.Errorf("failed to create epoll instance: %v", err)
+	}
+	if w.inotifyFd, err = unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK); err != nil {
+		return fmt.Errorf("failed to create inotify instance: %v", err)
+	}
+	if w.stopFd, err = unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK); err != nil {
+		return fmt.Errorf("failed to create eventfd: %v", err)
+	}
+	if w.mountInfo, err = os.Open(procMountInfo); err != nil {
+		return fmt.Errorf("failed to open %s: %v", procMountInfo, err)
+	}
+
+	if w.mountPoints, err = w.listMountPoints(); err != nil {
+		return err
+	}
+
+	for class, config := range w.discoveryMap {
+		if w.filters[class], err = newEntryFilter(config); err != nil {
+			return err
+		}
+		if err := w.addWatches(class, config, ""); err != nil {
+			return fmt.Errorf("failed to watch %q for storage class %q: %v", config.MountDir, class, err)
+		}
+	}
+
+	for _, reg := range []struct {
+		fd     int
+		events uint32
+	}{
+		{w.stopFd, unix.EPOLLIN},
+		{w.inotifyFd, unix.EPOLLIN},
+		{int(w.mountInfo.Fd()), unix.EPOLLPRI | unix.EPOLLERR},
+	} {
+		event := unix.EpollEvent{Events: reg.events, Fd: int32(reg.fd)}
+		if err := unix.EpollCtl(w.epollFd, unix.EPOLL_CTL_ADD, reg.fd, &event); err != nil {
+			return fmt.Errorf("failed to register fd %d with epoll: %v", reg.fd, err)
+		}
+	}
+	return nil
+}
+
+// addWatches watches the directory dir of the storage class, and the
+// directories under it which are not mount points nor excluded down to the
+// MaxDepth of the class.
+func (w *inotifyWatcher) addWatches(class string, config common.MountConfig, dir string) error {
+	dirPath := filepath.Join(config.MountDir, dir)
+	wd, err := unix.InotifyAddWatch(w.inotifyFd, dirPath, inotifyMask)
+	if err != nil {
+		return err
+	}
+	target := watchTarget{class: class, dir: dir}
+	if !slices.Contains(w.watches[int32(wd)], target) {
+		w.watches[int32(wd)] = append(w.watches[int32(wd)], target)
+	}
+	depth := 0
+	if dir != "" {
+		depth = entryDepth(dir)
+	}
+	if depth+1 >= config.MaxDepth {
+		// The entries of dir are at the MaxDepth of the class.
+		return nil
+	}
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			w.addNestedWatches(class, config, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return nil
+}
+
+// addNestedWatches watches the nested directory dir of the storage class if
+// it is within its MaxDepth and is neither a mount point, which is a volume,
+// nor excluded.
+func (w *inotifyWatcher) addNestedWatches(class string, config common.MountConfig, dir string) {
+	if entryDepth(dir) >= config.MaxDepth || w.mountPoints.Has(filepath.Join(config.MountDir, dir)) || w.filters[class].excluded(dir) != "" {
+		return
+	}
+	if err := w.addWatches(class, config, dir); err != nil {
+		klog.Errorf("Path watcher failed to watch %q for storage class %q: %v", filepath.Join(config.MountDir, dir), class, err)
+	}
+}
+
+// removeWatches stops watching the directory dir of the storage class and
+// the directories under it.
+func (w *inotifyWatcher) removeWatches(class, dir string) {
+	for wd, targets := range w.watches {
+		targets = slices.DeleteFunc(targets, func(target watchTarget) bool {
+			return target.class == class && (target.dir == dir || strings.HasPrefix(target.dir, dir+"/"))
+		})
+		if len(targets) > 0 {
+			w.watches[wd] = targets
+			continue
+		}
+		delete(w.watches, wd)
+		if _, err := unix.InotifyRmWatch(w.inotifyFd, uint32(wd)); err != nil {
+			klog.V(4).Infof("Path watcher failed to remove watch %d: %v", wd, err)
+		}
+	}
+}
+
+// Events returns the channel on which path events are delivered.
+func (w *inotifyWatcher) Events() <-chan PathEvent {
+	return w.events
+}
+
+// Stop stops watching and releases all resources held by the watcher.
+func (w *inotifyWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		var one uint64 = 1
+		if _, err := unix.Write(w.stopFd, (*[8]byte)(unsafe.Pointer(&one))[:]); err != nil {
+			klog.Errorf("Failed to signal path watcher to stop: %v", err)
+		}
+	})
+}
+
+func (w *inotifyWatcher) close() {
+	for _, fd := range []int{w.epollFd, w.inotifyFd, w.stopFd} {
+		if fd >= 0 {
+			unix.Close(fd)
+		}
+	}
+	if w.mountInfo != nil {
+		w.mountInfo.Close()
+	}
+}
+
+func (w *inotifyWatcher) run() {
+	defer w.close()
+	events := make([]unix.EpollEvent, 3)
+	for {
+		n, err := unix.EpollWait(w.epollFd, events, -1)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			klog.Errorf("Path watcher stopped, epoll_wait failed: %v", err)
+			return
+		}
+		for _, event := range events[:n] {
+			switch int(event.Fd) {
+			case w.stopFd:
+				return
+			case w.inotifyFd:
+				w.handleInotify()
+			default:
+				w.handleMountChange()
+			}
+		}
+	}
+}
+
+func (w *inotifyWatcher) handleInotify() {
+	var buf [unix.SizeofInotifyEvent * 256]byte
+	for {
+		n, err := unix.Read(w.inotifyFd, buf[:])
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil || n <= 0 {
+			// EAGAIN: the queue has been drained.
+			return
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(raw.Len)]
+			offset += unix.SizeofInotifyEvent + int(raw.Len)
+
+			if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+				klog.Warningf("Path watcher event queue overflowed, changes will be picked up by the next resync")
+				continue
+			}
+			if raw.Mask&unix.IN_IGNORED != 0 {
+				// The watched directory was removed or unmounted.
+				delete(w.watches, raw.Wd)
+				continue
+			}
+			name := string(nameBytes)
+			for i, c := range nameBytes {
+				if c == 0 {
+					name = string(nameBytes[:i])
+					break
+				}
+			}
+			if name == "" {
+				continue
+			}
+			for _, target := range slices.Clone(w.watches[raw.Wd]) {
+				w.handleEntry(target, name, raw.Mask)
+			}
+		}
+	}
+}
+
+// handleEntry reports the entry name of the watched directory of target, and
+// updates the watches of the directories moved in or out of it.
+func (w *inotifyWatcher) handleEntry(target watchTarget, name string, mask uint32) {
+	config, ok := w.discoveryMap[target.class]
+	if !ok {
+		return
+	}
+	file := filepath.Join(target.dir, name)
+	if mask&unix.IN_MOVED_FROM != 0 {
+		if mask&unix.IN_ISDIR != 0 {
+			w.removeWatches(target.class, file)
+		}
+		return
+	}
+	if mask&unix.IN_ISDIR != 0 && mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+		w.addNestedWatches(target.class, config, file)
+	}
+	w.send(PathEvent{Class: target.class, File: file})
+}
+
+// handleMountChange reports the new mount points under a discovery directory,
+// within the MaxDepth of its storage class, with their path relative to it.
+func (w *inotifyWatcher) handleMountChange() {
+	mountPoints, err := w.listMountPoints()
+	if err != nil {
+		klog.Errorf("Path watcher failed to list mount points: %v", err)
+		return
+	}
+	added := mountPoints.Difference(w.mountPoints)
+	w.mountPoints = mountPoints
+	for _, mp := range sets.List(added) {
+		for class, config := range w.discoveryMap {
+			file, err := filepath.Rel(filepath.Clean(config.MountDir), filepath.Clean(mp))
+			if err != nil || file == "." || file == ".." || strings.HasPrefix(file, "../") {
+				continue
+			}
+			if entryDepth(file) <= max(config.MaxDepth, 1) {
+				w.send(PathEvent{Class: class, File: file})
+			}
+		}
+	}
+}
+
+func (w *inotifyWatcher) listMountPoints() (sets.Set[string], error) {
+	mountPoints, err := w.mounter.List()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving mountpoints: %v", err)
+	}
+	paths := sets.New[string]()
+	for _, mp := range mountPoints {
+		paths.Insert(mp.Path)
+	}
+	return paths, nil
+}
+
+func (w *inotifyWatcher) send(event PathEvent) {
+	klog.V(5).Infof("Path watcher observed %q for storage class %q", event.File, event.Class)
+	select {
+	case w.events <- event:
+	case <-w.done:
+	}
+}
@@ -0,0 +1,160 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/mount"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+)
+
+func TestPathWatcher_NewEntry(t *testing.T) {
+	mountDir := t.TempDir()
+	discoveryMap := map[string]common.MountConfig{
+		"sc1": {HostDir: "/mnt/disks", MountDir: mountDir},
+	}
+	w, err := NewPathWatcher(discoveryMap, &mount.FakeMounter{})
+	if err != nil {
+		t.Fatalf("Error creating path watcher: %v", err)
+	}
+	defer w.Stop()
+
+	if err := os.Symlink("/dev/null", filepath.Join(mountDir, "symlink1")); err != nil {
+		t.Fatalf("Error creating symlink: %v", err)
+	}
+
+	select {
+	case event := <-w.Events():
+		expected := PathEvent{Class: "sc1", File: "symlink1"}
+		if event != expected {
+			t.Errorf("Expected event %+v, got %+v", expected, event)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Timed out waiting for path event")
+	}
+}
+
+func TestPathWatcher_NestedEntries(t *testing.T) {
+	mountDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(mountDir, "rack1"), 0755); err != nil {
+		t.Fatalf("Error creating directory: %v", err)
+	}
+	discoveryMap := map[string]common.MountConfig{
+		"sc1": {HostDir: "/mnt/disks", MountDir: mountDir, MaxDepth: 2},
+	}
+	w, err := NewPathWatcher(discoveryMap, &mount.FakeMounter{})
+	if err != nil {
+		t.Fatalf("Error creating path watcher: %v", err)
+	}
+	defer w.Stop()
+
+	expectEvent := func(file string) {
+		t.Helper()
+		select {
+		case event := <-w.Events():
+			expected := PathEvent{Class: "sc1", File: file}
+			if event != expected {
+				t.Errorf("Expected event %+v, got %+v", expected, event)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Timed out waiting for path event of %q", file)
+		}
+	}
+	path := func(file string) string {
+		return filepath.Join(mountDir, file)
+	}
+	check := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Entries of existing directories.
+	check(os.Symlink("/dev/null", path("rack1/slot1")))
+	expectEvent("rack1/slot1")
+	// New directories are watched too, but not below MaxDepth.
+	check(os.Mkdir(path("rack2"), 0755))
+	expectEvent("rack2")
+	check(os.Mkdir(path("rack2/slot1"), 0755))
+	expectEvent("rack2/slot1")
+	check(os.Symlink("/dev/null", path("rack2/slot1/entry")))
+	// Moved directories are watched at their new path.
+	check(os.Rename(path("rack2"), path("rack3")))
+	expectEvent("rack3")
+	check(os.Symlink("/dev/null", path("rack3/slot2")))
+	expectEvent("rack3/slot2")
+	select {
+	case event := <-w.Events():
+		t.Errorf("Unexpected event %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPathWatcher_MissingDir(t *testing.T) {
+	discoveryMap := map[string]common.MountConfig{
+		"sc1": {HostDir: "/mnt/disks", MountDir: filepath.Join(t.TempDir(), "does-not-exist")},
+	}
+	if _, err := NewPathWatcher(discoveryMap, &mount.FakeMounter{}); err == nil {
+		t.Errorf("Expected error watching a missing directory")
+	}
+}
+
+func TestPathWatcher_NestedMountPoints(t *testing.T) {
+	w := &inotifyWatcher{
+		discoveryMap: map[string]common.MountConfig{
+			"sc1": {HostDir: "/mnt/disks", MountDir: "/mnt/disks"},
+			"sc2": {HostDir: "/mnt/racks", MountDir: "/mnt/racks", MaxDepth: 2},
+		},
+		mounter: &mount.FakeMounter{MountPoints: []mount.MountPoint{
+			{Path: "/mnt/disks/vol1"},
+			// Deeper than the MaxDepth of its class.
+			{Path: "/mnt/disks/dir/vol2"},
+			{Path: "/mnt/racks/rack1/slot1"},
+			{Path: "/mnt/racks/rack1/slot1/dir/vol3"},
+			{Path: "/mnt/racks"},
+			{Path: "/mnt/other/vol4"},
+		}},
+		mountPoints: sets.New[string](),
+		events:      make(chan PathEvent, pathEventBufferSize),
+		done:        make(chan struct{}),
+	}
+	w.handleMountChange()
+	close(w.events)
+
+	var events []PathEvent
+	for event := range w.events {
+		events = append(events, event)
+	}
+	expected := []PathEvent{
+		{Class: "sc1", File: "vol1"},
+		{Class: "sc2", File: "rack1/slot1"},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected events %+v, got %+v", expected, events)
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dryrun records the changes the provisioner would make to the PVs
+// and to the disks instead of making them.
+package dryrun
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/cache"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
+
+	batch_v1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// ActionCreate is the creation of a PV for a discovered volume.
+	ActionCreate = "create"
+	// ActionUpdate is the update of an existing PV.
+	ActionUpdate = "update"
+	// ActionDelete is the deletion of a PV, after the cleanup of its volume
+	// for released PVs.
+	ActionDelete = "delete"
+	// ActionCreateVolume is the creation of a volume on the disks, e.g. a
+	// logical volume, a directory of a directory pool or a partition, whose
+	// PV would be created once the volume exists.
+	ActionCreateVolume = "create-volume"
+)
+
+// Change is a change the provisioner would make to a PV or to the disks.
+type Change struct {
+	Action           string `json:"action"`
+	PersistentVolume string `json:"persistentVolume,omitempty"`
+	StorageClass     string `json:"storageClass,omitempty"`
+	Path             string `json:"path,omitempty"`
+	Capacity         string `json:"capacity,omitempty"`
+	Reason           string `json:"reason"`
+}
+
+// Plan is the list of changes the provisioner would make to the PVs and to
+// the disks.
+type Plan struct {
+	mutex   sync.Mutex
+	changes []Change
+}
+
+// Changes returns the changes recorded so far.
+func (p *Plan) Changes() []Change {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]Change(nil), p.changes...)
+}
+
+func (p *Plan) record(pv *v1.PersistentVolume, action, reason string) {
+	change := Change{
+		Action:           action,
+		PersistentVolume: pv.Name,
+		StorageClass:     pv.Spec.StorageClassName,
+		Reason:           reason,
+	}
+	if pv.Spec.Local != nil {
+		change.Path = pv.Spec.Local.Path
+	}
+	if capacity, ok := pv.Spec.Capacity[v1.ResourceStorage]; ok {
+		change.Capacity = capacity.String()
+	}
+	if p.add(change) {
+		klog.Infof("Dry run, would %s PV %q: %s", action, pv.Name, reason)
+	}
+}
+
+// RecordVolume records the creation of a volume of sizeBytes for the storage
+// class at path.
+func (p *Plan) RecordVolume(class, path string, sizeBytes int64, reason string) {
+	change := Change{
+		Action:       ActionCreateVolume,
+		StorageClass: class,
+		Path:         path,
+		Capacity:     resource.NewQuantity(sizeBytes, resource.BinarySI).String(),
+		Reason:       reason,
+	}
+	if p.add(change) {
+		klog.Infof("Dry run, would create volume %q: %s", path, reason)
+	}
+}
+
+// add appends change to the plan, or replaces the change with the same action
+// on the same PV and path recorded by a previous loop, and returns true if it
+// was appended.
+func (p *Plan) add(change Change) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for i, recorded := range p.changes {
+		if recorded.Action == change.Action && recorded.PersistentVolume == change.PersistentVolume && recorded.Path == change.Path {
+			p.changes[i] = change
+			return false
+		}
+	}
+	p.changes = append(p.changes, change)
+	return true
+}
+
+var _ util.APIUtil = &apiUtil{}
+
+// apiUtil records the changes to the PVs in a plan instead of sending them to
+// the API server. The previous state of the PVs is looked up in the cache.
+type apiUtil struct {
+	cache *cache.VolumeCache
+	plan  *Plan
+}
+
+// NewAPIUtil creates an APIUtil which records the changes to the PVs in plan.
+func NewAPIUtil(cache *cache.VolumeCache, plan *Plan) util.APIUtil {
+	return &apiUtil{cache: cache, plan: plan}
+}
+
+// CreatePV records the creation of the PV.
+func (u *apiUtil) CreatePV(pv *v1.PersistentVolume) (*v1.PersistentVolume, error) {
+	reason := "new volume discovered"
+	if ref := pv.Spec.ClaimRef; ref != nil {
+		reason = fmt.Sprintf("%s, pre-bound to claim %s/%s", reason, ref.Namespace, ref.Name)
+	}
+	u.plan.record(pv, ActionCreate, reason)
+	return pv, nil
+}
+
+// UpdatePV records the update of the PV.
+func (u *apiUtil) UpdatePV(pv *v1.PersistentVolume) (*v1.PersistentVolume, error) {
+	oldPV, exists := u.cache.GetPV(pv.Name)
+	if !exists {
+		oldPV = &v1.PersistentVolume{}
+	}
+	u.plan.record(pv, ActionUpdate, describeUpdate(oldPV, pv))
+	return pv, nil
+}
+
+// DeletePV records the deletion of the PV.
+func (u *apiUtil) DeletePV(pvName string) error {
+	pv, exists := u.cache.GetPV(pvName)
+	if !exists {
+		pv = &v1.PersistentVolume{}
+		pv.Name = pvName
+	}
+	reason := "volume is missing"
+	if pv.Status.Phase == v1.VolumeReleased {
+		reason = fmt.Sprintf("released with reclaim policy %s, its volume would be cleaned first", pv.Spec.PersistentVolumeReclaimPolicy)
+	}
+	u.plan.record(pv, ActionDelete, reason)
+	return nil
+}
+
+// CreateJob fails, cleanup jobs are never created in dry run.
+func (u *apiUtil) CreateJob(job *batch_v1.Job) error {
+	return fmt.Errorf("cannot create job %s/%s in dry run", job.Namespace, job.Name)
+}
+
+// DeleteJob fails, cleanup jobs are never deleted in dry run.
+func (u *apiUtil) DeleteJob(jobName string, namespace string) error {
+	return fmt.Errorf("cannot delete job %s/%s in dry run", namespace, jobName)
+}
+
+// describeUpdate returns the reasons of the changes from oldPV to newPV.
+func describeUpdate(oldPV, newPV *v1.PersistentVolume) string {
+	var reasons []string
+	oldReason, oldMissing := oldPV.Annotations[common.AnnVolumeMissing]
+	newReason, newMissing := newPV.Annotations[common.AnnVolumeMissing]
+	switch {
+	case newMissing && newReason != oldReason:
+		reasons = append(reasons, fmt.Sprintf("volume is missing: %s", newReason))
+	case oldMissing && !newMissing:
+		reasons = append(reasons, "volume is present again")
+	}
+	oldCapacity := oldPV.Spec.Capacity[v1.ResourceStorage]
+	newCapacity := newPV.Spec.Capacity[v1.ResourceStorage]
+	if !oldCapacity.Equal(newCapacity) {
+		reasons = append(reasons, fmt.Sprintf("capacity changes from %s to %s", oldCapacity.String(), newCapacity.String()))
+	}
+	if ref := newPV.Spec.ClaimRef; ref != nil && oldPV.Spec.ClaimRef == nil {
+		reasons = append(reasons, fmt.Sprintf("pre-bound to claim %s/%s", ref.Namespace, ref.Name))
+	}
+	if len(reasons) == 0 {
+		return "PV changes"
+	}
+	return strings.Join(reasons, ", ")
+}
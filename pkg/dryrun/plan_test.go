@@ -0,0 +1,142 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/cache"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+
+	batch_v1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func newPV(name string, phase v1.PersistentVolumePhase, capacity string) *v1.PersistentVolume {
+	pv := common.CreateLocalPVSpec(&common.LocalPVConfig{
+		Name:          name,
+		HostPath:      "/mnt/disks/" + name,
+		StorageClass:  "sc1",
+		ReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+	})
+	pv.Spec.Capacity = v1.ResourceList{v1.ResourceStorage: resource.MustParse(capacity)}
+	pv.Status.Phase = phase
+	return pv
+}
+
+func TestAPIUtil(t *testing.T) {
+	volumeCache := cache.NewVolumeCache()
+	available := newPV("pv1", v1.VolumeAvailable, "10Gi")
+	released := newPV("pv2", v1.VolumeReleased, "10Gi")
+	missing := newPV("pv3", v1.VolumeAvailable, "10Gi")
+	volumeCache.AddPV(available)
+	volumeCache.AddPV(released)
+	volumeCache.AddPV(missing)
+	plan := &Plan{}
+	apiUtil := NewAPIUtil(volumeCache, plan)
+
+	created := newPV("pv4", v1.VolumeAvailable, "20Gi")
+	created.Spec.ClaimRef = &v1.ObjectReference{Namespace: "ns1", Name: "claim1"}
+	if _, err := apiUtil.CreatePV(created); err != nil {
+		t.Fatalf("Unexpected error creating PV: %v", err)
+	}
+	updated := available.DeepCopy()
+	updated.Spec.Capacity[v1.ResourceStorage] = resource.MustParse("12Gi")
+	updated.Annotations[common.AnnVolumeMissing] = "path \"/mnt/disks/pv1\" is not a mount point"
+	if _, err := apiUtil.UpdatePV(updated); err != nil {
+		t.Fatalf("Unexpected error updating PV: %v", err)
+	}
+	for _, pvName := range []string{"pv2", "pv3"} {
+		if err := apiUtil.DeletePV(pvName); err != nil {
+			t.Fatalf("Unexpected error deleting PV: %v", err)
+		}
+	}
+
+	expected := []Change{
+		{
+			Action:           ActionCreate,
+			PersistentVolume: "pv4",
+			StorageClass:     "sc1",
+			Path:             "/mnt/disks/pv4",
+			Capacity:         "20Gi",
+			Reason:           "new volume discovered, pre-bound to claim ns1/claim1",
+		},
+		{
+			Action:           ActionUpdate,
+			PersistentVolume: "pv1",
+			StorageClass:     "sc1",
+			Path:             "/mnt/disks/pv1",
+			Capacity:         "12Gi",
+			Reason:           "volume is missing: path \"/mnt/disks/pv1\" is not a mount point, capacity changes from 10Gi to 12Gi",
+		},
+		{
+			Action:           ActionDelete,
+			PersistentVolume: "pv2",
+			StorageClass:     "sc1",
+			Path:             "/mnt/disks/pv2",
+			Capacity:         "10Gi",
+			Reason:           "released with reclaim policy Delete, its volume would be cleaned first",
+		},
+		{
+			Action:           ActionDelete,
+			PersistentVolume: "pv3",
+			StorageClass:     "sc1",
+			Path:             "/mnt/disks/pv3",
+			Capacity:         "10Gi",
+			Reason:           "volume is missing",
+		},
+	}
+	if changes := plan.Changes(); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected changes %+v, got %+v", expected, changes)
+	}
+	if pv, _ := volumeCache.GetPV("pv1"); pv != available {
+		t.Errorf("Expected PV in cache to be unchanged")
+	}
+	if err := apiUtil.CreateJob(&batch_v1.Job{}); err == nil {
+		t.Errorf("Expected creating a job to fail in dry run")
+	}
+}
+
+func TestPlan_RecordVolume(t *testing.T) {
+	plan := &Plan{}
+	// The partitions of a disk are planned again by each loop.
+	for i := 0; i < 2; i++ {
+		plan.RecordVolume("sc1", "/mnt/disks/sda1", 10<<30, "partition 1 of unused disk")
+		plan.RecordVolume("sc1", "/mnt/disks/sda2", 20<<30, "partition 2 of unused disk")
+	}
+	expected := []Change{
+		{
+			Action:       ActionCreateVolume,
+			StorageClass: "sc1",
+			Path:         "/mnt/disks/sda1",
+			Capacity:     "10Gi",
+			Reason:       "partition 1 of unused disk",
+		},
+		{
+			Action:       ActionCreateVolume,
+			StorageClass: "sc1",
+			Path:         "/mnt/disks/sda2",
+			Capacity:     "20Gi",
+			Reason:       "partition 2 of unused disk",
+		},
+	}
+	if changes := plan.Changes(); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected changes %+v, got %+v", expected, changes)
+	}
+}
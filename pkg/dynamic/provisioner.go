@@ -0,0 +1,416 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamic
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-lib-external-provisioner/v6/controller"
+	esUtil "sigs.k8s.io/sig-storage-lib-external-provisioner/v6/util"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/mount"
+)
+
+// annSelectedNode is the claim annotation with the node selected by the
+// scheduler for claims of WaitForFirstConsumer storage classes.
+const annSelectedNode = "volume.kubernetes.io/selected-node"
+
+var _ controller.Provisioner = &Provisioner{}
+var _ controller.Qualifier = &Provisioner{}
+var _ controller.DeletionGuard = &Provisioner{}
+var _ controller.BlockProvisioner = &Provisioner{}
+
+// Provisioner creates the volumes of the storage classes with dynamic
+// provisioning for the claims which selected this node, and deletes them
+// when they are released.
+type Provisioner struct {
+	*common.RuntimeConfig
+	// Labels added to the PVs
+	labels map[string]string
+	// Value of the node label used for the node affinity of the PVs
+	nodeValue string
+	// Node selector of the node affinity of the PVs
+	nodeSelector *v1.NodeSelector
+}
+
+// DynamicProvisioningConfigured returns true if any storage class of the
+// discovery map uses dynamic provisioning.
+func DynamicProvisioningConfigured(discoveryMap map[string]common.MountConfig) bool {
+	for _, config := range discoveryMap {
+		if config.DynamicProvisioning {
+			return true
+		}
+	}
+	return false
+}
+
+// NewProvisioner creates a Provisioner for the node of the runtime config.
+func NewProvisioner(config *common.RuntimeConfig) (*Provisioner, error) {
+	nodeSelector, err := common.GenerateNodeSelector(config.Node, config.NodeLabelsForPVAffinity)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{}
+	for _, labelName := range config.NodeLabelsForPV {
+		if labelValue, ok := config.Node.Labels[labelName]; ok {
+			labels[labelName] = labelValue
+		}
+	}
+	for labelName, labelValue := range config.LabelsForPV {
+		labels[labelName] = labelValue
+	}
+
+	return &Provisioner{
+		RuntimeConfig: config,
+		labels:        labels,
+		nodeValue:     config.Node.Labels[common.NodeLabelKey],
+		nodeSelector:  nodeSelector,
+	}, nil
+}
+
+// NewController returns the controller calling the provisioner for the
+// claims of the storage classes whose provisioner is
+// common.DynamicProvisionerName.
+func NewController(client kubernetes.Interface, provisioner *Provisioner, kubeVersion string) *controller.ProvisionController {
+	return controller.NewProvisionController(client, common.DynamicProvisionerName, provisioner, kubeVersion,
+		// The provisioners of all nodes run at the same time, each one only
+		// serving the claims which selected its node.
+		controller.LeaderElection(false))
+}
+
+// ShouldProvision returns true for the claims of storage classes with
+// dynamic provisioning which selected this node.
+func (p *Provisioner) ShouldProvision(_ context.Context, claim *v1.PersistentVolumeClaim) bool {
+	config, ok := p.DiscoveryMap[esUtil.GetPersistentVolumeClaimClass(claim)]
+	return ok && config.DynamicProvisioning && claim.Annotations[annSelectedNode] == p.Node.Name
+}
+
+// ShouldDelete returns true for the volumes of this node.
+func (p *Provisioner) ShouldDelete(_ context.Context, pv *v1.PersistentVolume) bool {
+	return pv.Spec.Local != nil && slices.Contains(util.GetLocalPersistentVolumeNodeNames(pv), p.nodeValue)
+}
+
+// SupportsBlock returns true as logical volumes can be provisioned as block
+// volumes.
+func (p *Provisioner) SupportsBlock(_ context.Context) bool {
+	return true
+}
+
+// Provision creates a directory with a project quota or a logical volume of
+// the requested size, and returns the PV for it.
+func (p *Provisioner) Provision(_ context.Context, options controller.ProvisionOptions) (*v1.PersistentVolume, controller.ProvisioningState, error) {
+	class := options.StorageClass.Name
+	config, ok := p.DiscoveryMap[class]
+	if !ok || !config.DynamicProvisioning {
+		return nil, controller.ProvisioningFinished, fmt.Errorf("storage class %q is not configured for dynamic provisioning", class)
+	}
+	if options.SelectedNode == nil || options.SelectedNode.Name != p.Node.Name {
+		return nil, controller.ProvisioningFinished, fmt.Errorf("claim did not select node %q", p.Node.Name)
+	}
+
+	accessMode := v1.ReadWriteOnce
+	if modes := options.PVC.Spec.AccessModes; len(modes) > 0 {
+		if len(modes) > 1 || (modes[0] != v1.ReadWriteOnce && modes[0] != v1.ReadWriteOncePod) {
+			return nil, controller.ProvisioningFinished, fmt.Errorf("unsupported access modes %v", modes)
+		}
+		accessMode = modes[0]
+	}
+	volMode := v1.PersistentVolumeFilesystem
+	if options.PVC.Spec.VolumeMode != nil {
+		volMode = *options.PVC.Spec.VolumeMode
+	}
+	request := options.PVC.Spec.Resources.Requests[v1.ResourceStorage]
+	sizeBytes := request.Value()
+	if sizeBytes <= 0 {
+		return nil, controller.ProvisioningFinished, fmt.Errorf("invalid storage request %s", request.String())
+	}
+
+	var hostPath string
+	var state controller.ProvisioningState
+	var err error
+	if config.LVM != nil {
+		hostPath, sizeBytes, state, err = p.provisionLogicalVolume(options.PVName, sizeBytes, volMode, config)
+	} else {
+		hostPath, state, err = p.provisionDirectory(options.PVName, sizeBytes, volMode, config)
+	}
+	if err != nil {
+		return nil, state, err
+	}
+
+	reclaimPolicy := v1.PersistentVolumeReclaimDelete
+	if options.StorageClass.ReclaimPolicy != nil {
+		reclaimPolicy = *options.StorageClass.ReclaimPolicy
+	}
+	localPVConfig := &common.LocalPVConfig{
+		Name:            options.PVName,
+		HostPath:        hostPath,
+		Capacity:        sizeBytes,
+		StorageClass:    class,
+		ReclaimPolicy:   reclaimPolicy,
+		ProvisionerName: common.DynamicProvisionerName,
+		VolumeMode:      volMode,
+		AccessMode:      accessMode,
+		Labels:          p.labels,
+		MountOptions:    options.StorageClass.MountOptions,
+		NodeAffinity: &v1.VolumeNodeAffinity{
+			Required: common.VolumeNodeSelector(p.nodeSelector, config),
+		},
+	}
+	if config.FsType != "" {
+		localPVConfig.FsType = &config.FsType
+	}
+	klog.Infof("Provisioned volume at host path %q with capacity %d for claim %s/%s, creating Local PV %q",
+		hostPath, sizeBytes, options.PVC.Namespace, options.PVC.Name, options.PVName)
+	return common.CreateLocalPVSpec(localPVConfig), controller.ProvisioningFinished, nil
+}
+
+// provisionDirectory creates the directory of the volume under MountDir with
+// a project quota of sizeBytes, and returns its host path. It holds the volume
+// lock while allocating the project, and reschedules the claim if the quotas
+// of the filesystem leave no room for it.
+func (p *Provisioner) provisionDirectory(name string, sizeBytes int64, volMode v1.PersistentVolumeMode, config common.MountConfig) (string, controller.ProvisioningState, error) {
+	if volMode != v1.PersistentVolumeFilesystem {
+		return "", controller.ProvisioningFinished, fmt.Errorf("volume mode %q is not supported for directories", volMode)
+	}
+	p.VolumeLock.Lock()
+	defer p.VolumeLock.Unlock()
+	projectIDs, err := common.GetProjectIDs(p.VolUtil, p.QuotaUtil, config.MountDir)
+	if err != nil {
+		return "", controller.ProvisioningFinished, fmt.Errorf("failed to get project IDs under %q: %v", config.MountDir, err)
+	}
+	mountPath := filepath.Join(config.MountDir, name)
+	projectID := projectIDs[name]
+	if projectID == 0 {
+		if state, err := p.checkDirectorySpace(sizeBytes, config); err != nil {
+			return "", state, err
+		}
+		projectID, err = common.NewProjectID(p.QuotaUtil, mountPath, projectIDs)
+		if err != nil {
+			return "", controller.ProvisioningFinished, err
+		}
+	}
+	if err := p.VolUtil.MakeDir(mountPath); err != nil {
+		return "", controller.ProvisioningFinished, fmt.Errorf("failed to create directory %q: %v", mountPath, err)
+	}
+	if err := p.QuotaUtil.SetProjectQuota(mountPath, projectID, sizeBytes); err != nil {
+		return "", controller.ProvisioningInBackground, fmt.Errorf("failed to set quota of directory %q: %v", mountPath, err)
+	}
+	return filepath.Join(config.HostDir, name), controller.ProvisioningFinished, nil
+}
+
+// checkDirectorySpace returns an error if the quotas of the projects of the
+// filesystem of MountDir and sizeBytes exceed its capacity, or if less than
+// sizeBytes are available.
+func (p *Provisioner) checkDirectorySpace(sizeBytes int64, config common.MountConfig) (controller.ProvisioningState, error) {
+	capacityByte, err := p.VolUtil.GetFsCapacityByte(config.HostDir, config.MountDir)
+	if err != nil {
+		return controller.ProvisioningFinished, fmt.Errorf("failed to get capacity of %q: %v", config.MountDir, err)
+	}
+	availableByte, err := p.VolUtil.GetFsAvailableByte(config.HostDir, config.MountDir)
+	if err != nil {
+		return controller.ProvisioningFinished, fmt.Errorf("failed to get available bytes of %q: %v", config.MountDir, err)
+	}
+	quotas, err := p.QuotaUtil.ListProjectQuotas(config.MountDir)
+	if err != nil {
+		return controller.ProvisioningFinished, fmt.Errorf("failed to list projects of %q: %v", config.MountDir, err)
+	}
+	committedByte := sizeBytes
+	for _, limit := range quotas {
+		committedByte += limit
+	}
+	if committedByte > capacityByte || availableByte < sizeBytes {
+		// Let the scheduler pick a node with enough space.
+		return controller.ProvisioningReschedule, fmt.Errorf("not enough space in %q for %d bytes: %d bytes of %d are committed to quotas, %d available",
+			config.MountDir, sizeBytes, committedByte-sizeBytes, capacityByte, availableByte)
+	}
+	return controller.ProvisioningFinished, nil
+}
+
+// provisionLogicalVolume creates the logical volume of the volume in the
+// volume group, formats and mounts it for Filesystem volumes, and returns its
+// host path and size.
+func (p *Provisioner) provisionLogicalVolume(name string, sizeBytes int64, volMode v1.PersistentVolumeMode, config common.MountConfig) (string, int64, controller.ProvisioningState, error) {
+	lvm := config.LVM
+	if volMode == v1.PersistentVolumeFilesystem && config.FormatAndMount == nil {
+		return "", 0, controller.ProvisioningFinished, fmt.Errorf("Filesystem volumes of volume group %q require formatAndMount", lvm.VolumeGroup)
+	}
+	sizeBytes, state, err := p.createLogicalVolume(name, sizeBytes, lvm)
+	if err != nil {
+		return "", 0, state, err
+	}
+	if volMode == v1.PersistentVolumeBlock {
+		return filepath.Join(config.HostDir, name), sizeBytes, controller.ProvisioningFinished, nil
+	}
+	if err := p.formatAndMount(name, config); err != nil {
+		return "", 0, controller.ProvisioningInBackground, err
+	}
+	return filepath.Join(config.FormatAndMount.HostDir, name), sizeBytes, controller.ProvisioningFinished, nil
+}
+
+// createLogicalVolume creates the logical volume of the volume unless it
+// exists, and returns its size rounded up to the extent size. It holds the
+// volume lock so that the discovery and the deleter don't change the volume
+// group in the meantime.
+func (p *Provisioner) createLogicalVolume(name string, sizeBytes int64, lvm *common.LVMConfig) (int64, controller.ProvisioningState, error) {
+	p.VolumeLock.Lock()
+	defer p.VolumeLock.Unlock()
+	vg, err := p.LVMUtil.GetVolumeGroup(lvm.VolumeGroup)
+	if err != nil {
+		return 0, controller.ProvisioningFinished, fmt.Errorf("volume group %q error: %v", lvm.VolumeGroup, err)
+	}
+	if vg.ExtentSizeBytes > 0 {
+		sizeBytes = esUtil.RoundUpSize(sizeBytes, vg.ExtentSizeBytes) * vg.ExtentSizeBytes
+	}
+	volumes, err := p.LVMUtil.ListLogicalVolumes(lvm.VolumeGroup)
+	if err != nil {
+		return 0, controller.ProvisioningFinished, fmt.Errorf("volume group %q logical volumes error: %v", lvm.VolumeGroup, err)
+	}
+	if slices.ContainsFunc(volumes, func(lv util.LogicalVolume) bool { return lv.Name == name }) {
+		return sizeBytes, controller.ProvisioningFinished, nil
+	}
+	if lvm.ThinPool == "" && vg.FreeBytes < sizeBytes {
+		// Let the scheduler pick a node with enough space.
+		return 0, controller.ProvisioningReschedule, fmt.Errorf("not enough free space in volume group %q for %d bytes", lvm.VolumeGroup, sizeBytes)
+	}
+	klog.Infof("Creating logical volume %q of %d bytes in volume group %q", name, sizeBytes, lvm.VolumeGroup)
+	if err := p.LVMUtil.CreateLogicalVolume(lvm.VolumeGroup, name, sizeBytes, lvm.ThinPool); err != nil {
+		return 0, controller.ProvisioningFinished, fmt.Errorf("failed to create logical volume %q in volume group %q: %v", name, lvm.VolumeGroup, err)
+	}
+	return sizeBytes, controller.ProvisioningFinished, nil
+}
+
+// formatAndMount formats the logical volume with FsType unless it already
+// carries such a filesystem, and mounts it under FormatAndMount.MountDir.
+func (p *Provisioner) formatAndMount(name string, config common.MountConfig) error {
+	devicePath := filepath.Join(config.MountDir, name)
+	signatures, err := p.PartitionUtil.GetSignatures(devicePath)
+	if err != nil {
+		return fmt.Errorf("failed to read signatures of %q: %v", devicePath, err)
+	}
+	if len(signatures) == 0 {
+		klog.Infof("Formatting %q with %s", devicePath, config.FsType)
+		if err := p.FormatUtil.Format(devicePath, config.FsType, common.FilesystemLabel, config.FormatAndMount.MkfsOptions, false); err != nil {
+			return fmt.Errorf("failed to format %q: %v", devicePath, err)
+		}
+	} else if !util.HasSignature(signatures, config.FsType) {
+		return fmt.Errorf("logical volume %q carries unexpected signatures %v", devicePath, signatures)
+	}
+
+	mountPath := filepath.Join(config.FormatAndMount.MountDir, name)
+	mounted, err := p.isMounted(mountPath)
+	if err != nil || mounted {
+		return err
+	}
+	if err := p.VolUtil.MakeDir(mountPath); err != nil {
+		return fmt.Errorf("failed to create mount point %q: %v", mountPath, err)
+	}
+	if err := p.Mounter.Mount(devicePath, mountPath, config.FsType, nil); err != nil {
+		return fmt.Errorf("failed to mount %q at %q: %v", devicePath, mountPath, err)
+	}
+	return nil
+}
+
+// Delete removes the directory or logical volume of the PV.
+func (p *Provisioner) Delete(_ context.Context, pv *v1.PersistentVolume) error {
+	if !p.ShouldDelete(context.TODO(), pv) {
+		return &controller.IgnoredError{Reason: fmt.Sprintf("volume is not on node %q", p.Node.Name)}
+	}
+	config, ok := p.DiscoveryMap[pv.Spec.StorageClassName]
+	if !ok || !config.DynamicProvisioning {
+		return fmt.Errorf("storage class %q is not configured for dynamic provisioning", pv.Spec.StorageClassName)
+	}
+	name := filepath.Base(pv.Spec.Local.Path)
+	klog.Infof("Deleting volume %q of PV %q", pv.Spec.Local.Path, pv.Name)
+	if config.LVM != nil {
+		return p.deleteLogicalVolume(name, config)
+	}
+	return p.deleteDirectory(name, config)
+}
+
+// deleteDirectory removes the project quota and the directory of the volume.
+func (p *Provisioner) deleteDirectory(name string, config common.MountConfig) error {
+	files, err := p.VolUtil.ReadDir(config.MountDir)
+	if err != nil {
+		return fmt.Errorf("error reading directory: %v", err)
+	}
+	if !slices.Contains(files, name) {
+		return nil
+	}
+	mountPath := filepath.Join(config.MountDir, name)
+	projectID, err := p.QuotaUtil.GetProjectID(mountPath)
+	if err != nil {
+		return fmt.Errorf("failed to get project ID of directory %q: %v", mountPath, err)
+	}
+	if projectID != 0 {
+		if err := p.QuotaUtil.RemoveProjectQuota(mountPath, projectID); err != nil {
+			return fmt.Errorf("failed to remove quota of directory %q: %v", mountPath, err)
+		}
+	}
+	return p.VolUtil.RemoveDir(mountPath)
+}
+
+// deleteLogicalVolume unmounts the logical volume of the volume if it is
+// mounted, and removes it from the volume group while holding the volume
+// lock.
+func (p *Provisioner) deleteLogicalVolume(name string, config common.MountConfig) error {
+	lvm := config.LVM
+	if config.FormatAndMount != nil {
+		mountPath := filepath.Join(config.FormatAndMount.MountDir, name)
+		mounted, err := p.isMounted(mountPath)
+		if err != nil {
+			return err
+		}
+		if mounted {
+			if err := p.Mounter.Unmount(mountPath); err != nil {
+				return fmt.Errorf("failed to unmount %q: %v", mountPath, err)
+			}
+		}
+		if err := p.VolUtil.RemoveDir(mountPath); err != nil {
+			return fmt.Errorf("failed to remove mount point %q: %v", mountPath, err)
+		}
+	}
+	p.VolumeLock.Lock()
+	defer p.VolumeLock.Unlock()
+	volumes, err := p.LVMUtil.ListLogicalVolumes(lvm.VolumeGroup)
+	if err != nil {
+		return fmt.Errorf("volume group %q logical volumes error: %v", lvm.VolumeGroup, err)
+	}
+	if !slices.ContainsFunc(volumes, func(lv util.LogicalVolume) bool { return lv.Name == name }) {
+		return nil
+	}
+	if err := p.LVMUtil.RemoveLogicalVolume(lvm.VolumeGroup, name); err != nil {
+		return fmt.Errorf("failed to remove logical volume %q from volume group %q: %v", name, lvm.VolumeGroup, err)
+	}
+	return nil
+}
+
+func (p *Provisioner) isMounted(mountPath string) (bool, error) {
+	mountPoints, err := p.Mounter.List()
+	if err != nil {
+		return false, fmt.Errorf("error retrieving mountpoints: %v", err)
+	}
+	return slices.ContainsFunc(mountPoints, func(mp mount.MountPoint) bool { return mp.Path == mountPath }), nil
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamic
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"sigs.k8s.io/sig-storage-lib-external-provisioner/v6/controller"
+	esUtil "sigs.k8s.io/sig-storage-lib-external-provisioner/v6/util"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/mount"
+)
+
+const (
+	testNodeName = "test-node"
+	testHostDir  = "/mnt/pool"
+	testMountDir = "/pool"
+)
+
+type testConfig struct {
+	volUtil       *util.FakeVolumeUtil
+	quotaUtil     *util.FakeQuotaUtil
+	lvmUtil       *util.FakeLVMUtil
+	partitionUtil *util.FakePartitionUtil
+	formatUtil    *util.FakeFormatUtil
+	mounter       *mount.FakeMounter
+}
+
+func testSetup(t *testing.T, test *testConfig, classes map[string]common.MountConfig) *Provisioner {
+	test.volUtil = util.NewFakeVolumeUtil(false, map[string][]*util.FakeDirEntry{})
+	test.volUtil.AddNewDirEntries(testMountDir, map[string][]*util.FakeDirEntry{"": {}})
+	test.volUtil.AddNewDirEntries("/", map[string][]*util.FakeDirEntry{
+		"": {{Name: filepath.Base(testMountDir), Capacity: 12 * esUtil.GiB, Available: 10 * esUtil.GiB, VolumeType: util.FakeEntryFile}},
+	})
+	test.quotaUtil = util.NewFakeQuotaUtil()
+	test.lvmUtil = util.NewFakeLVMUtil(&util.VolumeGroup{
+		Name:            "vg0",
+		SizeBytes:       20 * esUtil.GiB,
+		FreeBytes:       10 * esUtil.GiB,
+		ExtentSizeBytes: 4 * esUtil.MiB,
+	})
+	test.partitionUtil = util.NewFakePartitionUtil(map[string]string{})
+	test.formatUtil = util.NewFakeFormatUtil()
+	test.mounter = &mount.FakeMounter{}
+
+	runtimeConfig := &common.RuntimeConfig{
+		UserConfig: &common.UserConfig{
+			Node: &v1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   testNodeName,
+				Labels: map[string]string{common.NodeLabelKey: testNodeName, "zone": "a"},
+			}},
+			DiscoveryMap:    classes,
+			NodeLabelsForPV: []string{"zone"},
+		},
+		VolUtil:       test.volUtil,
+		QuotaUtil:     test.quotaUtil,
+		LVMUtil:       test.lvmUtil,
+		PartitionUtil: test.partitionUtil,
+		FormatUtil:    test.formatUtil,
+		Mounter:       test.mounter,
+	}
+	p, err := NewProvisioner(runtimeConfig)
+	if err != nil {
+		t.Fatalf("Error creating provisioner: %v", err)
+	}
+	return p
+}
+
+func testOptions(class, pvName, size string, volMode v1.PersistentVolumeMode) controller.ProvisionOptions {
+	return controller.ProvisionOptions{
+		StorageClass: &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: class}},
+		PVName:       pvName,
+		PVC: &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "claim",
+				Namespace:   "default",
+				Annotations: map[string]string{annSelectedNode: testNodeName},
+			},
+			Spec: v1.PersistentVolumeClaimSpec{
+				StorageClassName: &class,
+				AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+				VolumeMode:       &volMode,
+				Resources: v1.VolumeResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(size)},
+				},
+			},
+		},
+		SelectedNode: &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: testNodeName}},
+	}
+}
+
+func TestProvision_Directory(t *testing.T) {
+	test := &testConfig{}
+	rackSelector := v1.NodeSelectorTerm{
+		MatchExpressions: []v1.NodeSelectorRequirement{{Key: "rack", Operator: v1.NodeSelectorOpIn, Values: []string{"r1"}}},
+	}
+	p := testSetup(t, test, map[string]common.MountConfig{
+		"dirs": {HostDir: testHostDir, MountDir: testMountDir, VolumeMode: "Filesystem", DynamicProvisioning: true,
+			Selector: []v1.NodeSelectorTerm{rackSelector}},
+	})
+	options := testOptions("dirs", "pvc-1", "5Gi", v1.PersistentVolumeFilesystem)
+	if !p.ShouldProvision(context.TODO(), options.PVC) {
+		t.Fatalf("Expected claim to be provisioned")
+	}
+
+	pv, state, err := p.Provision(context.TODO(), options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state != controller.ProvisioningFinished {
+		t.Errorf("Expected provisioning state %q, got %q", controller.ProvisioningFinished, state)
+	}
+	if pv.Spec.Local.Path != filepath.Join(testHostDir, "pvc-1") {
+		t.Errorf("Expected PV path %q, got %q", filepath.Join(testHostDir, "pvc-1"), pv.Spec.Local.Path)
+	}
+	capacity := pv.Spec.Capacity[v1.ResourceStorage]
+	if capacity.Value() != 5*esUtil.GiB {
+		t.Errorf("Expected PV capacity %d, got %d", 5*esUtil.GiB, capacity.Value())
+	}
+	if pv.Labels["zone"] != "a" {
+		t.Errorf("Expected PV label zone=a, got %v", pv.Labels)
+	}
+	// The selector of the class is an alternative to the node.
+	expectedTerms := slices.Concat(p.nodeSelector.NodeSelectorTerms, []v1.NodeSelectorTerm{rackSelector})
+	if terms := pv.Spec.NodeAffinity.Required.NodeSelectorTerms; !reflect.DeepEqual(terms, expectedTerms) {
+		t.Errorf("Expected node selector terms %+v, got %+v", expectedTerms, terms)
+	}
+	if pv.Annotations[common.AnnProvisionedBy] != common.DynamicProvisionerName {
+		t.Errorf("Expected PV provisioned by %q, got %q", common.DynamicProvisionerName, pv.Annotations[common.AnnProvisionedBy])
+	}
+	mountPath := filepath.Join(testMountDir, "pvc-1")
+	projectID := test.quotaUtil.ProjectIDs[mountPath]
+	if projectID == 0 || test.quotaUtil.Limits[projectID] != 5*esUtil.GiB {
+		t.Errorf("Expected quota of %d bytes for %q, got project %d with %d bytes", 5*esUtil.GiB, mountPath, projectID, test.quotaUtil.Limits[projectID])
+	}
+
+	// A retry keeps the project ID
+	if _, _, err := p.Provision(context.TODO(), options); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if test.quotaUtil.ProjectIDs[mountPath] != projectID {
+		t.Errorf("Expected project ID %d to be kept, got %d", projectID, test.quotaUtil.ProjectIDs[mountPath])
+	}
+
+	// Claims which don't fit next to the quotas of the filesystem are
+	// rescheduled
+	_, state, err = p.Provision(context.TODO(), testOptions("dirs", "pvc-3", "8Gi", v1.PersistentVolumeFilesystem))
+	if err == nil || state != controller.ProvisioningReschedule {
+		t.Errorf("Expected provisioning state %q with error, got %q with %v", controller.ProvisioningReschedule, state, err)
+	}
+	if _, ok := test.quotaUtil.ProjectIDs[filepath.Join(testMountDir, "pvc-3")]; ok {
+		t.Errorf("Expected no project for a rescheduled claim")
+	}
+
+	// Block volumes can't be directories
+	if _, _, err := p.Provision(context.TODO(), testOptions("dirs", "pvc-2", "5Gi", v1.PersistentVolumeBlock)); err == nil {
+		t.Errorf("Expected error for Block volume")
+	}
+
+	pv.Status.Phase = v1.VolumeReleased
+	if err := p.Delete(context.TODO(), pv); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := test.quotaUtil.Limits[projectID]; ok {
+		t.Errorf("Expected quota of project %d to be removed", projectID)
+	}
+	if files, _ := test.volUtil.ReadDir(testMountDir); len(files) != 0 {
+		t.Errorf("Expected directory to be removed, got %v", files)
+	}
+}
+
+func TestProvision_LogicalVolume(t *testing.T) {
+	test := &testConfig{}
+	p := testSetup(t, test, map[string]common.MountConfig{
+		"lvs": {
+			HostDir:             "/dev/vg0",
+			MountDir:            "/dev/vg0",
+			VolumeMode:          "Filesystem",
+			FsType:              "ext4",
+			LVM:                 &common.LVMConfig{VolumeGroup: "vg0"},
+			FormatAndMount:      &common.FormatAndMount{HostDir: testHostDir, MountDir: testMountDir},
+			DynamicProvisioning: true,
+		},
+	})
+	test.partitionUtil.DevNodes["/dev/vg0/pvc-2"] = "/dev/dm-2"
+
+	// Block volumes are rounded up to the extent size
+	pv, _, err := p.Provision(context.TODO(), testOptions("lvs", "pvc-1", "1G", v1.PersistentVolumeBlock))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pv.Spec.Local.Path != "/dev/vg0/pvc-1" {
+		t.Errorf("Expected PV path /dev/vg0/pvc-1, got %q", pv.Spec.Local.Path)
+	}
+	capacity := pv.Spec.Capacity[v1.ResourceStorage]
+	if capacity.Value() != 956*esUtil.MiB {
+		t.Errorf("Expected PV capacity %d, got %d", 956*esUtil.MiB, capacity.Value())
+	}
+
+	// Filesystem volumes are formatted and mounted
+	pv, _, err = p.Provision(context.TODO(), testOptions("lvs", "pvc-2", "2Gi", v1.PersistentVolumeFilesystem))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pv.Spec.Local.Path != filepath.Join(testHostDir, "pvc-2") {
+		t.Errorf("Expected PV path %q, got %q", filepath.Join(testHostDir, "pvc-2"), pv.Spec.Local.Path)
+	}
+	if !reflect.DeepEqual(test.formatUtil.Formatted, []string{"/dev/vg0/pvc-2"}) {
+		t.Errorf("Expected /dev/vg0/pvc-2 to be formatted, got %v", test.formatUtil.Formatted)
+	}
+	if mounted, _ := p.isMounted(filepath.Join(testMountDir, "pvc-2")); !mounted {
+		t.Errorf("Expected /dev/vg0/pvc-2 to be mounted")
+	}
+
+	// Claims which don't fit are rescheduled
+	_, state, err := p.Provision(context.TODO(), testOptions("lvs", "pvc-3", "8Gi", v1.PersistentVolumeBlock))
+	if err == nil || state != controller.ProvisioningReschedule {
+		t.Errorf("Expected provisioning state %q, got %q with error %v", controller.ProvisioningReschedule, state, err)
+	}
+
+	if err := p.Delete(context.TODO(), pv); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if mounted, _ := p.isMounted(filepath.Join(testMountDir, "pvc-2")); mounted {
+		t.Errorf("Expected /dev/vg0/pvc-2 to be unmounted")
+	}
+	if !reflect.DeepEqual(test.lvmUtil.Removed, []string{"pvc-2"}) {
+		t.Errorf("Expected logical volume pvc-2 to be removed, got %v", test.lvmUtil.Removed)
+	}
+}
+
+func TestShouldProvision(t *testing.T) {
+	test := &testConfig{}
+	p := testSetup(t, test, map[string]common.MountConfig{
+		"static": {HostDir: testHostDir, MountDir: testMountDir},
+		"dirs":   {HostDir: testHostDir, MountDir: testMountDir, DynamicProvisioning: true},
+	})
+	options := testOptions("dirs", "pvc-1", "1Gi", v1.PersistentVolumeFilesystem)
+	options.PVC.Annotations[annSelectedNode] = "other-node"
+	if p.ShouldProvision(context.TODO(), options.PVC) {
+		t.Errorf("Expected claim of another node not to be provisioned")
+	}
+	options = testOptions("static", "pvc-1", "1Gi", v1.PersistentVolumeFilesystem)
+	if p.ShouldProvision(context.TODO(), options.PVC) {
+		t.Errorf("Expected claim of static class not to be provisioned")
+	}
+
+	pv := &v1.PersistentVolume{Spec: v1.PersistentVolumeSpec{
+		PersistentVolumeSource: v1.PersistentVolumeSource{Local: &v1.LocalVolumeSource{Path: "/mnt/pool/pvc-1"}},
+		NodeAffinity: &v1.VolumeNodeAffinity{Required: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{{
+			MatchExpressions: []v1.NodeSelectorRequirement{{Key: common.NodeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{"other-node"}}},
+		}}}},
+	}}
+	if p.ShouldDelete(context.TODO(), pv) {
+		t.Errorf("Expected volume of another node not to be deleted")
+	}
+	if _, ok := p.Delete(context.TODO(), pv).(*controller.IgnoredError); !ok {
+		t.Errorf("Expected volume of another node to be ignored")
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/metrics"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/utils/mount"
+)
+
+// Monitor reads the health of the disks backing the PVs created by the
+// provisioner, exposes it as metrics and flags the PVs of failing disks.
+type Monitor struct {
+	*common.RuntimeConfig
+}
+
+// NewMonitor creates a Monitor object that checks the disks of the PVs in
+// the cache of the given runtime config.
+func NewMonitor(config *common.RuntimeConfig) *Monitor {
+	return &Monitor{RuntimeConfig: config}
+}
+
+// CheckVolumes reads the health of the disks of the available and bound PVs
+// and updates the PVs accordingly. The PVs of failing disks are annotated,
+// the available ones are pre-bound to a claim which does not exist so that
+// they are not scheduled, and events are recorded on the bound PVs and their
+// claims.
+func (m *Monitor) CheckVolumes() {
+	mountPoints, err := m.Mounter.List()
+	if err != nil {
+		klog.Errorf("Failed to check disk health, error retrieving mountpoints: %v", err)
+		return
+	}
+
+	resetMetrics()
+	devices := map[string]*util.DeviceHealth{}
+	for _, pv := range m.Cache.ListPVs() {
+		if pv.Spec.Local == nil || pv.DeletionTimestamp != nil {
+			continue
+		}
+		if pv.Status.Phase != v1.VolumeAvailable && pv.Status.Phase != v1.VolumeBound {
+			continue
+		}
+		config, ok := m.DiscoveryMap[pv.Spec.StorageClassName]
+		if !ok {
+			continue
+		}
+		mountPath, err := common.GetContainerPath(pv, config)
+		if err != nil {
+			klog.Errorf("Failed to check disk health of PV %q: %v", pv.Name, err)
+			continue
+		}
+		devPath := m.volumeDevice(mountPath, mountPoints)
+		if devPath == "" {
+			klog.V(4).Infof("No disk found for PV %q at %q", pv.Name, mountPath)
+			continue
+		}
+		health, ok := devices[devPath]
+		if !ok {
+			health, err = m.HealthUtil.GetDeviceHealth(devPath)
+			if err != nil {
+				klog.Warningf("Failed to read health of device %q of PV %q: %v", devPath, pv.Name, err)
+			} else {
+				recordMetrics(health)
+			}
+			devices[devPath] = health
+		}
+		if health == nil {
+			continue
+		}
+		m.updateVolumeHealth(pv, diskProblems(health))
+	}
+}
+
+// volumeDevice returns the block device of the volume at mountPath, which is
+// either the volume itself or the device mounted at or above mountPath.
+func (m *Monitor) volumeDevice(mountPath string, mountPoints []mount.MountPoint) string {
+	if isBlock, err := m.VolUtil.IsBlock(mountPath); err == nil && isBlock {
+		return mountPath
+	}
+	device := ""
+	longest := -1
+	for _, mp := range mountPoints {
+		if !strings.HasPrefix(mp.Device, "/dev/") || len(mp.Path) <= longest {
+			continue
+		}
+		if mp.Path == mountPath || strings.HasPrefix(mountPath, strings.TrimSuffix(mp.Path, "/")+"/") {
+			device = mp.Device
+			longest = len(mp.Path)
+		}
+	}
+	return device
+}
+
+// diskProblems returns the reasons why the disk is considered failing, empty if
+// it is healthy. I/O errors are only exposed as metrics since they may be
+// caused by transient transport failures.
+func diskProblems(health *util.DeviceHealth) []string {
+	var problems []string
+	if health.SmartFailed {
+		problems = append(problems, "SMART overall-health self-assessment failed")
+	}
+	if health.CriticalWarning != 0 {
+		problems = append(problems, fmt.Sprintf("critical warning 0x%02x", health.CriticalWarning))
+	}
+	if health.AvailableSpareThreshold > 0 && health.AvailableSpare < health.AvailableSpareThreshold {
+		problems = append(problems, fmt.Sprintf("available spare %d%% below threshold %d%%", health.AvailableSpare, health.AvailableSpareThreshold))
+	}
+	if health.PercentageUsed >= 100 {
+		problems = append(problems, fmt.Sprintf("%d%% of endurance used", health.PercentageUsed))
+	}
+	if health.MediaErrors > 0 {
+		problems = append(problems, fmt.Sprintf("%d media errors", health.MediaErrors))
+	}
+	if health.PendingSectors > 0 {
+		problems = append(problems, fmt.Sprintf("%d pending sectors", health.PendingSectors))
+	}
+	if health.UncorrectableSectors > 0 {
+		problems = append(problems, fmt.Sprintf("%d uncorrectable sectors", health.UncorrectableSectors))
+	}
+	for i := range problems {
+		problems[i] = fmt.Sprintf("disk %s: %s", health.Disk, problems[i])
+	}
+	return problems
+}
+
+// updateVolumeHealth flags the PV as unhealthy if there are problems with
+// its disk, or removes the flags set by a previous check otherwise.
+func (m *Monitor) updateVolumeHealth(pv *v1.PersistentVolume, problems []string) {
+	reason := strings.Join(problems, ", ")
+	current, annotated := pv.Annotations[common.AnnVolumeUnhealthy]
+	claimName, reserved := pv.Annotations[common.AnnUnhealthyClaim]
+	placeholder := reserved && isUnhealthyClaimRef(pv.Spec.ClaimRef, claimName)
+	reserve := reason != "" && pv.Status.Phase == v1.VolumeAvailable && pv.Spec.ClaimRef == nil
+	if reason == "" && !annotated && !reserved {
+		return
+	}
+	if reason != "" && current == reason && !reserve {
+		return
+	}
+
+	newPV := pv.DeepCopy()
+	if reason == "" {
+		delete(newPV.Annotations, common.AnnVolumeUnhealthy)
+		delete(newPV.Annotations, common.AnnUnhealthyClaim)
+		if placeholder {
+			newPV.Spec.ClaimRef = nil
+		}
+	} else {
+		if newPV.Annotations == nil {
+			newPV.Annotations = map[string]string{}
+		}
+		newPV.Annotations[common.AnnVolumeUnhealthy] = reason
+		if reserve {
+			newPV.Spec.ClaimRef = &v1.ObjectReference{
+				Kind:       "PersistentVolumeClaim",
+				APIVersion: "v1",
+				Namespace:  m.Namespace,
+				Name:       common.UnhealthyClaimNamePrefix + string(uuid.NewUUID()),
+			}
+			newPV.Annotations[common.AnnUnhealthyClaim] = newPV.Spec.ClaimRef.Name
+		}
+	}
+	updatedPV, err := m.APIUtil.UpdatePV(newPV)
+	if err != nil {
+		klog.Errorf("Error updating health of PV %q: %v", pv.Name, err)
+		return
+	}
+	m.Cache.UpdatePV(updatedPV)
+
+	if reason == "" {
+		klog.Infof("Disk of PV %q is healthy again", pv.Name)
+		m.Recorder.Event(pv, v1.EventTypeNormal, common.EventVolumeHealthy, "Disk of the volume is healthy again")
+		return
+	}
+	if current == reason {
+		return
+	}
+	klog.Warningf("Disk of PV %q is failing: %s", pv.Name, reason)
+	m.Recorder.Eventf(pv, v1.EventTypeWarning, common.EventVolumeUnhealthy, "Disk of the volume is failing: %s", reason)
+	if pv.Status.Phase == v1.VolumeBound && pv.Spec.ClaimRef != nil && !placeholder {
+		claim := &v1.ObjectReference{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+			Namespace:  pv.Spec.ClaimRef.Namespace,
+			Name:       pv.Spec.ClaimRef.Name,
+			UID:        pv.Spec.ClaimRef.UID,
+		}
+		m.Recorder.Eventf(claim, v1.EventTypeWarning, common.EventVolumeUnhealthy, "Disk of volume %q is failing: %s", pv.Name, reason)
+	}
+}
+
+// isUnhealthyClaimRef returns true if the claim reference is the one to the
+// claim claimName set on available PVs of failing disks, which is still not
+// bound.
+func isUnhealthyClaimRef(claimRef *v1.ObjectReference, claimName string) bool {
+	return claimRef != nil && claimRef.Name == claimName && claimRef.UID == ""
+}
+
+func resetMetrics() {
+	metrics.DeviceHealthy.Reset()
+	metrics.DeviceMediaErrors.Reset()
+	metrics.DeviceBadSectors.Reset()
+	metrics.DevicePercentageUsed.Reset()
+	metrics.DeviceAvailableSpare.Reset()
+	metrics.DeviceTemperatureCelsius.Reset()
+	metrics.DeviceIOErrors.Reset()
+}
+
+func recordMetrics(health *util.DeviceHealth) {
+	device := filepath.Join("/dev", health.Disk)
+	healthy := 1.0
+	if len(diskProblems(health)) > 0 {
+		healthy = 0
+	}
+	metrics.DeviceHealthy.WithLabelValues(device).Set(healthy)
+	metrics.DeviceMediaErrors.WithLabelValues(device).Set(float64(health.MediaErrors))
+	metrics.DeviceBadSectors.WithLabelValues(device, "reallocated").Set(float64(health.ReallocatedSectors))
+	metrics.DeviceBadSectors.WithLabelValues(device, "pending").Set(float64(health.PendingSectors))
+	metrics.DeviceBadSectors.WithLabelValues(device, "uncorrectable").Set(float64(health.UncorrectableSectors))
+	metrics.DevicePercentageUsed.WithLabelValues(device).Set(float64(health.PercentageUsed))
+	if health.AvailableSpareThreshold > 0 {
+		metrics.DeviceAvailableSpare.WithLabelValues(device).Set(float64(health.AvailableSpare))
+	}
+	if health.TemperatureCelsius != 0 {
+		metrics.DeviceTemperatureCelsius.WithLabelValues(device).Set(float64(health.TemperatureCelsius))
+	}
+	metrics.DeviceIOErrors.WithLabelValues(device).Set(float64(health.IOErrors))
+}
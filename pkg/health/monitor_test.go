@@ -0,0 +1,222 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/cache"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/mount"
+)
+
+const (
+	testHostDir   = "/mnt/disks"
+	testMountDir  = "/discoveryPath"
+	testNamespace = "kube-system"
+)
+
+var blockMode = v1.PersistentVolumeBlock
+
+var testDiscoveryMap = map[string]common.MountConfig{
+	"sc1": {HostDir: testHostDir + "/dir1", MountDir: testMountDir + "/dir1", VolumeMode: "Filesystem"},
+	"sc2": {HostDir: testHostDir + "/dir2", MountDir: testMountDir + "/dir2", VolumeMode: "Block"},
+}
+
+type testConfig struct {
+	cache      *cache.VolumeCache
+	client     *fake.Clientset
+	healthUtil *util.FakeHealthUtil
+	recorder   *record.FakeRecorder
+}
+
+func testPV(name, path, class string, phase v1.PersistentVolumePhase, claimRef *v1.ObjectReference) *v1.PersistentVolume {
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				Local: &v1.LocalVolumeSource{Path: path},
+			},
+			StorageClassName: class,
+			ClaimRef:         claimRef,
+		},
+		Status: v1.PersistentVolumeStatus{Phase: phase},
+	}
+	if class == "sc2" {
+		pv.Spec.VolumeMode = &blockMode
+	}
+	return pv
+}
+
+func testSetup(pvs ...*v1.PersistentVolume) (*testConfig, *Monitor) {
+	test := &testConfig{
+		cache:      cache.NewVolumeCache(),
+		healthUtil: util.NewFakeHealthUtil(),
+		recorder:   record.NewFakeRecorder(10),
+	}
+	objects := make([]runtime.Object, 0)
+	for _, pv := range pvs {
+		test.cache.AddPV(pv)
+		objects = append(objects, pv)
+	}
+	test.client = fake.NewSimpleClientset(objects...)
+
+	volUtil := util.NewFakeVolumeUtil(false /*deleteShouldFail*/, map[string][]*util.FakeDirEntry{})
+	volUtil.AddNewDirEntries(testMountDir, map[string][]*util.FakeDirEntry{
+		"dir1": {
+			{Name: "mount1", VolumeType: util.FakeEntryFile},
+			{Name: "mount2", VolumeType: util.FakeEntryFile},
+		},
+		"dir2": {
+			{Name: "block1", VolumeType: util.FakeEntryBlock},
+		},
+	})
+	runtimeConfig := &common.RuntimeConfig{
+		UserConfig: &common.UserConfig{
+			DiscoveryMap: testDiscoveryMap,
+			Namespace:    testNamespace,
+		},
+		Cache:      test.cache,
+		VolUtil:    volUtil,
+		HealthUtil: test.healthUtil,
+		APIUtil:    util.NewAPIUtil(test.client),
+		Recorder:   test.recorder,
+		Mounter: &mount.FakeMounter{
+			MountPoints: []mount.MountPoint{
+				{Path: "/discoveryPath/dir1/mount1", Device: "/dev/nvme0n1"},
+				{Path: "/discoveryPath/dir1/mount2", Device: "/dev/nvme1n1"},
+			},
+		},
+	}
+	return test, NewMonitor(runtimeConfig)
+}
+
+// verifyEvents checks the recorded events regardless of their order, since
+// the PVs are listed from the cache in no particular order.
+func verifyEvents(t *testing.T, recorder *record.FakeRecorder, expected ...string) {
+	events := []string{}
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	slices.Sort(events)
+	slices.Sort(expected)
+	if !slices.Equal(events, expected) {
+		t.Errorf("Expected events %q, got %q", expected, events)
+	}
+}
+
+func TestCheckVolumes(t *testing.T) {
+	boundClaim := &v1.ObjectReference{Namespace: "default", Name: "claim3", UID: "uid3"}
+	// A claim of a user whose name looks like the unhealthy claim.
+	userClaim := &v1.ObjectReference{Namespace: testNamespace, Name: common.UnhealthyClaimNamePrefix + "data"}
+	test, monitor := testSetup(
+		testPV("pv1", "/mnt/disks/dir1/mount1", "sc1", v1.VolumeAvailable, nil),
+		testPV("pv2", "/mnt/disks/dir1/mount2", "sc1", v1.VolumeAvailable, nil),
+		testPV("pv3", "/mnt/disks/dir2/block1", "sc2", v1.VolumeBound, boundClaim),
+		testPV("pv4", "/mnt/disks/dir1/mount2", "sc1", v1.VolumeAvailable, userClaim),
+	)
+	test.healthUtil.Health["/dev/nvme0n1"] = &util.DeviceHealth{Disk: "nvme0n1", MediaErrors: 3}
+	test.healthUtil.Health["/dev/nvme1n1"] = &util.DeviceHealth{Disk: "nvme1n1", AvailableSpare: 100, AvailableSpareThreshold: 10}
+	test.healthUtil.Health["/discoveryPath/dir2/block1"] = &util.DeviceHealth{Disk: "sda", SmartFailed: true, PendingSectors: 8}
+
+	monitor.CheckVolumes()
+
+	pv1, _ := test.cache.GetPV("pv1")
+	if reason := pv1.Annotations[common.AnnVolumeUnhealthy]; reason != "disk nvme0n1: 3 media errors" {
+		t.Errorf("Expected PV %q to be annotated as unhealthy, got %q", pv1.Name, reason)
+	}
+	if claimName := pv1.Annotations[common.AnnUnhealthyClaim]; !isUnhealthyClaimRef(pv1.Spec.ClaimRef, claimName) || pv1.Spec.ClaimRef.Namespace != testNamespace || !strings.HasPrefix(claimName, common.UnhealthyClaimNamePrefix) {
+		t.Errorf("Expected PV %q to be pre-bound to the unhealthy claim, got %+v", pv1.Name, pv1.Spec.ClaimRef)
+	}
+
+	pv2, _ := test.cache.GetPV("pv2")
+	if _, ok := pv2.Annotations[common.AnnVolumeUnhealthy]; ok || pv2.Spec.ClaimRef != nil {
+		t.Errorf("Expected PV %q on a healthy disk to be unchanged, got %+v", pv2.Name, pv2)
+	}
+
+	pv3, _ := test.cache.GetPV("pv3")
+	reason := "disk sda: SMART overall-health self-assessment failed, disk sda: 8 pending sectors"
+	if pv3.Annotations[common.AnnVolumeUnhealthy] != reason {
+		t.Errorf("Expected PV %q to be annotated as unhealthy, got %q", pv3.Name, pv3.Annotations[common.AnnVolumeUnhealthy])
+	}
+	if pv3.Spec.ClaimRef.Name != boundClaim.Name {
+		t.Errorf("Expected claim reference of bound PV %q to be unchanged, got %+v", pv3.Name, pv3.Spec.ClaimRef)
+	}
+	verifyEvents(t, test.recorder,
+		"Warning VolumeUnhealthy Disk of the volume is failing: disk nvme0n1: 3 media errors",
+		"Warning VolumeUnhealthy Disk of the volume is failing: "+reason,
+		"Warning VolumeUnhealthy Disk of volume \"pv3\" is failing: "+reason,
+	)
+
+	// The same problems are only reported once.
+	monitor.CheckVolumes()
+	verifyEvents(t, test.recorder)
+
+	// The PVs of disks which recovered are released.
+	test.healthUtil.Health["/dev/nvme0n1"].MediaErrors = 0
+	monitor.CheckVolumes()
+	pv1, _ = test.cache.GetPV("pv1")
+	if len(pv1.Annotations) > 0 || pv1.Spec.ClaimRef != nil {
+		t.Errorf("Expected PV %q on a recovered disk to be released, got annotations %v and claim reference %+v", pv1.Name, pv1.Annotations, pv1.Spec.ClaimRef)
+	}
+	verifyEvents(t, test.recorder, "Normal VolumeHealthy Disk of the volume is healthy again")
+	pv4, _ := test.cache.GetPV("pv4")
+	if !reflect.DeepEqual(pv4.Spec.ClaimRef, userClaim) {
+		t.Errorf("Expected claim reference of PV %q pre-bound by a user to be unchanged, got %+v", pv4.Name, pv4.Spec.ClaimRef)
+	}
+}
+
+func TestDiskProblems(t *testing.T) {
+	testcases := map[string]struct {
+		health   util.DeviceHealth
+		expected []string
+	}{
+		"healthy": {
+			health: util.DeviceHealth{Disk: "nvme0n1", PercentageUsed: 99, AvailableSpare: 10, AvailableSpareThreshold: 10, IOErrors: 2},
+		},
+		"nvme": {
+			health: util.DeviceHealth{Disk: "nvme0n1", CriticalWarning: 0x4, PercentageUsed: 100, AvailableSpare: 5, AvailableSpareThreshold: 10},
+			expected: []string{
+				"disk nvme0n1: critical warning 0x04",
+				"disk nvme0n1: available spare 5% below threshold 10%",
+				"disk nvme0n1: 100% of endurance used",
+			},
+		},
+		"ata": {
+			health: util.DeviceHealth{Disk: "sda", ReallocatedSectors: 12, UncorrectableSectors: 1},
+			expected: []string{
+				"disk sda: 1 uncorrectable sectors",
+			},
+		},
+	}
+	for name, tc := range testcases {
+		problems := diskProblems(&tc.health)
+		if !slices.Equal(problems, tc.expected) {
+			t.Errorf("%s: expected problems %q, got %q", name, tc.expected, problems)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// GroupName is the API group of the LocalVolumeInventory resource
+	GroupName = "local-static-provisioner.sigs.k8s.io"
+	// Kind is the kind of the LocalVolumeInventory resource
+	Kind = "LocalVolumeInventory"
+)
+
+var (
+	// SchemeGroupVersion is the group version of the LocalVolumeInventory resource
+	SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+	// Resource is the cluster scoped LocalVolumeInventory resource
+	Resource = SchemeGroupVersion.WithResource("localvolumeinventories")
+)
+
+// LocalVolumeInventory lists the volumes seen by the provisioner of a node in
+// the discovery directories of its storage classes. It is named after the
+// node.
+type LocalVolumeInventory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status LocalVolumeInventoryStatus `json:"status,omitempty"`
+}
+
+// LocalVolumeInventoryStatus is the status of a LocalVolumeInventory
+type LocalVolumeInventoryStatus struct {
+	// LastUpdateTime is the time at which the volumes last changed
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+	// Volumes are the paths found in the discovery directories, sorted by
+	// storage class and path
+	Volumes []Volume `json:"volumes,omitempty"`
+}
+
+// Volume describes a path found in the discovery directory of a storage class
+type Volume struct {
+	// Path of the volume on the host
+	Path string `json:"path"`
+	// StorageClass whose discovery directory contains the path
+	StorageClass string `json:"storageClass"`
+	// VolumeMode detected for the path, Block or Filesystem
+	VolumeMode string `json:"volumeMode,omitempty"`
+	// Capacity measured for the volume
+	Capacity *resource.Quantity `json:"capacity,omitempty"`
+	// DeviceIdentity is the WWN, serial or filesystem UUID identifying the
+	// volume with the device identity mode
+	DeviceIdentity string `json:"deviceIdentity,omitempty"`
+	// PersistentVolume is the name of the PV of the volume
+	PersistentVolume string `json:"persistentVolume,omitempty"`
+	// Reason why the path was skipped, or why it has no PV yet
+	Reason string `json:"reason,omitempty"`
+}
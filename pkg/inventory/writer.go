@@ -0,0 +1,103 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+
+	"k8s.io/klog/v2"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+)
+
+// Writer writes the LocalVolumeInventory of a node.
+type Writer struct {
+	client dynamic.Interface
+	node   *v1.Node
+	// volumes last written, valid if written is set
+	volumes []Volume
+	written bool
+}
+
+// NewWriter returns a Writer of the LocalVolumeInventory of the node.
+func NewWriter(client dynamic.Interface, node *v1.Node) *Writer {
+	return &Writer{client: client, node: node}
+}
+
+// Update writes the volumes to the LocalVolumeInventory of the node, which is
+// created if it does not exist yet. Nothing is written if the volumes did not
+// change since the last update.
+func (w *Writer) Update(volumes []Volume) error {
+	if w.written && equality.Semantic.DeepEqual(w.volumes, volumes) {
+		return nil
+	}
+
+	inventory := &LocalVolumeInventory{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: SchemeGroupVersion.String(),
+			Kind:       Kind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: w.node.Name,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "v1",
+					Kind:       "Node",
+					Name:       w.node.Name,
+					UID:        w.node.UID,
+				},
+			},
+		},
+		Status: LocalVolumeInventoryStatus{
+			LastUpdateTime: metav1.Now(),
+			Volumes:        volumes,
+		},
+	}
+
+	client := w.client.Resource(Resource)
+	existing, err := client.Get(context.TODO(), w.node.Name, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+	if found {
+		inventory.ResourceVersion = existing.GetResourceVersion()
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(inventory)
+	if err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	if found {
+		_, err = client.Update(context.TODO(), obj, metav1.UpdateOptions{})
+	} else {
+		_, err = client.Create(context.TODO(), obj, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	klog.V(4).Infof("Updated local volume inventory of node %q with %d volumes", w.node.Name, len(volumes))
+	w.volumes = volumes
+	w.written = true
+	return nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/deleter"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/metrics"
+)
+
+var (
+	cleanupQueueDepth = prometheus.NewDesc(
+		prometheus.BuildFQName("", metrics.LocalVolumeProvisionerSubsystem, "cleanup_queue_depth"),
+		"Number of cleanups waiting for a slot. Broken down by storage class.",
+		[]string{"storage_class"}, nil,
+	)
+	cleanupRunning = prometheus.NewDesc(
+		prometheus.BuildFQName("", metrics.LocalVolumeProvisionerSubsystem, "cleanup_running"),
+		"Number of running cleanups. Broken down by storage class.",
+		[]string{"storage_class"}, nil,
+	)
+)
+
+type cleanupSchedulerCollector struct {
+	scheduler *deleter.CleanupScheduler
+}
+
+// NewCleanupSchedulerCollector creates a cleanup scheduler collector
+func NewCleanupSchedulerCollector(scheduler *deleter.CleanupScheduler) prometheus.Collector {
+	return &cleanupSchedulerCollector{scheduler: scheduler}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (collector *cleanupSchedulerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cleanupQueueDepth
+	ch <- cleanupRunning
+}
+
+// Collect implements the prometheus.Collector interface.
+func (collector *cleanupSchedulerCollector) Collect(ch chan<- prometheus.Metric) {
+	addGauge := func(desc *prometheus.Desc, v float64, lv ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
+	}
+	for class, stats := range collector.scheduler.Stats() {
+		addGauge(cleanupQueueDepth, float64(stats.Queued), class)
+		addGauge(cleanupRunning, float64(stats.Running), class)
+	}
+}
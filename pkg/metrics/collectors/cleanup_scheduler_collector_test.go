@@ -0,0 +1,64 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/common"
+	"sigs.k8s.io/sig-storage-local-static-provisioner/pkg/deleter"
+)
+
+func TestCleanupSchedulerCollector(t *testing.T) {
+	const metadata = `
+		# HELP local_volume_provisioner_cleanup_queue_depth Number of cleanups waiting for a slot. Broken down by storage class.
+		# TYPE local_volume_provisioner_cleanup_queue_depth gauge
+		# HELP local_volume_provisioner_cleanup_running Number of running cleanups. Broken down by storage class.
+		# TYPE local_volume_provisioner_cleanup_running gauge
+	`
+
+	var (
+		want = metadata + `
+			local_volume_provisioner_cleanup_queue_depth{storage_class="sc1"} 1
+			local_volume_provisioner_cleanup_queue_depth{storage_class="sc2"} 1
+			local_volume_provisioner_cleanup_running{storage_class="sc1"} 1
+			local_volume_provisioner_cleanup_running{storage_class="sc2"} 0
+			`
+
+		metrics = []string{
+			"local_volume_provisioner_cleanup_queue_depth",
+			"local_volume_provisioner_cleanup_running",
+		}
+	)
+
+	scheduler := deleter.NewCleanupScheduler(deleter.NewProcTable())
+	scheduler.SetLimits(&common.UserConfig{
+		MaxConcurrentCleanups: 1,
+		DiscoveryMap:          map[string]common.MountConfig{"sc1": {}, "sc2": {}},
+	})
+	release := make(chan struct{})
+	defer close(release)
+	clean := func(common.MountConfig) { <-release }
+	scheduler.Schedule("pv1", "sc1", clean)
+	scheduler.Schedule("pv2", "sc1", clean)
+	scheduler.Schedule("pv3", "sc2", clean)
+	if err := testutil.CollectAndCompare(NewCleanupSchedulerCollector(scheduler), strings.NewReader(want), metrics...); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
@@ -23,6 +23,11 @@ import (
 )
 
 var (
+	procTableQueued = prometheus.NewDesc(
+		prometheus.BuildFQName("", metrics.LocalVolumeProvisionerSubsystem, "proctable_queued"),
+		"Number of queued operations in proctable.",
+		[]string{}, nil,
+	)
 	procTableRunning = prometheus.NewDesc(
 		prometheus.BuildFQName("", metrics.LocalVolumeProvisionerSubsystem, "proctable_running"),
 		"Number of running operations in proctable.",
@@ -38,6 +43,11 @@ var (
 		"Number of failed operations in proctable.",
 		[]string{}, nil,
 	)
+	procTableTimedOut = prometheus.NewDesc(
+		prometheus.BuildFQName("", metrics.LocalVolumeProvisionerSubsystem, "proctable_timed_out"),
+		"Number of timed out operations in proctable.",
+		[]string{}, nil,
+	)
 )
 
 type procTableCollector struct {
@@ -51,9 +61,11 @@ func NewProcTableCollector(procTable deleter.ProcTable) prometheus.Collector {
 
 // Describe implements the prometheus.Collector interface.
 func (collector *procTableCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- procTableQueued
 	ch <- procTableRunning
 	ch <- procTableSucceeded
 	ch <- procTableFailed
+	ch <- procTableTimedOut
 }
 
 // Collect implements the prometheus.Collector interface.
@@ -62,7 +74,9 @@ func (collector *procTableCollector) Collect(ch chan<- prometheus.Metric) {
 	addGauge := func(desc *prometheus.Desc, v float64, lv ...string) {
 		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
 	}
+	addGauge(procTableQueued, float64(stats.Queued))
 	addGauge(procTableRunning, float64(stats.Running))
 	addGauge(procTableSucceeded, float64(stats.Succeeded))
 	addGauge(procTableFailed, float64(stats.Failed))
+	addGauge(procTableTimedOut, float64(stats.TimedOut))
 }
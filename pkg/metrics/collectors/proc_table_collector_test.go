@@ -36,21 +36,29 @@ func TestProcTableCollector(t *testing.T) {
 		# TYPE local_volume_provisioner_proctable_succeeded gauge
 		# HELP local_volume_provisioner_proctable_failed Number of failed operations in proctable.
 		# TYPE local_volume_provisioner_proctable_failed gauge
+		# HELP local_volume_provisioner_proctable_timed_out Number of timed out operations in proctable.
+		# TYPE local_volume_provisioner_proctable_timed_out gauge
+		# HELP local_volume_provisioner_proctable_queued Number of queued operations in proctable.
+		# TYPE local_volume_provisioner_proctable_queued gauge
 		# HELP local_volume_provisioner_proctable_running Number of running operations in proctable.
 		# TYPE local_volume_provisioner_proctable_running gauge
 	`
 
 	var (
 		want = metadata + `
+			local_volume_provisioner_proctable_queued 1
 			local_volume_provisioner_proctable_running 2
 			local_volume_provisioner_proctable_succeeded 2
 			local_volume_provisioner_proctable_failed 1
+			local_volume_provisioner_proctable_timed_out 1
 			`
 
 		metrics = []string{
+			"local_volume_provisioner_proctable_queued",
 			"local_volume_provisioner_proctable_running",
 			"local_volume_provisioner_proctable_succeeded",
 			"local_volume_provisioner_proctable_failed",
+			"local_volume_provisioner_proctable_timed_out",
 		}
 	)
 
@@ -60,9 +68,12 @@ func TestProcTableCollector(t *testing.T) {
 	fakeProcTable.MarkRunning("pv3")
 	fakeProcTable.MarkRunning("pv4")
 	fakeProcTable.MarkRunning("pv5")
+	fakeProcTable.MarkQueued("pv6")
 	fakeProcTable.MarkSucceeded("pv1")
 	fakeProcTable.MarkFailed("pv2")
 	fakeProcTable.MarkSucceeded("pv3")
+	fakeProcTable.MarkRunning("pv7")
+	fakeProcTable.MarkTimedOut("pv7")
 	if err := testutil.CollectAndCompare(&procTableCollector{procTable: fakeProcTable}, strings.NewReader(want), metrics...); err != nil {
 		t.Errorf("unexpected collecting result:\n%s", err)
 	}
@@ -29,6 +29,8 @@ const (
 	LocalVolumeProvisionerSubsystem = "local_volume_provisioner"
 	// APIServerRequestCreate represents metrics related to create resource request.
 	APIServerRequestCreate = "create"
+	// APIServerRequestUpdate represents metrics related to update resource request.
+	APIServerRequestUpdate = "update"
 	// APIServerRequestDelete represents metrics related to delete resource request.
 	APIServerRequestDelete = "delete"
 	// DeleteTypeProcess represents metrics related deletion in process.
@@ -66,6 +68,105 @@ var (
 		},
 		[]string{"mode"},
 	)
+	// PersistentVolumeDeviceRemovedTotal is used to collect accumulated count of persistent volumes whose block device was removed.
+	PersistentVolumeDeviceRemovedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "persistentvolume_device_removed_total",
+			Help:      "Total number of persistent volumes whose backing block device was removed. Broken down by persistent volume mode.",
+		},
+		[]string{"mode"},
+	)
+	// PersistentVolumeCapacityUpdateTotal is used to collect accumulated count of available persistent volumes whose capacity was updated.
+	PersistentVolumeCapacityUpdateTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "persistentvolume_capacity_update_total",
+			Help:      "Total number of available persistent volumes whose capacity was updated to the measured capacity. Broken down by persistent volume mode.",
+		},
+		[]string{"mode"},
+	)
+	// PersistentVolumeCapacityDriftBytes is used to collect the difference between the measured and the advertised capacity of bound persistent volumes.
+	PersistentVolumeCapacityDriftBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "persistentvolume_capacity_drift_bytes",
+			Help:      "Difference between the measured and the advertised capacity of bound persistent volumes in bytes. Broken down by persistent volume.",
+		},
+		[]string{"persistentvolume"},
+	)
+	// MissingVolumes is used to collect the number of persistent volumes whose backing volume is missing.
+	MissingVolumes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "missing_volumes",
+			Help:      "Number of available and bound persistent volumes whose backing volume is missing. Broken down by persistent volume mode.",
+		},
+		[]string{"mode"},
+	)
+	// DeviceHealthy is used to collect the health of the disks of the local volumes.
+	DeviceHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "device_healthy",
+			Help:      "Whether the disk backing local volumes is healthy (1) or failing (0). Broken down by device.",
+		},
+		[]string{"device"},
+	)
+	// DeviceMediaErrors is used to collect the unrecovered media errors of the disks of the local volumes.
+	DeviceMediaErrors = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "device_media_errors",
+			Help:      "Number of unrecovered media and data integrity errors reported by the disk. Broken down by device.",
+		},
+		[]string{"device"},
+	)
+	// DeviceBadSectors is used to collect the bad sectors of the ATA disks of the local volumes.
+	DeviceBadSectors = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "device_bad_sectors",
+			Help:      "Number of bad sectors reported by the disk. Broken down by device, type (reallocated, pending or uncorrectable).",
+		},
+		[]string{"device", "type"},
+	)
+	// DevicePercentageUsed is used to collect the endurance used by the disks of the local volumes.
+	DevicePercentageUsed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "device_percentage_used",
+			Help:      "Percentage of the rated endurance used by the disk, may exceed 100. Broken down by device.",
+		},
+		[]string{"device"},
+	)
+	// DeviceAvailableSpare is used to collect the spare capacity left on the NVMe disks of the local volumes.
+	DeviceAvailableSpare = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "device_available_spare",
+			Help:      "Percentage of the spare capacity available on the disk. Broken down by device.",
+		},
+		[]string{"device"},
+	)
+	// DeviceTemperatureCelsius is used to collect the temperature of the disks of the local volumes.
+	DeviceTemperatureCelsius = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "device_temperature_celsius",
+			Help:      "Temperature of the disk in degrees Celsius. Broken down by device.",
+		},
+		[]string{"device"},
+	)
+	// DeviceIOErrors is used to collect the I/O errors counted by the kernel for the disks of the local volumes.
+	DeviceIOErrors = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "device_io_errors",
+			Help:      "Number of commands to the disk which failed with an I/O error since boot. Broken down by device.",
+		},
+		[]string{"device"},
+	)
 	// PersistentVolumeDeleteTotal is used to collect accumulated count of persistent volumes deleted.
 	PersistentVolumeDeleteTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -75,6 +176,24 @@ var (
 		},
 		[]string{"mode", "type"},
 	)
+	// PersistentVolumeDeleteTimedOutTotal is used to collect accumulated count of persistent volume cleanups killed by their timeout.
+	PersistentVolumeDeleteTimedOutTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "persistentvolume_delete_timed_out_total",
+			Help:      "Total number of persistent volume cleanups which timed out. Broken down by persistent volume mode, delete type (process or job).",
+		},
+		[]string{"mode", "type"},
+	)
+	// PersistentVolumeCleanupProgressRatio is used to collect the progress of the running cleanups of persistent volumes.
+	PersistentVolumeCleanupProgressRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: LocalVolumeProvisionerSubsystem,
+			Name:      "cleanup_progress_ratio",
+			Help:      "Ratio of the bytes processed by the running cleanups of persistent volumes in process which report their progress. Broken down by persistent volume.",
+		},
+		[]string{"persistentvolume"},
+	)
 	// PersistentVolumeDeleteFailedTotal is used to collect accumulated count of persistent volume delete failed attempts.
 	PersistentVolumeDeleteFailedTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -89,7 +208,7 @@ var (
 		prometheus.HistogramOpts{
 			Subsystem: LocalVolumeProvisionerSubsystem,
 			Name:      "persistentvolume_delete_duration_seconds",
-			Help:      "Latency in seconds to delete persistent volumes. Broken down by persistent volume mode, delete type (process or job), capacity and cleanup_command.",
+			Help:      "Latency in seconds to delete persistent volumes, including the time their cleanup was queued. Broken down by persistent volume mode, delete type (process or job), capacity and cleanup_command.",
 			Buckets:   prometheus.DefBuckets,
 		},
 		[]string{"mode", "type", "capacity", "cleanup_command"},
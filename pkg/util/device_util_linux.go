@@ -0,0 +1,222 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	sysDevBlockDir = "/sys/dev/block"
+	udevDataDir    = "/run/udev/data"
+	diskByIDDir    = "/dev/disk/by-id"
+	diskByPathDir  = "/dev/disk/by-path"
+	diskByUUIDDir  = "/dev/disk/by-uuid"
+
+	sectorSize = 512
+)
+
+// GetDeviceAttributes returns the attributes of the block device at fullPath.
+// Attributes missing from sysfs are looked up in the udev database, which is
+// only available if /run/udev of the host is mounted into the provisioner.
+func (u *volumeUtil) GetDeviceAttributes(fullPath string) (*DeviceAttributes, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(fullPath, &st); err != nil {
+		return nil, err
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFBLK {
+		return nil, fmt.Errorf("%q is not a block device", fullPath)
+	}
+	devID := fmt.Sprintf("%d:%d", unix.Major(uint64(st.Rdev)), unix.Minor(uint64(st.Rdev)))
+	sysPath, err := filepath.EvalSymlinks(filepath.Join(sysDevBlockDir, devID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %q in sysfs: %v", fullPath, err)
+	}
+	attrs, err := readDeviceAttributes(sysPath, readUdevProperties(filepath.Join(udevDataDir, "b"+devID)))
+	if err != nil {
+		return nil, err
+	}
+	devPath, err := filepath.EvalSymlinks(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	attrs.ByID = deviceLinks(diskByIDDir, devPath)
+	attrs.ByPath = deviceLinks(diskByPathDir, devPath)
+	if attrs.FsUUID == "" {
+		if uuids := deviceLinks(diskByUUIDDir, devPath); len(uuids) > 0 {
+			attrs.FsUUID = uuids[0]
+		}
+	}
+	return attrs, nil
+}
+
+// GetFsUUID returns the UUID of the filesystem mounted at mountPath, read
+// from the udev database or the /dev/disk/by-uuid links of its device.
+func (u *volumeUtil) GetFsUUID(mountPath string) (string, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(mountPath, &st); err != nil {
+		return "", err
+	}
+	devID := fmt.Sprintf("%d:%d", unix.Major(uint64(st.Dev)), unix.Minor(uint64(st.Dev)))
+	if uuid := readUdevProperties(filepath.Join(udevDataDir, "b"+devID))["ID_FS_UUID"]; uuid != "" {
+		return uuid, nil
+	}
+	entries, err := os.ReadDir(diskByUUIDDir)
+	if err != nil {
+		return "", fmt.Errorf("no filesystem UUID found for %q: %v", mountPath, err)
+	}
+	for _, entry := range entries {
+		var devSt unix.Stat_t
+		if err := unix.Stat(filepath.Join(diskByUUIDDir, entry.Name()), &devSt); err != nil {
+			continue
+		}
+		if devSt.Mode&unix.S_IFMT == unix.S_IFBLK && devSt.Rdev == st.Dev {
+			return entry.Name(), nil
+		}
+	}
+	return "", fmt.Errorf("no filesystem UUID found for %q", mountPath)
+}
+
+// readDeviceAttributes reads the attributes of the block device at sysPath,
+// the canonical sysfs directory of the device, falling back to the udev
+// properties of the device.
+func readDeviceAttributes(sysPath string, udev map[string]string) (*DeviceAttributes, error) {
+	attrs := &DeviceAttributes{
+		Name:      filepath.Base(sysPath),
+		Transport: transportFromSysPath(sysPath),
+	}
+
+	size, err := strconv.ParseInt(readSysfsAttr(sysPath, "size"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read size of block device %q: %v", attrs.Name, err)
+	}
+	attrs.SizeBytes = size * sectorSize
+
+	// The queue and device attributes of a partition are those of its disk.
+	diskPath := sysPath
+	if partition := readSysfsAttr(sysPath, "partition"); partition != "" {
+		if attrs.PartitionNumber, err = strconv.Atoi(partition); err != nil {
+			return nil, fmt.Errorf("failed to read partition number of block device %q: %v", attrs.Name, err)
+		}
+		diskPath = filepath.Dir(sysPath)
+		attrs.PartitionName = firstNonEmpty(readUeventProperties(sysPath)["PARTNAME"], udev["ID_PART_ENTRY_NAME"])
+	}
+	attrs.Rotational = readSysfsAttr(diskPath, "queue/rotational") == "1"
+	attrs.Model = firstNonEmpty(readSysfsAttr(diskPath, "device/model"), udev["ID_MODEL"])
+	attrs.Vendor = firstNonEmpty(readSysfsAttr(diskPath, "device/vendor"), udev["ID_VENDOR"])
+	attrs.Serial = firstNonEmpty(readSysfsAttr(diskPath, "device/serial"), udev["ID_SERIAL_SHORT"])
+	attrs.WWN = firstNonEmpty(readSysfsAttr(diskPath, "wwid"), readSysfsAttr(diskPath, "device/wwid"), udev["ID_WWN_WITH_EXTENSION"], udev["ID_WWN"])
+	attrs.FsUUID = udev["ID_FS_UUID"]
+	return attrs, nil
+}
+
+// transportFromSysPath derives the transport from the bus hierarchy of the
+// canonical sysfs path, e.g. /sys/devices/pci0000:00/0000:00:04.0/nvme/nvme0/nvme0n1.
+func transportFromSysPath(sysPath string) string {
+	switch {
+	case strings.Contains(sysPath, "/nvme"):
+		return TransportNVMe
+	case strings.Contains(sysPath, "/usb"):
+		return TransportUSB
+	case strings.Contains(sysPath, "/virtio"):
+		return TransportVirtio
+	case strings.Contains(sysPath, "/ata"):
+		return TransportSATA
+	case strings.Contains(sysPath, "/end_device-"):
+		return TransportSAS
+	case strings.Contains(sysPath, "/host"):
+		return TransportSCSI
+	}
+	return ""
+}
+
+func readSysfsAttr(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readUeventProperties reads the "KEY=VALUE" properties of the uevent
+// attribute of the sysfs directory.
+func readUeventProperties(sysPath string) map[string]string {
+	properties := map[string]string{}
+	for _, line := range strings.Split(readSysfsAttr(sysPath, "uevent"), "\n") {
+		if key, value, found := strings.Cut(line, "="); found {
+			properties[key] = value
+		}
+	}
+	return properties
+}
+
+// readUdevProperties reads the "E:KEY=VALUE" properties of a udev database
+// entry. It returns an empty map if the entry does not exist.
+func readUdevProperties(path string) map[string]string {
+	properties := map[string]string{}
+	file, err := os.Open(path)
+	if err != nil {
+		return properties
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, found := strings.CutPrefix(scanner.Text(), "E:")
+		if !found {
+			continue
+		}
+		if key, value, found := strings.Cut(line, "="); found {
+			properties[key] = strings.TrimSpace(value)
+		}
+	}
+	return properties
+}
+
+// deviceLinks returns the names of the links in dir which resolve to devPath.
+func deviceLinks(dir, devPath string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var links []string
+	for _, entry := range entries {
+		target, err := filepath.EvalSymlinks(filepath.Join(dir, entry.Name()))
+		if err == nil && target == devPath {
+			links = append(links, entry.Name())
+		}
+	}
+	return links
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
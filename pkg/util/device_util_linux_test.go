@@ -0,0 +1,158 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeSysfsAttrs(t *testing.T, dir string, attrs map[string]string) {
+	for name, value := range attrs {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %q: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(value+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %q: %v", path, err)
+		}
+	}
+}
+
+func TestReadDeviceAttributes(t *testing.T) {
+	root := t.TempDir()
+	nvmeDisk := filepath.Join(root, "devices/pci0000:00/0000:00:04.0/nvme/nvme0/nvme0n1")
+	writeSysfsAttrs(t, nvmeDisk, map[string]string{
+		"size":                "3907029168",
+		"queue/rotational":    "0",
+		"device/model":        "Example NVMe SSD 2TB                    ",
+		"device/serial":       "S1234567       ",
+		"wwid":                "eui.0025388b91b2c1a4",
+		"nvme0n1p1/size":      "2048",
+		"nvme0n1p1/partition": "1",
+		"nvme0n1p1/uevent":    "MAJOR=259\nMINOR=1\nDEVNAME=nvme0n1p1\nDEVTYPE=partition\nPARTN=1\nPARTNAME=local-static-provisioner-1",
+	})
+	sataDisk := filepath.Join(root, "devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda")
+	writeSysfsAttrs(t, sataDisk, map[string]string{
+		"size":             "1953525168",
+		"queue/rotational": "1",
+		"device/vendor":    "ATA     ",
+		"device/model":     "HDD 1TB",
+	})
+	udevData := filepath.Join(root, "b8:0")
+	writeSysfsAttrs(t, root, map[string]string{
+		"b8:0": "S:disk/by-id/ata-HDD_1TB_Z1234\nE:ID_SERIAL_SHORT=Z1234\nE:ID_WWN=0x5000c500a1b2c3d4\nE:ID_FS_UUID=0b4b7e1c-8d2e-4a57-9b3c-3f1f4c6a2d10\nG:systemd",
+	})
+
+	tests := []struct {
+		name     string
+		sysPath  string
+		udev     map[string]string
+		expected *DeviceAttributes
+	}{
+		{
+			name:    "nvme disk",
+			sysPath: nvmeDisk,
+			udev:    map[string]string{},
+			expected: &DeviceAttributes{
+				Name:      "nvme0n1",
+				Model:     "Example NVMe SSD 2TB",
+				Serial:    "S1234567",
+				WWN:       "eui.0025388b91b2c1a4",
+				Transport: TransportNVMe,
+				SizeBytes: 3907029168 * 512,
+			},
+		},
+		{
+			name:    "nvme partition",
+			sysPath: filepath.Join(nvmeDisk, "nvme0n1p1"),
+			udev:    map[string]string{},
+			expected: &DeviceAttributes{
+				Name:            "nvme0n1p1",
+				PartitionNumber: 1,
+				PartitionName:   "local-static-provisioner-1",
+				Model:           "Example NVMe SSD 2TB",
+				Serial:          "S1234567",
+				WWN:             "eui.0025388b91b2c1a4",
+				Transport:       TransportNVMe,
+				SizeBytes:       2048 * 512,
+			},
+		},
+		{
+			name:    "sata disk with udev properties",
+			sysPath: sataDisk,
+			udev:    readUdevProperties(udevData),
+			expected: &DeviceAttributes{
+				Name:       "sda",
+				Model:      "HDD 1TB",
+				Vendor:     "ATA",
+				Serial:     "Z1234",
+				WWN:        "0x5000c500a1b2c3d4",
+				Rotational: true,
+				Transport:  TransportSATA,
+				SizeBytes:  1953525168 * 512,
+				FsUUID:     "0b4b7e1c-8d2e-4a57-9b3c-3f1f4c6a2d10",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attrs, err := readDeviceAttributes(test.sysPath, test.udev)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.expected, attrs); diff != "" {
+				t.Errorf("Unexpected attributes (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := readDeviceAttributes(filepath.Join(root, "missing"), map[string]string{}); err == nil {
+		t.Errorf("Expected error reading a missing device")
+	}
+}
+
+func TestDeviceLinks(t *testing.T) {
+	root := t.TempDir()
+	dev := filepath.Join(root, "nvme0n1")
+	if err := os.WriteFile(dev, nil, 0600); err != nil {
+		t.Fatalf("Failed to create fake device: %v", err)
+	}
+	byID := filepath.Join(root, "by-id")
+	if err := os.Mkdir(byID, 0755); err != nil {
+		t.Fatalf("Failed to create %q: %v", byID, err)
+	}
+	for link, target := range map[string]string{
+		"nvme-eui.0025388b91b2c1a4": "../nvme0n1",
+		"nvme-Example_S1234567":     dev,
+		"nvme-other":                "../nvme1n1",
+	} {
+		if err := os.Symlink(target, filepath.Join(byID, link)); err != nil {
+			t.Fatalf("Failed to create link: %v", err)
+		}
+	}
+	expected := []string{"nvme-Example_S1234567", "nvme-eui.0025388b91b2c1a4"}
+	if diff := cmp.Diff(expected, deviceLinks(byID, dev)); diff != "" {
+		t.Errorf("Unexpected links (-want +got):\n%s", diff)
+	}
+}
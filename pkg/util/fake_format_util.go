@@ -0,0 +1,41 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+var _ FormatUtil = &FakeFormatUtil{}
+
+// FakeFormatUtil is a stub interface for unit testing
+type FakeFormatUtil struct {
+	// Device paths formatted by Format
+	Formatted []string
+	// Device paths formatted by Format with force set
+	Forced []string
+}
+
+// NewFakeFormatUtil returns a FormatUtil object for use in unit testing
+func NewFakeFormatUtil() *FakeFormatUtil {
+	return &FakeFormatUtil{}
+}
+
+// Format records the formatted device
+func (u *FakeFormatUtil) Format(devPath, fsType, label string, options []string, force bool) error {
+	u.Formatted = append(u.Formatted, devPath)
+	if force {
+		u.Forced = append(u.Forced, devPath)
+	}
+	return nil
+}
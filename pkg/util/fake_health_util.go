@@ -0,0 +1,45 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+)
+
+var _ HealthUtil = &FakeHealthUtil{}
+
+// FakeHealthUtil is a stub interface for unit testing
+type FakeHealthUtil struct {
+	// Health of the disks by device path
+	Health map[string]*DeviceHealth
+}
+
+// NewFakeHealthUtil returns a HealthUtil object for use in unit testing
+func NewFakeHealthUtil() *FakeHealthUtil {
+	return &FakeHealthUtil{
+		Health: map[string]*DeviceHealth{},
+	}
+}
+
+// GetDeviceHealth returns the health of the device
+func (u *FakeHealthUtil) GetDeviceHealth(devPath string) (*DeviceHealth, error) {
+	health, ok := u.Health[devPath]
+	if !ok {
+		return nil, fmt.Errorf("device %q not found", devPath)
+	}
+	return health, nil
+}
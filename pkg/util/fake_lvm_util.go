@@ -0,0 +1,105 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+)
+
+var _ LVMUtil = &FakeLVMUtil{}
+
+// FakeLVMUtil is a stub interface for unit testing
+type FakeLVMUtil struct {
+	VolumeGroups   map[string]*VolumeGroup
+	LogicalVolumes map[string][]LogicalVolume
+	// Names of the logical volumes created by CreateLogicalVolume
+	Created []string
+	// Names of the logical volumes removed by RemoveLogicalVolume
+	Removed []string
+}
+
+// NewFakeLVMUtil returns an LVMUtil object for use in unit testing
+func NewFakeLVMUtil(volumeGroups ...*VolumeGroup) *FakeLVMUtil {
+	u := &FakeLVMUtil{
+		VolumeGroups:   map[string]*VolumeGroup{},
+		LogicalVolumes: map[string][]LogicalVolume{},
+	}
+	for _, vg := range volumeGroups {
+		u.VolumeGroups[vg.Name] = vg
+	}
+	return u
+}
+
+// GetVolumeGroup returns the volume group
+func (u *FakeLVMUtil) GetVolumeGroup(vg string) (*VolumeGroup, error) {
+	group, ok := u.VolumeGroups[vg]
+	if !ok {
+		return nil, fmt.Errorf("volume group %q not found", vg)
+	}
+	copied := *group
+	return &copied, nil
+}
+
+// ListLogicalVolumes returns the logical volumes of the volume group
+func (u *FakeLVMUtil) ListLogicalVolumes(vg string) ([]LogicalVolume, error) {
+	if _, ok := u.VolumeGroups[vg]; !ok {
+		return nil, fmt.Errorf("volume group %q not found", vg)
+	}
+	return u.LogicalVolumes[vg], nil
+}
+
+// CreateLogicalVolume adds the logical volume to the volume group, taking the
+// space of thick volumes from its free space
+func (u *FakeLVMUtil) CreateLogicalVolume(vg, name string, sizeBytes int64, thinPool string) error {
+	group, ok := u.VolumeGroups[vg]
+	if !ok {
+		return fmt.Errorf("volume group %q not found", vg)
+	}
+	for _, lv := range u.LogicalVolumes[vg] {
+		if lv.Name == name {
+			return fmt.Errorf("logical volume %q already exists in volume group %q", name, vg)
+		}
+	}
+	if thinPool == "" {
+		if group.FreeBytes < sizeBytes {
+			return fmt.Errorf("insufficient free space in volume group %q", vg)
+		}
+		group.FreeBytes -= sizeBytes
+	}
+	u.LogicalVolumes[vg] = append(u.LogicalVolumes[vg], LogicalVolume{Name: name, SizeBytes: sizeBytes, ThinPool: thinPool})
+	u.Created = append(u.Created, name)
+	return nil
+}
+
+// RemoveLogicalVolume removes the logical volume from the volume group
+func (u *FakeLVMUtil) RemoveLogicalVolume(vg, name string) error {
+	group, ok := u.VolumeGroups[vg]
+	if !ok {
+		return fmt.Errorf("volume group %q not found", vg)
+	}
+	for i, lv := range u.LogicalVolumes[vg] {
+		if lv.Name == name {
+			if lv.ThinPool == "" {
+				group.FreeBytes += lv.SizeBytes
+			}
+			u.LogicalVolumes[vg] = append(u.LogicalVolumes[vg][:i], u.LogicalVolumes[vg][i+1:]...)
+			u.Removed = append(u.Removed, name)
+			return nil
+		}
+	}
+	return fmt.Errorf("logical volume %q not found in volume group %q", name, vg)
+}
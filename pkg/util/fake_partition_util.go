@@ -0,0 +1,83 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+)
+
+var _ PartitionUtil = &FakePartitionUtil{}
+
+// FakePartitionUtil is a stub interface for unit testing
+type FakePartitionUtil struct {
+	// Device node of each device path, e.g. /dev/nvme0n1
+	DevNodes map[string]string
+	// Signatures found on each device path
+	Signatures map[string][]Signature
+	// Partitions of each device path
+	Partitions map[string][]Partition
+	// Device paths partitioned by CreatePartitions
+	Created []string
+}
+
+// NewFakePartitionUtil returns a PartitionUtil object for use in unit testing
+func NewFakePartitionUtil(devNodes map[string]string) *FakePartitionUtil {
+	return &FakePartitionUtil{
+		DevNodes:   devNodes,
+		Signatures: map[string][]Signature{},
+		Partitions: map[string][]Partition{},
+	}
+}
+
+// GetSignatures returns the signatures of the device
+func (u *FakePartitionUtil) GetSignatures(devPath string) ([]Signature, error) {
+	if _, ok := u.DevNodes[devPath]; !ok {
+		return nil, fmt.Errorf("device %q not found", devPath)
+	}
+	return u.Signatures[devPath], nil
+}
+
+// ListPartitions returns the partitions of the device
+func (u *FakePartitionUtil) ListPartitions(devPath string) ([]Partition, error) {
+	partitions, ok := u.Partitions[devPath]
+	if !ok {
+		return nil, fmt.Errorf("device %q does not contain a recognized partition table", devPath)
+	}
+	return partitions, nil
+}
+
+// CreatePartitions records the partitions of the device and adds a GPT
+// signature to it
+func (u *FakePartitionUtil) CreatePartitions(devPath string, partitions []Partition) error {
+	devNode, ok := u.DevNodes[devPath]
+	if !ok {
+		return fmt.Errorf("device %q not found", devPath)
+	}
+	if len(u.Signatures[devPath]) > 0 {
+		return fmt.Errorf("device %q already contains signatures %v", devPath, u.Signatures[devPath])
+	}
+	created := []Partition{}
+	for _, partition := range partitions {
+		partition.Node = fmt.Sprintf("%sp%d", devNode, partition.Number)
+		partition.Path = partition.Node
+		created = append(created, partition)
+	}
+	u.Signatures[devPath] = []Signature{{Type: "PMBR"}, {Type: "gpt"}}
+	u.Partitions[devPath] = created
+	u.Created = append(u.Created, devPath)
+	return nil
+}
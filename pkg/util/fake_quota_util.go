@@ -0,0 +1,78 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+)
+
+var _ QuotaUtil = &FakeQuotaUtil{}
+
+// FakeQuotaUtil is a stub interface for unit testing
+type FakeQuotaUtil struct {
+	mutex sync.Mutex
+	// Project IDs of the directories
+	ProjectIDs map[string]uint32
+	// Disk space limits of the projects
+	Limits map[uint32]int64
+}
+
+// NewFakeQuotaUtil returns a QuotaUtil object for use in unit testing
+func NewFakeQuotaUtil() *FakeQuotaUtil {
+	return &FakeQuotaUtil{
+		ProjectIDs: map[string]uint32{},
+		Limits:     map[uint32]int64{},
+	}
+}
+
+// GetProjectID returns the project ID of the directory
+func (u *FakeQuotaUtil) GetProjectID(path string) (uint32, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.ProjectIDs[path], nil
+}
+
+// SetProjectQuota records the project ID of the directory and the limit of
+// the project
+func (u *FakeQuotaUtil) SetProjectQuota(path string, projectID uint32, sizeBytes int64) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if projectID == 0 {
+		return fmt.Errorf("invalid project ID 0 for directory %q", path)
+	}
+	u.ProjectIDs[path] = projectID
+	u.Limits[projectID] = sizeBytes
+	return nil
+}
+
+// RemoveProjectQuota removes the limit of the project
+func (u *FakeQuotaUtil) RemoveProjectQuota(path string, projectID uint32) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	delete(u.Limits, projectID)
+	return nil
+}
+
+// ListProjectQuotas returns the limits of all the projects, as if all the
+// directories were on the same filesystem
+func (u *FakeQuotaUtil) ListProjectQuotas(path string) (map[uint32]int64, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return maps.Clone(u.Limits), nil
+}
@@ -18,6 +18,7 @@ package util
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"k8s.io/klog/v2"
@@ -49,6 +50,14 @@ type FakeDirEntry struct {
 	// Expected hash value of the PV name
 	Hash     uint32
 	Capacity int64
+	// Available bytes of a file entry
+	Available int64
+	// Attributes of a block device entry
+	DeviceAttributes *DeviceAttributes
+	// UUID of the filesystem of a file entry
+	FsUUID string
+	// Contents of a file entry
+	Content []byte
 }
 
 // NewFakeVolumeUtil returns a VolumeUtil object for use in unit testing
@@ -110,6 +119,17 @@ func (u *FakeVolumeUtil) ReadDir(fullPath string) ([]string, error) {
 	return fileNames, nil
 }
 
+// ReadFile returns the contents of the given file entry
+func (u *FakeVolumeUtil) ReadFile(fullPath string) ([]byte, error) {
+	dir, file := filepath.Split(fullPath)
+	for _, f := range u.directoryFiles[filepath.Clean(dir)] {
+		if file == f.Name {
+			return f.Content, nil
+		}
+	}
+	return nil, fmt.Errorf("Directory entry %q not found: %w", fullPath, os.ErrNotExist)
+}
+
 // DeleteContents removes all the contents under the given directory
 func (u *FakeVolumeUtil) DeleteContents(hostPath, mountPath string) error {
 	if u.deleteShouldFail {
@@ -123,11 +143,125 @@ func (u *FakeVolumeUtil) GetFsCapacityByte(hostPath, mountPath string) (int64, e
 	return u.getDirEntryCapacity(mountPath, FakeEntryFile)
 }
 
+// GetFsAvailableByte returns the available bytes of a mounted filesystem.
+func (u *FakeVolumeUtil) GetFsAvailableByte(hostPath, mountPath string) (int64, error) {
+	dir, file := filepath.Split(mountPath)
+	for _, f := range u.directoryFiles[filepath.Clean(dir)] {
+		if file == f.Name {
+			if f.VolumeType != FakeEntryFile {
+				return 0, fmt.Errorf("Directory entry %q is not a %q", f.Name, FakeEntryFile)
+			}
+			return f.Available, nil
+		}
+	}
+	return 0, fmt.Errorf("Directory entry %q not found", mountPath)
+}
+
 // GetBlockCapacityByte returns the space in the specified block device.
 func (u *FakeVolumeUtil) GetBlockCapacityByte(fullPath string) (int64, error) {
 	return u.getDirEntryCapacity(fullPath, FakeEntryBlock)
 }
 
+// GetDeviceAttributes returns the attributes of the specified block device.
+func (u *FakeVolumeUtil) GetDeviceAttributes(fullPath string) (*DeviceAttributes, error) {
+	dir, file := filepath.Split(fullPath)
+	dir = filepath.Clean(dir)
+	files, found := u.directoryFiles[dir]
+	if !found {
+		return nil, fmt.Errorf("Directory %q not found", dir)
+	}
+
+	for _, f := range files {
+		if file == f.Name {
+			if f.VolumeType != FakeEntryBlock {
+				return nil, fmt.Errorf("Directory entry %q is not a %q", fullPath, FakeEntryBlock)
+			}
+			if f.DeviceAttributes == nil {
+				return &DeviceAttributes{Name: f.Name, SizeBytes: f.Capacity}, nil
+			}
+			return f.DeviceAttributes, nil
+		}
+	}
+	return nil, fmt.Errorf("Directory entry %q not found", fullPath)
+}
+
+// GetFsUUID returns the filesystem UUID of the specified file entry.
+func (u *FakeVolumeUtil) GetFsUUID(mountPath string) (string, error) {
+	dir, file := filepath.Split(mountPath)
+	dir = filepath.Clean(dir)
+	files, found := u.directoryFiles[dir]
+	if !found {
+		return "", fmt.Errorf("Directory %q not found", dir)
+	}
+
+	for _, f := range files {
+		if file == f.Name {
+			if f.FsUUID == "" {
+				return "", fmt.Errorf("no filesystem UUID found for %q", mountPath)
+			}
+			return f.FsUUID, nil
+		}
+	}
+	return "", fmt.Errorf("Directory entry %q not found", mountPath)
+}
+
+// EnsureSymlink adds a copy of the target entry named after linkPath to the
+// directory of linkPath, replacing an existing entry of that name.
+func (u *FakeVolumeUtil) EnsureSymlink(linkPath, target string) error {
+	targetDir, targetFile := filepath.Split(target)
+	var entry *FakeDirEntry
+	for _, f := range u.directoryFiles[filepath.Clean(targetDir)] {
+		if f.Name == targetFile {
+			entry = f
+		}
+	}
+	if entry == nil {
+		return fmt.Errorf("Directory entry %q not found", target)
+	}
+	dir, file := filepath.Split(linkPath)
+	dir = filepath.Clean(dir)
+	link := *entry
+	link.Name = file
+	files := []*FakeDirEntry{&link}
+	for _, f := range u.directoryFiles[dir] {
+		if f.Name != file {
+			files = append(files, f)
+		}
+	}
+	u.directoryFiles[dir] = files
+	return nil
+}
+
+// MakeDir adds a file entry for fullPath to its directory if it does not exist.
+func (u *FakeVolumeUtil) MakeDir(fullPath string) error {
+	dir, file := filepath.Split(fullPath)
+	dir = filepath.Clean(dir)
+	for _, f := range u.directoryFiles[dir] {
+		if f.Name == file {
+			if f.VolumeType != FakeEntryFile {
+				return fmt.Errorf("%q exists and is not a directory", fullPath)
+			}
+			return nil
+		}
+	}
+	u.directoryFiles[dir] = append(u.directoryFiles[dir], &FakeDirEntry{Name: file, VolumeType: FakeEntryFile})
+	return nil
+}
+
+// RemoveDir removes the entry of the directory and the entries under it
+func (u *FakeVolumeUtil) RemoveDir(fullPath string) error {
+	dir, file := filepath.Split(fullPath)
+	dir = filepath.Clean(dir)
+	for i, f := range u.directoryFiles[dir] {
+		if f.Name == file {
+			u.directoryFiles[dir] = append(u.directoryFiles[dir][:i], u.directoryFiles[dir][i+1:]...)
+			break
+		}
+	}
+	delete(u.directoryFiles, filepath.Clean(fullPath))
+	return nil
+}
+
 func (u *FakeVolumeUtil) getDirEntryCapacity(fullPath string, entryType string) (int64, error) {
 	dir, file := filepath.Split(fullPath)
 	dir = filepath.Clean(dir)
@@ -139,7 +273,7 @@ func (u *FakeVolumeUtil) getDirEntryCapacity(fullPath string, entryType string)
 	for _, f := range files {
 		if file == f.Name {
 			if f.VolumeType != entryType {
-				return 0, fmt.Errorf("Directory entry %q is not a %q", f, entryType)
+				return 0, fmt.Errorf("Directory entry %q is not a %q", f.Name, entryType)
 			}
 			return f.Capacity, nil
 		}
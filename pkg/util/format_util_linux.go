@@ -0,0 +1,61 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var _ FormatUtil = &formatUtil{}
+
+type formatUtil struct{}
+
+// NewFormatUtil returns a FormatUtil object which creates filesystems with
+// the mkfs.<fsType> commands
+func NewFormatUtil() FormatUtil {
+	return &formatUtil{}
+}
+
+// Format creates a filesystem on the device with mkfs.<fsType>
+func (u *formatUtil) Format(devPath, fsType, label string, options []string, force bool) error {
+	args := mkfsArgs(devPath, fsType, label, options, force)
+	if out, err := exec.Command("mkfs."+fsType, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("mkfs.%s failed: %v: %s", fsType, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// mkfsArgs returns the arguments of mkfs.<fsType>. All supported mkfs
+// commands take the label with -L, the ext family forces with -F and the
+// others, e.g. xfs and btrfs, with -f.
+func mkfsArgs(devPath, fsType, label string, options []string, force bool) []string {
+	args := []string{"-L", label}
+	if force {
+		if strings.HasPrefix(fsType, "ext") {
+			args = append(args, "-F")
+		} else {
+			args = append(args, "-f")
+		}
+	}
+	args = append(args, options...)
+	return append(args, devPath)
+}
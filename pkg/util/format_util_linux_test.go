@@ -0,0 +1,57 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMkfsArgs(t *testing.T) {
+	tests := []struct {
+		fsType   string
+		options  []string
+		force    bool
+		expected []string
+	}{
+		{
+			fsType:   "ext4",
+			options:  []string{"-m", "0"},
+			expected: []string{"-L", "local-pv", "-m", "0", "/dev/nvme0n1"},
+		},
+		{
+			fsType:   "ext4",
+			force:    true,
+			expected: []string{"-L", "local-pv", "-F", "/dev/nvme0n1"},
+		},
+		{
+			fsType:   "xfs",
+			force:    true,
+			expected: []string{"-L", "local-pv", "-f", "/dev/nvme0n1"},
+		},
+	}
+	for _, test := range tests {
+		args := mkfsArgs("/dev/nvme0n1", test.fsType, "local-pv", test.options, test.force)
+		if diff := cmp.Diff(test.expected, args); diff != "" {
+			t.Errorf("Unexpected mkfs.%s arguments (-want +got):\n%s", test.fsType, diff)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+)
+
+var _ FormatUtil = &formatUtil{}
+
+type formatUtil struct{}
+
+// NewFormatUtil returns a FormatUtil object which fails all operations as
+// formatting is only supported on Linux
+func NewFormatUtil() FormatUtil {
+	return &formatUtil{}
+}
+
+// Format is not supported
+func (u *formatUtil) Format(devPath, fsType, label string, options []string, force bool) error {
+	return fmt.Errorf("Format is unsupported in this build")
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+// HealthUtil is an interface for reading the health of block devices
+type HealthUtil interface {
+	// GetDeviceHealth returns the health of the disk of the block device at
+	// devPath, which may also be a partition of the disk
+	GetDeviceHealth(devPath string) (*DeviceHealth, error)
+}
+
+// DeviceHealth describes the health of a disk as reported by its NVMe or ATA
+// SMART log and by the kernel
+type DeviceHealth struct {
+	// Kernel name of the disk, e.g. nvme0n1
+	Disk string
+	// True if the disk failed its SMART overall health self-assessment
+	SmartFailed bool
+	// NVMe critical warning bits, 0 if none
+	CriticalWarning int
+	// Percentage of the rated endurance which was used, may exceed 100
+	PercentageUsed int
+	// Available spare capacity and its threshold in percent, 0 if unknown
+	AvailableSpare          int
+	AvailableSpareThreshold int
+	// Unrecovered media and data integrity errors
+	MediaErrors int64
+	// Reallocated, pending and offline uncorrectable sectors of ATA disks
+	ReallocatedSectors   int64
+	PendingSectors       int64
+	UncorrectableSectors int64
+	// Temperature in degrees Celsius, 0 if unknown
+	TemperatureCelsius int
+	// Commands which failed with an I/O error as counted by the kernel
+	IOErrors int64
+}
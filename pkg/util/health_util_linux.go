@@ -0,0 +1,163 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// smartctl exit status bits of command line and device open errors, the
+	// other bits report the health of the device
+	smartctlCommandLineError = 1 << 0
+	smartctlDeviceOpenError  = 1 << 1
+
+	ataReallocatedSectorCount   = 5
+	ataCurrentPendingSector     = 197
+	ataOfflineUncorrectable     = 198
+	ataPercentageUsedStatistics = "Percentage Used Endurance Indicator"
+)
+
+var _ HealthUtil = &healthUtil{}
+
+type healthUtil struct{}
+
+// NewHealthUtil returns a HealthUtil object which reads the SMART logs of
+// disks with smartctl and their I/O error counters from sysfs
+func NewHealthUtil() HealthUtil {
+	return &healthUtil{}
+}
+
+// GetDeviceHealth returns the health of the disk of the block device at
+// devPath as reported by smartctl and the ioerr_cnt sysfs attribute of SCSI
+// disks
+func (u *healthUtil) GetDeviceHealth(devPath string) (*DeviceHealth, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(devPath, &st); err != nil {
+		return nil, err
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFBLK {
+		return nil, fmt.Errorf("%q is not a block device", devPath)
+	}
+	devID := fmt.Sprintf("%d:%d", unix.Major(uint64(st.Rdev)), unix.Minor(uint64(st.Rdev)))
+	diskPath, err := filepath.EvalSymlinks(filepath.Join(sysDevBlockDir, devID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %q in sysfs: %v", devPath, err)
+	}
+	if readSysfsAttr(diskPath, "partition") != "" {
+		diskPath = filepath.Dir(diskPath)
+	}
+	disk := filepath.Base(diskPath)
+
+	out, err := exec.Command("smartctl", "--json", "--all", filepath.Join("/dev", disk)).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode()&(smartctlCommandLineError|smartctlDeviceOpenError) == 0 {
+		// The device was read, the exit status only reports its health.
+		err = nil
+	}
+	if err != nil {
+		return nil, commandError("smartctl", err)
+	}
+	health, err := parseSmartctlOutput(out)
+	if err != nil {
+		return nil, err
+	}
+	health.Disk = disk
+	if ioerrCount := readSysfsAttr(diskPath, "device/ioerr_cnt"); ioerrCount != "" {
+		if health.IOErrors, err = strconv.ParseInt(ioerrCount, 0, 64); err != nil {
+			return nil, fmt.Errorf("failed to parse I/O error count %q of disk %q: %v", ioerrCount, disk, err)
+		}
+	}
+	return health, nil
+}
+
+// parseSmartctlOutput parses the JSON output of smartctl --all of an NVMe or
+// ATA disk.
+func parseSmartctlOutput(out []byte) (*DeviceHealth, error) {
+	var report struct {
+		SmartStatus *struct {
+			Passed bool `json:"passed"`
+		} `json:"smart_status"`
+		Temperature struct {
+			Current int `json:"current"`
+		} `json:"temperature"`
+		NVMeLog *struct {
+			CriticalWarning         int   `json:"critical_warning"`
+			AvailableSpare          int   `json:"available_spare"`
+			AvailableSpareThreshold int   `json:"available_spare_threshold"`
+			PercentageUsed          int   `json:"percentage_used"`
+			MediaErrors             int64 `json:"media_errors"`
+		} `json:"nvme_smart_health_information_log"`
+		ATAAttributes struct {
+			Table []struct {
+				ID  int `json:"id"`
+				Raw struct {
+					Value int64 `json:"value"`
+				} `json:"raw"`
+			} `json:"table"`
+		} `json:"ata_smart_attributes"`
+		ATAStatistics struct {
+			Pages []struct {
+				Table []struct {
+					Name  string `json:"name"`
+					Value int    `json:"value"`
+				} `json:"table"`
+			} `json:"pages"`
+		} `json:"ata_device_statistics"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse smartctl output: %v", err)
+	}
+
+	health := &DeviceHealth{
+		SmartFailed:        report.SmartStatus != nil && !report.SmartStatus.Passed,
+		TemperatureCelsius: report.Temperature.Current,
+	}
+	if log := report.NVMeLog; log != nil {
+		health.CriticalWarning = log.CriticalWarning
+		health.AvailableSpare = log.AvailableSpare
+		health.AvailableSpareThreshold = log.AvailableSpareThreshold
+		health.PercentageUsed = log.PercentageUsed
+		health.MediaErrors = log.MediaErrors
+	}
+	for _, attr := range report.ATAAttributes.Table {
+		switch attr.ID {
+		case ataReallocatedSectorCount:
+			health.ReallocatedSectors = attr.Raw.Value
+		case ataCurrentPendingSector:
+			health.PendingSectors = attr.Raw.Value
+		case ataOfflineUncorrectable:
+			health.UncorrectableSectors = attr.Raw.Value
+		}
+	}
+	for _, page := range report.ATAStatistics.Pages {
+		for _, stat := range page.Table {
+			if stat.Name == ataPercentageUsedStatistics {
+				health.PercentageUsed = stat.Value
+			}
+		}
+	}
+	return health, nil
+}
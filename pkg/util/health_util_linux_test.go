@@ -0,0 +1,107 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSmartctlOutput(t *testing.T) {
+	testcases := []struct {
+		name     string
+		output   string
+		expected *DeviceHealth
+	}{
+		{
+			name: "nvme",
+			output: `{
+  "device": {"name": "/dev/nvme0n1", "type": "nvme", "protocol": "NVMe"},
+  "smart_status": {"passed": false, "nvme": {"value": 4}},
+  "nvme_smart_health_information_log": {
+    "critical_warning": 4,
+    "temperature": 41,
+    "available_spare": 100,
+    "available_spare_threshold": 10,
+    "percentage_used": 12,
+    "media_errors": 3
+  },
+  "temperature": {"current": 41}
+}`,
+			expected: &DeviceHealth{
+				SmartFailed:             true,
+				CriticalWarning:         4,
+				PercentageUsed:          12,
+				AvailableSpare:          100,
+				AvailableSpareThreshold: 10,
+				MediaErrors:             3,
+				TemperatureCelsius:      41,
+			},
+		},
+		{
+			name: "ata",
+			output: `{
+  "device": {"name": "/dev/sda", "type": "sat", "protocol": "ATA"},
+  "smart_status": {"passed": true},
+  "ata_smart_attributes": {
+    "table": [
+      {"id": 5, "name": "Reallocated_Sector_Ct", "raw": {"value": 8, "string": "8"}},
+      {"id": 194, "name": "Temperature_Celsius", "raw": {"value": 30, "string": "30"}},
+      {"id": 197, "name": "Current_Pending_Sector", "raw": {"value": 2, "string": "2"}},
+      {"id": 198, "name": "Offline_Uncorrectable", "raw": {"value": 1, "string": "1"}}
+    ]
+  },
+  "ata_device_statistics": {
+    "pages": [
+      {"number": 7, "table": [{"name": "Percentage Used Endurance Indicator", "value": 5}]}
+    ]
+  },
+  "temperature": {"current": 30}
+}`,
+			expected: &DeviceHealth{
+				PercentageUsed:       5,
+				ReallocatedSectors:   8,
+				PendingSectors:       2,
+				UncorrectableSectors: 1,
+				TemperatureCelsius:   30,
+			},
+		},
+		{
+			// Disks without SMART support are considered healthy
+			name:     "no smart",
+			output:   `{"device": {"name": "/dev/vda", "type": "scsi"}}`,
+			expected: &DeviceHealth{},
+		},
+	}
+	for _, tc := range testcases {
+		health, err := parseSmartctlOutput([]byte(tc.output))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(health, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, health)
+		}
+	}
+
+	if _, err := parseSmartctlOutput([]byte("smartctl: command not found")); err == nil {
+		t.Errorf("Expected error for unexpected output")
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+)
+
+var _ HealthUtil = &healthUtil{}
+
+type healthUtil struct{}
+
+// NewHealthUtil returns a HealthUtil object which fails all operations as
+// reading the health of disks is only supported on Linux
+func NewHealthUtil() HealthUtil {
+	return &healthUtil{}
+}
+
+// GetDeviceHealth is not supported
+func (u *healthUtil) GetDeviceHealth(devPath string) (*DeviceHealth, error) {
+	return nil, fmt.Errorf("GetDeviceHealth is unsupported in this build")
+}
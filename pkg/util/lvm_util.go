@@ -0,0 +1,49 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+// LVMUtil is an interface for managing LVM logical volumes
+type LVMUtil interface {
+	// GetVolumeGroup returns the size and free space of the volume group
+	GetVolumeGroup(vg string) (*VolumeGroup, error)
+
+	// ListLogicalVolumes returns the logical volumes of the volume group
+	ListLogicalVolumes(vg string) ([]LogicalVolume, error)
+
+	// CreateLogicalVolume creates a logical volume of sizeBytes in the
+	// volume group, a thin volume in thinPool if it is not empty
+	CreateLogicalVolume(vg, name string, sizeBytes int64, thinPool string) error
+
+	// RemoveLogicalVolume removes the logical volume from the volume group
+	RemoveLogicalVolume(vg, name string) error
+}
+
+// VolumeGroup describes an LVM volume group
+type VolumeGroup struct {
+	Name            string
+	SizeBytes       int64
+	FreeBytes       int64
+	ExtentSizeBytes int64
+}
+
+// LogicalVolume describes an LVM logical volume
+type LogicalVolume struct {
+	Name      string
+	SizeBytes int64
+	// Thin pool of a thin volume, empty for thick volumes
+	ThinPool string
+}
@@ -0,0 +1,148 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+var _ LVMUtil = &lvmUtil{}
+
+type lvmUtil struct{}
+
+// NewLVMUtil returns an LVMUtil object which manages logical volumes with the
+// LVM commands. The commands do not wait for udev, which usually does not run
+// in the provisioner container.
+func NewLVMUtil() LVMUtil {
+	return &lvmUtil{}
+}
+
+// GetVolumeGroup returns the volume group as reported by vgs
+func (u *lvmUtil) GetVolumeGroup(vg string) (*VolumeGroup, error) {
+	out, err := exec.Command("vgs", "--reportformat", "json", "--units", "b", "--nosuffix",
+		"-o", "vg_name,vg_size,vg_free,vg_extent_size", vg).Output()
+	if err != nil {
+		return nil, commandError("vgs", err)
+	}
+	return parseVgsOutput(out, vg)
+}
+
+// ListLogicalVolumes returns the logical volumes as reported by lvs
+func (u *lvmUtil) ListLogicalVolumes(vg string) ([]LogicalVolume, error) {
+	out, err := exec.Command("lvs", "--reportformat", "json", "--units", "b", "--nosuffix",
+		"-o", "lv_name,lv_size,pool_lv", vg).Output()
+	if err != nil {
+		return nil, commandError("lvs", err)
+	}
+	return parseLvsOutput(out)
+}
+
+// CreateLogicalVolume creates the logical volume with lvcreate
+func (u *lvmUtil) CreateLogicalVolume(vg, name string, sizeBytes int64, thinPool string) error {
+	args := []string{"--yes", "--noudevsync", "--name", name}
+	if thinPool != "" {
+		args = append(args, "--virtualsize", fmt.Sprintf("%db", sizeBytes), "--thinpool", thinPool)
+	} else {
+		args = append(args, "--size", fmt.Sprintf("%db", sizeBytes))
+	}
+	args = append(args, vg)
+	if out, err := exec.Command("lvcreate", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("lvcreate failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// RemoveLogicalVolume removes the logical volume with lvremove
+func (u *lvmUtil) RemoveLogicalVolume(vg, name string) error {
+	if out, err := exec.Command("lvremove", "--yes", "--noudevsync", vg+"/"+name).CombinedOutput(); err != nil {
+		return fmt.Errorf("lvremove failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+type lvmReport struct {
+	Report []struct {
+		VG []map[string]string `json:"vg"`
+		LV []map[string]string `json:"lv"`
+	} `json:"report"`
+}
+
+func parseLvmReport(out []byte, command string) (*lvmReport, error) {
+	var report lvmReport
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse %s output: %v", command, err)
+	}
+	if len(report.Report) == 0 {
+		return nil, fmt.Errorf("empty %s report", command)
+	}
+	return &report, nil
+}
+
+// parseVgsOutput parses the output of "vgs --reportformat json" with sizes
+// in bytes.
+func parseVgsOutput(out []byte, vg string) (*VolumeGroup, error) {
+	report, err := parseLvmReport(out, "vgs")
+	if err != nil {
+		return nil, err
+	}
+	for _, fields := range report.Report[0].VG {
+		if fields["vg_name"] != vg {
+			continue
+		}
+		group := &VolumeGroup{Name: vg}
+		for field, value := range map[string]*int64{
+			"vg_size":        &group.SizeBytes,
+			"vg_free":        &group.FreeBytes,
+			"vg_extent_size": &group.ExtentSizeBytes,
+		} {
+			if *value, err = strconv.ParseInt(fields[field], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid %s of volume group %q: %v", field, vg, err)
+			}
+		}
+		return group, nil
+	}
+	return nil, fmt.Errorf("volume group %q not found", vg)
+}
+
+// parseLvsOutput parses the output of "lvs --reportformat json" with sizes
+// in bytes.
+func parseLvsOutput(out []byte) ([]LogicalVolume, error) {
+	report, err := parseLvmReport(out, "lvs")
+	if err != nil {
+		return nil, err
+	}
+	var volumes []LogicalVolume
+	for _, fields := range report.Report[0].LV {
+		size, err := strconv.ParseInt(fields["lv_size"], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size of logical volume %q: %v", fields["lv_name"], err)
+		}
+		volumes = append(volumes, LogicalVolume{
+			Name:      fields["lv_name"],
+			SizeBytes: size,
+			ThinPool:  fields["pool_lv"],
+		})
+	}
+	return volumes, nil
+}
@@ -0,0 +1,81 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseVgsOutput(t *testing.T) {
+	output := `  {
+      "report": [
+          {
+              "vg": [
+                  {"vg_name":"vg0", "vg_size":"1000203091968", "vg_free":"800162473984", "vg_extent_size":"4194304"}
+              ]
+          }
+      ]
+  }`
+	expected := &VolumeGroup{
+		Name:            "vg0",
+		SizeBytes:       1000203091968,
+		FreeBytes:       800162473984,
+		ExtentSizeBytes: 4194304,
+	}
+	vg, err := parseVgsOutput([]byte(output), "vg0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, vg); diff != "" {
+		t.Errorf("Unexpected volume group (-want +got):\n%s", diff)
+	}
+
+	if _, err := parseVgsOutput([]byte(output), "vg1"); err == nil {
+		t.Errorf("Expected error for missing volume group")
+	}
+}
+
+func TestParseLvsOutput(t *testing.T) {
+	output := `  {
+      "report": [
+          {
+              "lv": [
+                  {"lv_name":"pool0", "lv_size":"107374182400", "pool_lv":""},
+                  {"lv_name":"local-static-provisioner-1", "lv_size":"10737418240", "pool_lv":"pool0"},
+                  {"lv_name":"root", "lv_size":"53687091200", "pool_lv":""}
+              ]
+          }
+      ]
+  }`
+	expected := []LogicalVolume{
+		{Name: "pool0", SizeBytes: 107374182400},
+		{Name: "local-static-provisioner-1", SizeBytes: 10737418240, ThinPool: "pool0"},
+		{Name: "root", SizeBytes: 53687091200},
+	}
+	volumes, err := parseLvsOutput([]byte(output))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, volumes); diff != "" {
+		t.Errorf("Unexpected logical volumes (-want +got):\n%s", diff)
+	}
+}
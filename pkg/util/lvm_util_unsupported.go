@@ -0,0 +1,54 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+)
+
+var _ LVMUtil = &lvmUtil{}
+
+type lvmUtil struct{}
+
+// NewLVMUtil returns an LVMUtil object which fails all operations as LVM is
+// only supported on Linux
+func NewLVMUtil() LVMUtil {
+	return &lvmUtil{}
+}
+
+// GetVolumeGroup is not supported
+func (u *lvmUtil) GetVolumeGroup(vg string) (*VolumeGroup, error) {
+	return nil, fmt.Errorf("GetVolumeGroup is unsupported in this build")
+}
+
+// ListLogicalVolumes is not supported
+func (u *lvmUtil) ListLogicalVolumes(vg string) ([]LogicalVolume, error) {
+	return nil, fmt.Errorf("ListLogicalVolumes is unsupported in this build")
+}
+
+// CreateLogicalVolume is not supported
+func (u *lvmUtil) CreateLogicalVolume(vg, name string, sizeBytes int64, thinPool string) error {
+	return fmt.Errorf("CreateLogicalVolume is unsupported in this build")
+}
+
+// RemoveLogicalVolume is not supported
+func (u *lvmUtil) RemoveLogicalVolume(vg, name string) error {
+	return fmt.Errorf("RemoveLogicalVolume is unsupported in this build")
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+// PartitionUtil is an interface for partitioning block devices
+type PartitionUtil interface {
+	// GetSignatures returns the filesystem, RAID and partition table
+	// signatures found on the device
+	GetSignatures(devPath string) ([]Signature, error)
+
+	// ListPartitions returns the partitions of the partition table of the device
+	ListPartitions(devPath string) ([]Partition, error)
+
+	// CreatePartitions writes a new GPT partition table with the given
+	// partitions to the device
+	CreatePartitions(devPath string, partitions []Partition) error
+}
+
+// Signature describes a filesystem, RAID or partition table signature
+type Signature struct {
+	// Type of the signature, e.g. "gpt" or "ext4"
+	Type string
+	// Label of the filesystem, if any
+	Label string
+}
+
+// HasSignature returns true if signatures contain one of the given type
+func HasSignature(signatures []Signature, sigType string) bool {
+	for _, signature := range signatures {
+		if signature.Type == sigType {
+			return true
+		}
+	}
+	return false
+}
+
+// Partition describes a partition of a GPT partition table
+type Partition struct {
+	// Partition number, starting from 1
+	Number int
+	// GPT partition name
+	Name      string
+	SizeBytes int64
+	// Device node of the partition, e.g. /dev/nvme0n1p1
+	Node string
+	// Path of the partition which is stable across reboots, the
+	// /dev/disk/by-partuuid link if it exists, otherwise Node
+	Path string
+}
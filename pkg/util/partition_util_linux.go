@@ -0,0 +1,167 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const diskByPartUUIDDir = "/dev/disk/by-partuuid"
+
+var _ PartitionUtil = &partitionUtil{}
+
+type partitionUtil struct{}
+
+// NewPartitionUtil returns a PartitionUtil object which partitions block
+// devices with wipefs and sfdisk from util-linux
+func NewPartitionUtil() PartitionUtil {
+	return &partitionUtil{}
+}
+
+// GetSignatures returns the types of the signatures found on the device by wipefs
+func (u *partitionUtil) GetSignatures(devPath string) ([]Signature, error) {
+	out, err := exec.Command("wipefs", "--json", devPath).Output()
+	if err != nil {
+		return nil, commandError("wipefs", err)
+	}
+	return parseWipefsOutput(out)
+}
+
+// ListPartitions returns the partitions of the device as reported by sfdisk
+func (u *partitionUtil) ListPartitions(devPath string) ([]Partition, error) {
+	// sfdisk derives the partition nodes from the name of the device.
+	devPath, err := filepath.EvalSymlinks(devPath)
+	if err != nil {
+		return nil, err
+	}
+	out, err := exec.Command("sfdisk", "--json", devPath).Output()
+	if err != nil {
+		return nil, commandError("sfdisk", err)
+	}
+	return parseSfdiskOutput(out)
+}
+
+// CreatePartitions writes a new GPT partition table to the device with sfdisk
+func (u *partitionUtil) CreatePartitions(devPath string, partitions []Partition) error {
+	var script strings.Builder
+	script.WriteString("label: gpt\n")
+	for _, partition := range partitions {
+		fmt.Fprintf(&script, "size=%dKiB, name=%q\n", partition.SizeBytes/1024, partition.Name)
+	}
+	cmd := exec.Command("sfdisk", "--quiet", devPath)
+	cmd.Stdin = strings.NewReader(script.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("sfdisk failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+type wipefsOutput struct {
+	Signatures []struct {
+		Type  string `json:"type"`
+		Label string `json:"label"`
+	} `json:"signatures"`
+}
+
+// parseWipefsOutput parses the output of "wipefs --json", which is empty if
+// the device has no signatures.
+func parseWipefsOutput(out []byte) ([]Signature, error) {
+	if len(strings.TrimSpace(string(out))) == 0 {
+		return nil, nil
+	}
+	var output wipefsOutput
+	if err := json.Unmarshal(out, &output); err != nil {
+		return nil, fmt.Errorf("failed to parse wipefs output: %v", err)
+	}
+	var signatures []Signature
+	for _, signature := range output.Signatures {
+		signatures = append(signatures, Signature{Type: signature.Type, Label: signature.Label})
+	}
+	return signatures, nil
+}
+
+type sfdiskOutput struct {
+	PartitionTable struct {
+		Label      string `json:"label"`
+		SectorSize int64  `json:"sectorsize"`
+		Partitions []struct {
+			Node string `json:"node"`
+			Size int64  `json:"size"`
+			UUID string `json:"uuid"`
+			Name string `json:"name"`
+		} `json:"partitions"`
+	} `json:"partitiontable"`
+}
+
+// parseSfdiskOutput parses the output of "sfdisk --json". The partition paths
+// are the /dev/disk/by-partuuid links of the partitions if they exist.
+func parseSfdiskOutput(out []byte) ([]Partition, error) {
+	var output sfdiskOutput
+	if err := json.Unmarshal(out, &output); err != nil {
+		return nil, fmt.Errorf("failed to parse sfdisk output: %v", err)
+	}
+	table := output.PartitionTable
+	sectorSize := table.SectorSize
+	if sectorSize == 0 {
+		sectorSize = 512
+	}
+	var partitions []Partition
+	for _, p := range table.Partitions {
+		partition := Partition{
+			Number:    partitionNumber(p.Node),
+			Name:      p.Name,
+			SizeBytes: p.Size * sectorSize,
+			Node:      p.Node,
+			Path:      p.Node,
+		}
+		if p.UUID != "" {
+			link := filepath.Join(diskByPartUUIDDir, strings.ToLower(p.UUID))
+			if _, err := os.Stat(link); err == nil {
+				partition.Path = link
+			}
+		}
+		partitions = append(partitions, partition)
+	}
+	return partitions, nil
+}
+
+// partitionNumber returns the number at the end of the partition node name.
+func partitionNumber(node string) int {
+	i := len(node)
+	for i > 0 && node[i-1] >= '0' && node[i-1] <= '9' {
+		i--
+	}
+	number, _ := strconv.Atoi(node[i:])
+	return number
+}
+
+// commandError adds the standard error of a failed command to err.
+func commandError(name string, err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%s failed: %v: %s", name, err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return fmt.Errorf("%s failed: %v", name, err)
+}
@@ -0,0 +1,126 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseWipefsOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []Signature
+	}{
+		{
+			name:   "no signatures",
+			output: "",
+		},
+		{
+			name: "gpt",
+			output: `{
+   "signatures": [
+      {"device":"nvme0n1", "offset":"0x200", "type":"gpt", "uuid":"4f1b3c6e-0f5a-4e4b-9d8e-2a1c5b7d9e10", "label":null},
+      {"device":"nvme0n1", "offset":"0x1bfe", "type":"PMBR", "uuid":null, "label":null}
+   ]
+}`,
+			expected: []Signature{{Type: "gpt"}, {Type: "PMBR"}},
+		},
+		{
+			name: "filesystem",
+			output: `{
+   "signatures": [
+      {"device":"nvme1n1", "offset":"0x438", "type":"ext4", "uuid":"0b4b7e1c-8d2e-4a57-9b3c-3f1f4c6a2d10", "label":"local-pv"}
+   ]
+}`,
+			expected: []Signature{{Type: "ext4", Label: "local-pv"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signatures, err := parseWipefsOutput([]byte(test.output))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.expected, signatures); diff != "" {
+				t.Errorf("Unexpected signatures (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := parseWipefsOutput([]byte("wipefs: invalid")); err == nil {
+		t.Errorf("Expected error parsing invalid output")
+	}
+}
+
+func TestParseSfdiskOutput(t *testing.T) {
+	output := `{
+   "partitiontable": {
+      "label": "gpt",
+      "id": "4F1B3C6E-0F5A-4E4B-9D8E-2A1C5B7D9E10",
+      "device": "/dev/nvme0n1",
+      "unit": "sectors",
+      "firstlba": 2048,
+      "lastlba": 3907029134,
+      "sectorsize": 4096,
+      "partitions": [
+         {
+            "node": "/dev/nvme0n1p1",
+            "start": 256,
+            "size": 262144,
+            "type": "0FC63DAF-8483-4772-8E79-3D69D8477DE4",
+            "uuid": "A1B2C3D4-0000-4000-8000-000000000001",
+            "name": "local-static-provisioner-1"
+         },
+         {
+            "node": "/dev/nvme0n1p12",
+            "start": 262400,
+            "size": 262144,
+            "type": "0FC63DAF-8483-4772-8E79-3D69D8477DE4",
+            "uuid": "A1B2C3D4-0000-4000-8000-000000000002"
+         }
+      ]
+   }
+}`
+	expected := []Partition{
+		{
+			Number:    1,
+			Name:      "local-static-provisioner-1",
+			SizeBytes: 262144 * 4096,
+			Node:      "/dev/nvme0n1p1",
+			Path:      "/dev/nvme0n1p1",
+		},
+		{
+			Number:    12,
+			SizeBytes: 262144 * 4096,
+			Node:      "/dev/nvme0n1p12",
+			Path:      "/dev/nvme0n1p12",
+		},
+	}
+	partitions, err := parseSfdiskOutput([]byte(output))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, partitions); diff != "" {
+		t.Errorf("Unexpected partitions (-want +got):\n%s", diff)
+	}
+}
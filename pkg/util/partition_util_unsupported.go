@@ -0,0 +1,49 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+)
+
+var _ PartitionUtil = &partitionUtil{}
+
+type partitionUtil struct{}
+
+// NewPartitionUtil returns a PartitionUtil object which fails all operations
+// as partitioning is only supported on Linux
+func NewPartitionUtil() PartitionUtil {
+	return &partitionUtil{}
+}
+
+// GetSignatures is not supported
+func (u *partitionUtil) GetSignatures(devPath string) ([]Signature, error) {
+	return nil, fmt.Errorf("GetSignatures is unsupported in this build")
+}
+
+// ListPartitions is not supported
+func (u *partitionUtil) ListPartitions(devPath string) ([]Partition, error) {
+	return nil, fmt.Errorf("ListPartitions is unsupported in this build")
+}
+
+// CreatePartitions is not supported
+func (u *partitionUtil) CreatePartitions(devPath string, partitions []Partition) error {
+	return fmt.Errorf("CreatePartitions is unsupported in this build")
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+// QuotaUtil is an interface for managing the project quotas of directories
+// on XFS and ext4 filesystems
+type QuotaUtil interface {
+	// GetProjectID returns the project ID of the directory, 0 if it has none
+	GetProjectID(path string) (uint32, error)
+
+	// SetProjectQuota assigns the project ID to the directory and the files
+	// created in it, and limits the disk space used by the project to sizeBytes
+	SetProjectQuota(path string, projectID uint32, sizeBytes int64) error
+
+	// RemoveProjectQuota removes the disk space limit of the project of the
+	// directory
+	RemoveProjectQuota(path string, projectID uint32) error
+
+	// ListProjectQuotas returns the disk space limits of the projects in use
+	// on the filesystem of the directory by project ID, 0 for the projects
+	// without limit
+	ListProjectQuotas(path string) (map[uint32]int64, error)
+}
@@ -0,0 +1,149 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+var _ QuotaUtil = &quotaUtil{}
+
+type quotaUtil struct{}
+
+// NewQuotaUtil returns a QuotaUtil object which manages project quotas with
+// xfs_io and xfs_quota, which also support ext4 filesystems mounted with the
+// prjquota option.
+func NewQuotaUtil() QuotaUtil {
+	return &quotaUtil{}
+}
+
+// GetProjectID returns the project ID of the directory as reported by xfs_io
+func (u *quotaUtil) GetProjectID(path string) (uint32, error) {
+	out, err := exec.Command("xfs_io", "-r", "-c", "lsproj", path).Output()
+	if err != nil {
+		return 0, commandError("xfs_io", err)
+	}
+	return parseLsprojOutput(out)
+}
+
+// SetProjectQuota sets the project ID and the project inheritance flag of the
+// directory with xfs_io, and the hard block limit of the project with xfs_quota
+func (u *quotaUtil) SetProjectQuota(path string, projectID uint32, sizeBytes int64) error {
+	if out, err := exec.Command("xfs_io", "-c", fmt.Sprintf("chproj %d", projectID), "-c", "chattr +P", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("xfs_io failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return u.setProjectLimit(path, projectID, (sizeBytes+1023)/1024)
+}
+
+// RemoveProjectQuota resets the hard block limit of the project with xfs_quota
+func (u *quotaUtil) RemoveProjectQuota(path string, projectID uint32) error {
+	return u.setProjectLimit(path, projectID, 0)
+}
+
+// ListProjectQuotas returns the hard block limits of the projects of the
+// filesystem of the directory as reported by xfs_quota
+func (u *quotaUtil) ListProjectQuotas(path string) (map[uint32]int64, error) {
+	out, err := u.xfsQuota(path, "report -p -b -n -N")
+	if err != nil {
+		return nil, err
+	}
+	return parseQuotaReport(out)
+}
+
+func (u *quotaUtil) setProjectLimit(path string, projectID uint32, limitKiB int64) error {
+	_, err := u.xfsQuota(path, fmt.Sprintf("limit -p bhard=%dk %d", limitKiB, projectID))
+	return err
+}
+
+// xfsQuota runs the xfs_quota command in expert mode on the filesystem of
+// the directory and returns its output
+func (u *quotaUtil) xfsQuota(path, command string) ([]byte, error) {
+	out, err := exec.Command("findmnt", "--noheadings", "--output", "TARGET,FSTYPE", "--target", path).Output()
+	if err != nil {
+		return nil, commandError("findmnt", err)
+	}
+	mountPoint, fsType, err := parseFindmntOutput(out)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"-x"}
+	if fsType != "xfs" {
+		// Other filesystems are only supported in foreign mode.
+		args = append(args, "-f")
+	}
+	args = append(args, "-c", command, mountPoint)
+	out, err = exec.Command("xfs_quota", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("xfs_quota failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// parseLsprojOutput parses the "projid = <id>" output of the xfs_io lsproj
+// command
+func parseLsprojOutput(out []byte) (uint32, error) {
+	key, value, found := strings.Cut(strings.TrimSpace(string(out)), "=")
+	if !found || strings.TrimSpace(key) != "projid" {
+		return 0, fmt.Errorf("unexpected xfs_io lsproj output %q", strings.TrimSpace(string(out)))
+	}
+	projectID, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid project ID in xfs_io lsproj output: %v", err)
+	}
+	return uint32(projectID), nil
+}
+
+// parseQuotaReport parses the "#<id> <used> <soft> <hard> ..." lines of the
+// xfs_quota project report in KiB blocks into the hard limits in bytes
+func parseQuotaReport(out []byte) (map[uint32]int64, error) {
+	limits := map[uint32]int64{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "#") {
+			return nil, fmt.Errorf("unexpected xfs_quota report line %q", line)
+		}
+		projectID, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "#"), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid project ID in xfs_quota report: %v", err)
+		}
+		hardKiB, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hard limit in xfs_quota report: %v", err)
+		}
+		limits[uint32(projectID)] = hardKiB * 1024
+	}
+	return limits, nil
+}
+
+// parseFindmntOutput parses the mount point and filesystem type reported by
+// findmnt
+func parseFindmntOutput(out []byte) (string, string, error) {
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return "", "", fmt.Errorf("unexpected findmnt output %q", strings.TrimSpace(string(out)))
+	}
+	return fields[0], fields[1], nil
+}
@@ -0,0 +1,64 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLsprojOutput(t *testing.T) {
+	projectID, err := parseLsprojOutput([]byte("projid = 1042\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if projectID != 1042 {
+		t.Errorf("Expected project ID 1042, got %d", projectID)
+	}
+
+	if _, err := parseLsprojOutput([]byte("foo: Inappropriate ioctl for device\n")); err == nil {
+		t.Errorf("Expected error for unexpected output")
+	}
+}
+
+func TestParseQuotaReport(t *testing.T) {
+	limits, err := parseQuotaReport([]byte("#0                   0          0          0     00 [--------]\n#1042           1024          0    1048576     00 [--------]\n\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[uint32]int64{0: 0, 1042: 1024 * 1024 * 1024}
+	if !reflect.DeepEqual(limits, expected) {
+		t.Errorf("Expected limits %v, got %v", expected, limits)
+	}
+
+	if _, err := parseQuotaReport([]byte("xfs_quota: cannot setup path for mount\n")); err == nil {
+		t.Errorf("Expected error for unexpected output")
+	}
+}
+
+func TestParseFindmntOutput(t *testing.T) {
+	mountPoint, fsType, err := parseFindmntOutput([]byte("/mnt/pool xfs\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if mountPoint != "/mnt/pool" || fsType != "xfs" {
+		t.Errorf("Expected mount point /mnt/pool of type xfs, got %s of type %s", mountPoint, fsType)
+	}
+}
@@ -0,0 +1,54 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+)
+
+var _ QuotaUtil = &quotaUtil{}
+
+type quotaUtil struct{}
+
+// NewQuotaUtil returns a QuotaUtil object which fails all operations as
+// project quotas are only supported on Linux
+func NewQuotaUtil() QuotaUtil {
+	return &quotaUtil{}
+}
+
+// GetProjectID is not supported
+func (u *quotaUtil) GetProjectID(path string) (uint32, error) {
+	return 0, fmt.Errorf("GetProjectID is unsupported in this build")
+}
+
+// SetProjectQuota is not supported
+func (u *quotaUtil) SetProjectQuota(path string, projectID uint32, sizeBytes int64) error {
+	return fmt.Errorf("SetProjectQuota is unsupported in this build")
+}
+
+// RemoveProjectQuota is not supported
+func (u *quotaUtil) RemoveProjectQuota(path string, projectID uint32) error {
+	return fmt.Errorf("RemoveProjectQuota is unsupported in this build")
+}
+
+// ListProjectQuotas is not supported
+func (u *quotaUtil) ListProjectQuotas(path string) (map[uint32]int64, error) {
+	return nil, fmt.Errorf("ListProjectQuotas is unsupported in this build")
+}
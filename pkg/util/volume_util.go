@@ -37,14 +37,74 @@ type VolumeUtil interface {
 	// ReadDir returns a list of files under the specified directory
 	ReadDir(fullPath string) ([]string, error)
 
+	// ReadFile returns the contents of the given file
+	ReadFile(fullPath string) ([]byte, error)
+
 	// Delete all the contents under the given path, but not the path itself
 	DeleteContents(hostPath, mountPath string) error
 
 	// Get capacity for fs on full path
 	GetFsCapacityByte(hostPath, mountPath string) (int64, error)
 
+	// Get available bytes for fs on full path
+	GetFsAvailableByte(hostPath, mountPath string) (int64, error)
+
 	// Get capacity of the block device
 	GetBlockCapacityByte(fullPath string) (int64, error)
+
+	// Get attributes of the block device
+	GetDeviceAttributes(fullPath string) (*DeviceAttributes, error)
+
+	// Get the UUID of the filesystem mounted at the given path
+	GetFsUUID(mountPath string) (string, error)
+
+	// Create or replace the symlink at linkPath pointing to target
+	EnsureSymlink(linkPath, target string) error
+
+	// Create the directory at the given path, including its parents
+	MakeDir(fullPath string) error
+
+	// Remove the directory at the given path and all its contents
+	RemoveDir(fullPath string) error
+}
+
+const (
+	// TransportNVMe is the transport of NVMe devices
+	TransportNVMe = "nvme"
+	// TransportSATA is the transport of ATA devices
+	TransportSATA = "sata"
+	// TransportSAS is the transport of SAS devices
+	TransportSAS = "sas"
+	// TransportSCSI is the transport of other SCSI devices
+	TransportSCSI = "scsi"
+	// TransportUSB is the transport of USB devices
+	TransportUSB = "usb"
+	// TransportVirtio is the transport of virtio devices
+	TransportVirtio = "virtio"
+)
+
+// DeviceAttributes describes a block device as reported by sysfs and udev
+type DeviceAttributes struct {
+	// Kernel name of the device, e.g. nvme0n1
+	Name string
+	// Partition number of the device, 0 for whole disks
+	PartitionNumber int
+	// GPT name of the partition, empty for whole disks
+	PartitionName string
+	Model         string
+	Vendor        string
+	Serial        string
+	WWN           string
+	// True if the device is a rotational disk
+	Rotational bool
+	// One of the Transport* constants, empty if unknown
+	Transport string
+	SizeBytes int64
+	// UUID of the filesystem on the device, empty if unformatted
+	FsUUID string
+	// Names of the links to the device in /dev/disk/by-id and /dev/disk/by-path
+	ByID   []string
+	ByPath []string
 }
 
 // IsDir checks if the given path is a directory
@@ -78,6 +138,38 @@ func (u *volumeUtil) ReadDir(fullPath string) ([]string, error) {
 	return files, nil
 }
 
+// ReadFile returns the contents of the given file
+func (u *volumeUtil) ReadFile(fullPath string) ([]byte, error) {
+	return os.ReadFile(fullPath)
+}
+
+// EnsureSymlink creates the symlink at linkPath pointing to target, replacing
+// an existing symlink pointing elsewhere
+func (u *volumeUtil) EnsureSymlink(linkPath, target string) error {
+	current, err := os.Readlink(linkPath)
+	if err == nil && current == target {
+		return nil
+	}
+	if err == nil {
+		if err := os.Remove(linkPath); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(target, linkPath)
+}
+
+// MakeDir creates the directory at fullPath, including its parents
+func (u *volumeUtil) MakeDir(fullPath string) error {
+	return os.MkdirAll(fullPath, 0755)
+}
+
+// RemoveDir removes the directory at fullPath and all its contents
+func (u *volumeUtil) RemoveDir(fullPath string) error {
+	return os.RemoveAll(fullPath)
+}
+
 // GetLocalPersistentVolumeNodeNames returns the node affinity node name(s) for
 // local PersistentVolumes. nil is returned if the PV does not have any
 // specific node affinity node selector terms and match expressions.
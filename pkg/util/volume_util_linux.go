@@ -47,6 +47,13 @@ func (u *volumeUtil) GetFsCapacityByte(hostPath, mountPath string) (int64, error
 	return capacity, err
 }
 
+// GetFsAvailableByte returns the bytes available to unprivileged users on a
+// mounted filesystem, which excludes the blocks reserved for root.
+func (u *volumeUtil) GetFsAvailableByte(hostPath, mountPath string) (int64, error) {
+	available, _, _, _, _, _, err := fs.Info(mountPath)
+	return available, err
+}
+
 // GetBlockCapacityByte returns  capacity in bytes of a block device.
 // fullPath is the pathname of block device.
 func (u *volumeUtil) GetBlockCapacityByte(fullPath string) (int64, error) {
@@ -39,6 +39,12 @@ func (u *volumeUtil) GetFsCapacityByte(hostPath, mountPath string) (int64, error
 	return 0, fmt.Errorf("GetFsCapacityByte is unsupported in this build")
 }
 
+// GetFsAvailableByte is defined here for darwin and other platforms
+// so that make test succeeds on them.
+func (u *volumeUtil) GetFsAvailableByte(hostPath, mountPath string) (int64, error) {
+	return 0, fmt.Errorf("GetFsAvailableByte is unsupported in this build")
+}
+
 // GetBlockCapacityByte is defined here for darwin and other platforms
 // so that make test succeeds on them.
 func (u *volumeUtil) GetBlockCapacityByte(fullPath string) (int64, error) {
@@ -58,3 +64,13 @@ func (u *volumeUtil) IsLikelyMountPoint(hostPath, mountPath string, mountPointMa
 func (u *volumeUtil) DeleteContents(hostPath, mountPath string) error {
 	return fmt.Errorf("DeleteContents is unsupported in this build")
 }
+
+// GetFsUUID for unsupported platform returns error.
+func (u *volumeUtil) GetFsUUID(mountPath string) (string, error) {
+	return "", fmt.Errorf("GetFsUUID is unsupported in this build")
+}
+
+// GetDeviceAttributes for unsupported platform returns error.
+func (u *volumeUtil) GetDeviceAttributes(fullPath string) (*DeviceAttributes, error) {
+	return nil, fmt.Errorf("GetDeviceAttributes is unsupported in this build")
+}
@@ -56,6 +56,20 @@ func (u *volumeUtil) GetFsCapacityByte(hostPath, mountPath string) (int64, error
 	return totalBytes, nil
 }
 
+// GetFsAvailableByte returns the free bytes of a mounted filesystem.
+// In Windows the path is in the context of the host, not in the context of the container
+func (u *volumeUtil) GetFsAvailableByte(hostPath, mountPath string) (int64, error) {
+	volumeID, err := u.csiProxy.GetVolumeId(hostPath)
+	if err != nil {
+		return 0, err
+	}
+	totalBytes, usedBytes, err := u.csiProxy.GetVolumeStats(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	return totalBytes - usedBytes, nil
+}
+
 // DeleteContents deletes all the contents under the given directory
 func (u *volumeUtil) DeleteContents(hostPath, mountPath string) error {
 	// mountPath is in the context of the volume inside local volume provisioner
@@ -84,6 +98,16 @@ func (u *volumeUtil) IsBlock(fullPath string) (bool, error) {
 	return false, fmt.Errorf("IsBlock is unsupported in this build")
 }
 
+// GetFsUUID for unsupported platform returns error.
+func (u *volumeUtil) GetFsUUID(mountPath string) (string, error) {
+	return "", fmt.Errorf("GetFsUUID is unsupported in this build")
+}
+
+// GetDeviceAttributes for unsupported platform returns error.
+func (u *volumeUtil) GetDeviceAttributes(fullPath string) (*DeviceAttributes, error) {
+	return nil, fmt.Errorf("GetDeviceAttributes is unsupported in this build")
+}
+
 func (u *volumeUtil) IsLikelyMountPoint(hostPath, mountPath string, mountPointMap map[string]interface{}) (bool, error) {
 	isLikelyMountPoint, err := u.csiProxy.IsSymlink(hostPath)
 	if err != nil {